@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flowforge/internal/api"
+	"flowforge/internal/database"
+	"flowforge/internal/supervisor"
+	"flowforge/internal/sysmon"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var attachPID int
+var attachLogFile string
+
+var attachCmd = &cobra.Command{
+	Use:   "attach --pid <pid>",
+	Short: "Supervise a process that was started outside FlowForge",
+	Long: `Attaches the telemetry and policy loop to an already-running process.
+FlowForge cannot capture the process's stdout, so output-based detection only
+works when --log-file points at a file the process writes to. Kill and pause
+are delivered with signals. Ctrl+C detaches and leaves the process running.
+Example:
+  flowforge attach --pid 4242
+  flowforge attach --pid 4242 --log-file agent.log --pause-on-breach`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !cmd.Flags().Changed("max-cpu") {
+			if configCpu := viper.GetFloat64("max-cpu"); configCpu > 0 {
+				maxCpu = configCpu
+			}
+		}
+		if !cmd.Flags().Changed("pause-on-breach") {
+			pauseOnBreach = viper.GetBool("pause-on-breach")
+		}
+		if !cmd.Flags().Changed("structured-logs") {
+			structuredLogs = viper.GetBool("structured-logs")
+		}
+		attachProcess(attachPID, attachLogFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+
+	attachCmd.Flags().IntVar(&attachPID, "pid", 0, "PID of the running process to supervise (required)")
+	attachCmd.Flags().StringVar(&attachLogFile, "log-file", "", "Log file to tail for output-based loop detection")
+	attachCmd.Flags().Float64Var(&maxCpu, "max-cpu", 60.0, "Maximum CPU usage threshold (Default: 60.0)")
+	attachCmd.Flags().StringVar(&modelName, "model", "gpt-4", "Model name for ROI calculation")
+	attachCmd.Flags().BoolVar(&noKill, "no-kill", false, "Watchdog mode: log & alert on loops but don't kill the process")
+	attachCmd.Flags().BoolVar(&pauseOnBreach, "pause-on-breach", false, "Freeze the process (SIGSTOP) instead of killing it on a policy breach")
+	attachCmd.Flags().BoolVar(&shadowMode, "shadow-mode", false, "Policy dry-run mode: evaluate actions but log-only for intervention")
+	attachCmd.Flags().StringVar(&policyRollout, "policy-rollout", "", "Policy rollout mode: shadow, canary, enforce (default: enforce)")
+	attachCmd.Flags().IntVar(&policyCanaryPercent, "policy-canary-percent", -1, "Policy canary enforcement percentage (0-100)")
+	attachCmd.Flags().StringVar(&maxRuntimeFlag, "max-runtime", "", "Deadline measured from attach time, e.g. 30m")
+	attachCmd.Flags().StringVar(&maxSilenceFlag, "max-silence", "", "Longest allowed gap between lines in --log-file, e.g. 5m")
+	attachCmd.Flags().StringVar(&maxStuckFlag, "max-stuck", "", "Longest time the process may show no CPU, I/O or context-switch progress, e.g. 2m")
+	attachCmd.Flags().StringVar(&maxWriteRateFlag, "max-write-bytes-per-sec", "", "Highest storage write rate for the process and its children, e.g. 50MB")
+	attachCmd.Flags().StringVar(&maxWriteBytesFlag, "max-write-bytes", "", "Most the process and its children may write to storage after attaching, e.g. 2GB")
+	attachCmd.Flags().StringVar(&maxTokensFlag, "max-tokens", "", "Most tokens the tailed log may show after attaching, e.g. 200000")
+	attachCmd.Flags().StringVar(&maxCostFlag, "max-cost-usd", "", "Most the tailed log may spend at the --model price after attaching, e.g. 5")
+	attachCmd.Flags().StringVar(&rulesFileFlag, "rules", "", "YAML file of detection rules checked against every line of --log-file (overrides rules-file)")
+	attachCmd.Flags().BoolVar(&structuredLogs, "structured-logs", false, "Parse JSON lines in --log-file and detect repeated identical tool calls and errors")
+	attachCmd.Flags().BoolVar(&deepWatch, "deep", false, "Enable Deep Watch (syscall monitoring)")
+	attachCmd.Flags().BoolVar(&standaloneRun, "standalone", false, "Serve the API on port 8080 instead of reporting to a running daemon")
+	_ = attachCmd.MarkFlagRequired("pid")
+}
+
+func attachProcess(pid int, logFile string) {
+	if err := database.InitDB(); err != nil {
+		fmt.Printf("Warning: Failed to initialize database: %v\n", err)
+	}
+	defer database.CloseDB()
+
+	target, err := supervisor.Attach(pid)
+	if err != nil {
+		fmt.Printf("Failed to attach: %v\n", err)
+		os.Exit(1)
+	}
+
+	knownPatterns, err := loadPatternWatch()
+	if err != nil {
+		fmt.Printf("Invalid pattern settings: %v\n", err)
+		os.Exit(1)
+	}
+	args := foreignCommandLine(pid)
+	fullCommand := strings.Join(args, " ")
+	dir, _ := os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid))
+	startTime := time.Now()
+
+	pollInterval := viper.GetInt("poll-interval")
+	if pollInterval <= 0 {
+		pollInterval = 500
+	}
+	logWindow := viper.GetInt("log-window")
+	if logWindow <= 0 {
+		logWindow = 10
+	}
+	rolloutMode, canaryPercent := resolvePolicyRolloutConfig()
+	// A foreign process cannot be relaunched, so breaches never restart.
+	attachPolicy, err := newRunPolicy(pollInterval, logWindow, rolloutMode, canaryPercent, false)
+	if err != nil {
+		fmt.Printf("Invalid policy configuration: %v\n", err)
+		os.Exit(1)
+	}
+	decider, err := newDecider(fullCommand)
+	if err != nil {
+		fmt.Printf("Invalid policy configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if logFile == "" && attachPolicy.MaxSilence > 0 {
+		// Without a log file every process would look silent.
+		fmt.Println("[FlowForge] max-silence needs --log-file; ignoring it.")
+		attachPolicy.MaxSilence = 0
+	}
+
+	agentID := uuid.New().String()
+	agentVersion := "1.0.0"
+	database.SetRunID(agentID)
+	fmt.Printf("[FlowForge] 🆔 Agent ID: %s (v%s)\n", agentID, agentVersion)
+	fmt.Printf("[FlowForge] Attached to PID %d: %s\n", pid, fullCommand)
+	fmt.Printf("[FlowForge] Config: max-cpu=%.1f%%, poll-interval=%dms, log-window=%d, no-kill=%v, policy-rollout=%s, policy-canary=%d%%\n",
+		maxCpu, pollInterval, logWindow, noKill, rolloutMode, canaryPercent)
+	_ = database.LogAuditEvent("operator", "ATTACH", "attached to running process", "cli", pid, fullCommand)
+
+	observer := NewLogObserver(logWindow*2, modelName)
+	engine, err := newDetectionEngine(logWindow, structuredLogs)
+	if err != nil {
+		fmt.Printf("Invalid detector configuration: %v\n", err)
+		os.Exit(1)
+	}
+	observer.FeedEngine(engine)
+	observer.ParseJSON(structuredLogs)
+	ruleSet, err := loadRules()
+	if err != nil {
+		fmt.Printf("Invalid rules: %v\n", err)
+		os.Exit(1)
+	}
+	observer.ApplyRules(ruleSet)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if logFile != "" {
+		fmt.Printf("[FlowForge] Tailing %s for output-based detection.\n", logFile)
+		go func() {
+			if err := tailFile(ctx, logFile, io.MultiWriter(os.Stdout, observer), 250*time.Millisecond); err != nil {
+				fmt.Printf("[FlowForge] Warning: stopped tailing %s: %v\n", logFile, err)
+			}
+		}()
+	} else {
+		fmt.Println("[FlowForge] No --log-file given: output-based detection is off; CPU and memory limits still apply.")
+	}
+
+	reporter := newRunReporter(agentID, fullCommand, args, dir)
+	defer reporter.Close()
+	if !standaloneRun {
+		client, err := connectToDaemon(agentID, fullCommand, args, dir, pid)
+		if err == nil {
+			reporter.attachWithControls(client, func() controlledProcess { return target }, func() {
+				fmt.Println("\n[FlowForge] Stop requested by daemon. Terminating attached process...")
+			})
+			fmt.Println("[FlowForge] Reporting to running daemon.")
+		}
+	}
+	if !reporter.Attached() {
+		fmt.Println("[FlowForge] Starting API server on port 8080...")
+		stopAPI := api.Start("8080")
+		defer stopAPI()
+		api.RegisterExternalWorker(fullCommand, args, dir, target)
+	}
+	reporter.UpdateState(0, "", "RUNNING", pid)
+	reporter.UpdateLifecycle("RUNNING", "RUNNING", pid)
+
+	var flowforgeTerminated atomic.Bool
+	mon := &processMonitor{
+		target:       target,
+		observer:     observer,
+		engine:       engine,
+		reporter:     reporter,
+		decider:      decider,
+		policy:       attachPolicy,
+		sysMonitor:   sysmon.NewMonitor(),
+		terminated:   &flowforgeTerminated,
+		command:      fullCommand,
+		agentID:      agentID,
+		agentVersion: agentVersion,
+		startTime:    startTime,
+		pollInterval: pollInterval,
+		logWindow:    logWindow,
+		patterns:     knownPatterns,
+		rules:        ruleSet,
+	}
+	go mon.run(ctx, cancel)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	exited := make(chan struct{})
+	go func() {
+		_ = target.Wait()
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+		cancel()
+		fmt.Printf("[FlowForge] Attached process %d exited.\n", pid)
+		reporter.UpdateState(0, "", "STOPPED", pid)
+		reporter.ReportExit(nil)
+	case sig := <-sigCh:
+		cancel()
+		fmt.Printf("\n[FlowForge] Received signal: %v. Detaching from PID %d...\n", sig, pid)
+		if target.Paused() {
+			_ = target.Resume()
+		}
+		_ = database.LogAuditEvent("operator", "DETACH", "received OS signal", "cli", pid, fullCommand)
+		reporter.ReportExit(errors.New("flowforge detached; process still running"))
+	}
+
+	if flowforgeTerminated.Load() {
+		os.Exit(1)
+	}
+}
+
+// foreignCommandLine reads the argv of pid from /proc, falling back to a
+// placeholder when it is unreadable.
+func foreignCommandLine(pid int) []string {
+	blob, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err == nil {
+		if args := strings.Split(strings.TrimRight(string(blob), "\x00"), "\x00"); len(args) > 0 && args[0] != "" {
+			return args
+		}
+	}
+	return []string{"pid:" + strconv.Itoa(pid)}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flowforge/internal/database"
+	"flowforge/internal/detect"
+	"flowforge/internal/policy"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// commandFingerprint keys learned baselines. Numbers, timestamps and IDs in
+// the command line are normalized away, so `train.py --seed 7` and
+// `train.py --seed 8` share one baseline.
+func commandFingerprint(command string) string {
+	normalized := detect.CommandNormalizer.Normalize(strings.Join(strings.Fields(command), " "))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadBaseline reads the learned baseline for fingerprint; errors and
+// missing history both give an empty baseline.
+func loadBaseline(fingerprint string) policy.Baseline {
+	stats, err := database.LoadCommandBaseline(fingerprint)
+	if err != nil {
+		return policy.Baseline{}
+	}
+	b := make(policy.Baseline, len(stats))
+	for _, s := range stats {
+		b[policy.Metric(s.Metric)] = policy.Stat{Count: s.Runs, Mean: s.Mean, Variance: s.Variance}
+	}
+	return b
+}
+
+// learnBaseline merges a finished run into the command's stored baseline.
+func learnBaseline(command string, run policy.RunStats) error {
+	fingerprint := commandFingerprint(command)
+	merged := loadBaseline(fingerprint).Merge(run)
+	stats := make([]database.BaselineStat, 0, len(merged))
+	for _, m := range policy.Metrics {
+		if s, ok := merged[m]; ok {
+			stats = append(stats, database.BaselineStat{Metric: string(m), Runs: s.Count, Mean: s.Mean, Variance: s.Variance})
+		}
+	}
+	return database.SaveCommandBaseline(fingerprint, command, stats)
+}
+
+// newDecider builds the decider named by the `decider` config key:
+// "threshold" (default) or "adaptive", which loads the command's baseline.
+func newDecider(command string) (policy.Decider, error) {
+	switch name := strings.ToLower(strings.TrimSpace(viper.GetString("decider"))); name {
+	case "", "threshold":
+		return policy.NewThresholdDecider(), nil
+	case "adaptive":
+		d := policy.NewAdaptiveDecider(loadBaseline(commandFingerprint(command)), viper.GetFloat64("adaptive-z-score"), viper.GetInt("adaptive-min-runs"))
+		fmt.Printf("[FlowForge] Adaptive decider: %s\n", describeBaseline(d))
+		return d, nil
+	default:
+		return nil, fmt.Errorf("decider must be threshold or adaptive, got %q", name)
+	}
+}
+
+// describeBaseline summarizes which metrics have enough history.
+func describeBaseline(d *policy.AdaptiveDecider) string {
+	var learned []string
+	for _, m := range policy.Metrics {
+		if d.Learned(m) {
+			s := d.Baseline[m]
+			learned = append(learned, fmt.Sprintf("%s %.2f±%.2f over %d runs", m, s.Mean, s.StdDev(), s.Count))
+		}
+	}
+	if len(learned) == 0 {
+		return fmt.Sprintf("no baseline yet for this command; static thresholds apply until %d clean runs", d.MinRuns)
+	}
+	return fmt.Sprintf("z=%.1f, %s", d.ZScore, strings.Join(learned, ", "))
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"flowforge/internal/database"
+	"flowforge/internal/policy"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestCommandFingerprintIgnoresVaryingArgs(t *testing.T) {
+	a := commandFingerprint("python3 train.py --seed 7 --date 2026-03-01T10:00:00Z")
+	b := commandFingerprint("python3  train.py --seed 8 --date 2026-03-02T11:30:00Z")
+	if a != b {
+		t.Fatalf("expected runs with different seeds to share a fingerprint: %s vs %s", a, b)
+	}
+	if a == commandFingerprint("python3 eval.py --seed 7") {
+		t.Fatal("expected different scripts to get different fingerprints")
+	}
+	if commandFingerprint("python3 /srv/jobs/train.py") == commandFingerprint("python3 /srv/jobs/eval.py") {
+		t.Fatal("expected script paths to be kept in the fingerprint")
+	}
+}
+
+func TestLearnBaselineFeedsAdaptiveDecider(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("FLOWFORGE_DB_PATH", filepath.Join(t.TempDir(), "flowforge.db"))
+	if err := database.InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(database.CloseDB)
+
+	if d, err := newDecider("python3 prep.py"); err != nil {
+		t.Fatalf("newDecider: %v", err)
+	} else if _, ok := d.(policy.ThresholdDecider); !ok {
+		t.Fatalf("expected the threshold decider by default, got %T", d)
+	}
+	viper.Set("decider", "oracle")
+	if _, err := newDecider("python3 prep.py"); err == nil {
+		t.Fatal("expected an unknown decider to be rejected")
+	}
+
+	for run := 0; run < 3; run++ {
+		stats := make(policy.RunStats)
+		for i := 0; i < 10; i++ {
+			stats.Observe(policy.Telemetry{CPUPercent: 94 + float64(i%3), MemoryMB: 512, LinesPerSec: 5}, i > 4)
+		}
+		if err := learnBaseline("python3 prep.py --shard 3", stats); err != nil {
+			t.Fatalf("learnBaseline: %v", err)
+		}
+	}
+
+	viper.Set("decider", "adaptive")
+	viper.Set("adaptive-min-runs", 3)
+	d, err := newDecider("python3 prep.py --shard 4")
+	if err != nil {
+		t.Fatalf("newDecider: %v", err)
+	}
+	adaptive, ok := d.(*policy.AdaptiveDecider)
+	if !ok {
+		t.Fatalf("expected the adaptive decider, got %T", d)
+	}
+	for _, m := range policy.Metrics {
+		if !adaptive.Learned(m) {
+			t.Fatalf("expected %s to be learned after three runs, got %+v", m, adaptive.Baseline)
+		}
+	}
+	if cpu := adaptive.Baseline[policy.MetricCPU]; cpu.Count != 3 || cpu.Mean < 94 || cpu.Mean > 96 {
+		t.Fatalf("unexpected cpu baseline %+v", cpu)
+	}
+}
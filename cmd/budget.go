@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// defaultBudgetAlertPercent is where the soft budget alert fires when
+// budget-alert-percent is not set.
+const defaultBudgetAlertPercent = 80
+
+// runBudget is the per-run token and dollar budget from flags, config and
+// profile.
+type runBudget struct {
+	MaxTokens    int64
+	MaxCostUSD   float64
+	AlertPercent int
+	Pause        bool // budget-action: pause; kill otherwise
+}
+
+// resolveBudget prefers the flag values, then config/profile.
+func resolveBudget() (runBudget, error) {
+	maxTokens, err := resolveBudgetSetting(maxTokensFlag, "max-tokens")
+	if err != nil {
+		return runBudget{}, err
+	}
+	maxCost, err := resolveBudgetSetting(maxCostFlag, "max-cost-usd")
+	if err != nil {
+		return runBudget{}, err
+	}
+	b := runBudget{
+		MaxTokens:    int64(maxTokens),
+		MaxCostUSD:   maxCost,
+		AlertPercent: defaultBudgetAlertPercent,
+	}
+	if viper.IsSet("budget-alert-percent") {
+		b.AlertPercent = viper.GetInt("budget-alert-percent")
+	}
+	switch action := strings.ToLower(strings.TrimSpace(viper.GetString("budget-action"))); action {
+	case "", "kill":
+	case "pause":
+		b.Pause = true
+	default:
+		return runBudget{}, fmt.Errorf("budget-action must be kill or pause, got %q", action)
+	}
+	return b, nil
+}
+
+// Enabled reports whether either budget is set.
+func (b runBudget) Enabled() bool {
+	return b.MaxTokens > 0 || b.MaxCostUSD > 0
+}
+
+func (b runBudget) String() string {
+	var parts []string
+	if b.MaxTokens > 0 {
+		parts = append(parts, fmt.Sprintf("max-tokens=%d", b.MaxTokens))
+	}
+	if b.MaxCostUSD > 0 {
+		parts = append(parts, fmt.Sprintf("max-cost-usd=$%.2f", b.MaxCostUSD))
+	}
+	action := "kill"
+	if b.Pause {
+		action = "pause"
+	}
+	if b.AlertPercent > 0 {
+		return fmt.Sprintf("%s (alerts at %d%%, then %s)", strings.Join(parts, ", "), b.AlertPercent, action)
+	}
+	return fmt.Sprintf("%s (then %s)", strings.Join(parts, ", "), action)
+}
+
+func resolveBudgetSetting(flagValue, key string) (float64, error) {
+	raw := flagValue
+	if raw == "" {
+		raw = viper.GetString(key)
+	}
+	n, err := parseBudgetSetting(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s %v", key, err)
+	}
+	return n, nil
+}
+
+// parseBudgetSetting accepts a plain number, with an optional leading "$"
+// for dollar amounts ("$5", "2.50", "200000"). Empty means disabled.
+func parseBudgetSetting(raw string) (float64, error) {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "$")
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("must be a number")
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("must be >= 0")
+	}
+	return n, nil
+}
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"flowforge/internal/database"
+	"flowforge/internal/detect"
+	"flowforge/internal/rules"
+	"flowforge/internal/supervisor"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -35,6 +41,48 @@ func validateConfig() error {
 			return fmt.Errorf("invalid config: max-memory-mb must be >= 0")
 		}
 	}
+	if err := validateFloatRange("cpu-limit-percent", 0, 100000); err != nil {
+		return err
+	}
+	if err := validateIntRange("max-pids", 0, 4194304); err != nil {
+		return err
+	}
+	if err := validateRestartConfig(""); err != nil {
+		return err
+	}
+	if err := validateDuration("max-runtime"); err != nil {
+		return err
+	}
+	if err := validateDuration("max-silence"); err != nil {
+		return err
+	}
+	if err := validateDuration("max-stuck"); err != nil {
+		return err
+	}
+	if err := validateSize("max-write-bytes-per-sec"); err != nil {
+		return err
+	}
+	if err := validateSize("max-write-bytes"); err != nil {
+		return err
+	}
+	if err := validateDetectors("detectors"); err != nil {
+		return err
+	}
+	if err := validateDeciderConfig(""); err != nil {
+		return err
+	}
+	if err := validateRulesFile("rules-file"); err != nil {
+		return err
+	}
+	if err := validateBudgetConfig(""); err != nil {
+		return err
+	}
+	if err := validatePatternConfig(); err != nil {
+		return err
+	}
+	if _, err := loadNormalizer(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
 	if viper.IsSet("max-tokens-per-min") {
 		rate := viper.GetFloat64("max-tokens-per-min")
 		if rate < 0 {
@@ -62,10 +110,208 @@ func validateProfiles() error {
 		if err := validateIntRange(prefix+".log-window", 2, 10000); err != nil {
 			return err
 		}
+		if err := validateFloatRange(prefix+".max-memory-mb", 0, 1<<30); err != nil {
+			return err
+		}
+		if err := validateFloatRange(prefix+".cpu-limit-percent", 0, 100000); err != nil {
+			return err
+		}
+		if err := validateIntRange(prefix+".max-pids", 0, 4194304); err != nil {
+			return err
+		}
+		if err := validateRestartConfig(prefix + "."); err != nil {
+			return err
+		}
+		if err := validateDuration(prefix + ".max-runtime"); err != nil {
+			return err
+		}
+		if err := validateDuration(prefix + ".max-silence"); err != nil {
+			return err
+		}
+		if err := validateDuration(prefix + ".max-stuck"); err != nil {
+			return err
+		}
+		if err := validateSize(prefix + ".max-write-bytes-per-sec"); err != nil {
+			return err
+		}
+		if err := validateSize(prefix + ".max-write-bytes"); err != nil {
+			return err
+		}
+		if err := validateDetectors(prefix + ".detectors"); err != nil {
+			return err
+		}
+		if err := validateDeciderConfig(prefix + "."); err != nil {
+			return err
+		}
+		if err := validateRulesFile(prefix + ".rules-file"); err != nil {
+			return err
+		}
+		if err := validateBudgetConfig(prefix + "."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRestartConfig(prefix string) error {
+	if viper.IsSet(prefix + "restart") {
+		if _, err := supervisor.ParseRestartPolicy(viper.GetString(prefix + "restart")); err != nil {
+			return fmt.Errorf("invalid config: %s", err)
+		}
+	}
+	if err := validateIntRange(prefix+"restart-max-attempts", 0, 10000); err != nil {
+		return err
+	}
+	if err := validateIntRange(prefix+"restart-backoff-ms", 0, 3600000); err != nil {
+		return err
+	}
+	if err := validateIntRange(prefix+"restart-backoff-max-ms", 0, 3600000); err != nil {
+		return err
+	}
+	return validateFloatRange(prefix+"restart-jitter", 0, 1)
+}
+
+func validateDeciderConfig(prefix string) error {
+	if viper.IsSet(prefix + "decider") {
+		switch strings.ToLower(strings.TrimSpace(viper.GetString(prefix + "decider"))) {
+		case "threshold", "adaptive":
+		default:
+			return fmt.Errorf("invalid config: %sdecider must be one of threshold|adaptive", prefix)
+		}
+	}
+	if err := validateFloatRange(prefix+"adaptive-z-score", 0.5, 20); err != nil {
+		return err
+	}
+	return validateIntRange(prefix+"adaptive-min-runs", 1, 1000)
+}
+
+func validateBudgetConfig(prefix string) error {
+	for _, key := range []string{prefix + "max-tokens", prefix + "max-cost-usd"} {
+		if !viper.IsSet(key) {
+			continue
+		}
+		if _, err := parseBudgetSetting(viper.GetString(key)); err != nil {
+			return fmt.Errorf("invalid config: %s %v", key, err)
+		}
+	}
+	if viper.IsSet(prefix + "budget-action") {
+		switch strings.ToLower(strings.TrimSpace(viper.GetString(prefix + "budget-action"))) {
+		case "kill", "pause":
+		default:
+			return fmt.Errorf("invalid config: %sbudget-action must be one of kill|pause", prefix)
+		}
+	}
+	return validateIntRange(prefix+"budget-alert-percent", 0, 100)
+}
+
+func validatePatternConfig() error {
+	if viper.IsSet("pattern-action") {
+		if !database.ValidPatternAction(strings.ToLower(strings.TrimSpace(viper.GetString("pattern-action")))) {
+			return fmt.Errorf("invalid config: pattern-action must be one of warn|alert|kill")
+		}
+	}
+	return validateDuration("pattern-ttl")
+}
+
+func validateDetectors(key string) error {
+	if !viper.IsSet(key) {
+		return nil
+	}
+	names := viper.GetStringSlice(key)
+	if len(names) == 0 {
+		return fmt.Errorf("invalid config: %s must list at least one detector (registered: %s)", key, strings.Join(detect.Registered(), ", "))
+	}
+	if _, err := detect.Build(names, detectorSettings()); err != nil {
+		return fmt.Errorf("invalid config: %s: %v (registered: %s)", key, err, strings.Join(detect.Registered(), ", "))
 	}
 	return nil
 }
 
+func validateRulesFile(key string) error {
+	path := strings.TrimSpace(viper.GetString(key))
+	if path == "" {
+		return nil
+	}
+	if _, err := rules.Load(path); err != nil {
+		return fmt.Errorf("invalid config: %s: %v", key, err)
+	}
+	return nil
+}
+
+func validateDuration(key string) error {
+	if !viper.IsSet(key) {
+		return nil
+	}
+	if _, err := parseDurationSetting(viper.GetString(key)); err != nil {
+		return fmt.Errorf("invalid config: %s %v", key, err)
+	}
+	return nil
+}
+
+// parseDurationSetting accepts Go durations ("90s", "30m") or a bare number
+// of seconds. Empty means disabled.
+func parseDurationSetting(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.ParseFloat(raw, 64); err == nil {
+		if secs < 0 {
+			return 0, fmt.Errorf("must be >= 0")
+		}
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("must be a duration like 90s or 30m")
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must be >= 0")
+	}
+	return d, nil
+}
+
+func validateSize(key string) error {
+	if !viper.IsSet(key) {
+		return nil
+	}
+	if _, err := parseSizeSetting(viper.GetString(key)); err != nil {
+		return fmt.Errorf("invalid config: %s %v", key, err)
+	}
+	return nil
+}
+
+// sizeUnits are the suffixes parseSizeSetting accepts, in binary multiples.
+var sizeUnits = map[string]uint64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+}
+
+// parseSizeSetting accepts a byte count ("1048576") or a size with a
+// binary unit ("50MB", "1.5g"). Empty means disabled.
+func parseSizeSetting(raw string) (uint64, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "" {
+		return 0, nil
+	}
+	i := strings.IndexFunc(raw, func(r rune) bool { return (r < '0' || r > '9') && r != '.' && r != '-' })
+	if i < 0 {
+		i = len(raw)
+	}
+	unit, ok := sizeUnits[strings.TrimSpace(raw[i:])]
+	n, err := strconv.ParseFloat(raw[:i], 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("must be a size like 50MB or a number of bytes")
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("must be >= 0")
+	}
+	return uint64(n * float64(unit)), nil
+}
+
 func validateFloatRange(key string, min, max float64) error {
 	if !viper.IsSet(key) {
 		return nil
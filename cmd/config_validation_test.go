@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -39,3 +43,260 @@ func TestValidateConfigRejectsOutOfRangeCanaryPercent(t *testing.T) {
 		t.Fatal("expected validation error for policy-canary-percent")
 	}
 }
+
+func TestValidateConfigRejectsNegativeProfileCgroupLimits(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("profiles.heavy.max-pids", -1)
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for negative profiles.heavy.max-pids")
+	}
+}
+
+func TestValidateConfigRejectsUnknownRestartPolicy(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("restart", "sometimes")
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for unknown restart policy")
+	}
+
+	viper.Set("restart", "on-failure")
+	viper.Set("profiles.standard.restart-jitter", 1.5)
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for profiles.standard.restart-jitter > 1")
+	}
+}
+
+func TestValidateConfigLivenessDurations(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("max-runtime", "30m")
+	viper.Set("profiles.heavy.max-silence", 300)
+	if err := validateConfig(); err != nil {
+		t.Fatalf("expected durations and bare seconds to validate, got %v", err)
+	}
+
+	viper.Set("profiles.heavy.max-silence", "soon")
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for profiles.heavy.max-silence")
+	}
+
+	viper.Set("profiles.heavy.max-silence", 300)
+	viper.Set("max-stuck", "-2m")
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for negative max-stuck")
+	}
+}
+
+func TestValidateConfigSizes(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("max-write-bytes-per-sec", "50MB")
+	viper.Set("max-write-bytes", 1048576)
+	viper.Set("profiles.light.max-write-bytes", "1.5g")
+	if err := validateConfig(); err != nil {
+		t.Fatalf("expected sizes and byte counts to validate, got %v", err)
+	}
+
+	viper.Set("profiles.light.max-write-bytes", "lots")
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for profiles.light.max-write-bytes")
+	}
+
+	viper.Set("profiles.light.max-write-bytes", "")
+	viper.Set("max-write-bytes-per-sec", "-1MB")
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for negative max-write-bytes-per-sec")
+	}
+
+	for raw, want := range map[string]uint64{"": 0, "512": 512, "4k": 4096, "50MB": 50 << 20, "1.5 GiB": 3 << 29} {
+		if got, err := parseSizeSetting(raw); err != nil || got != want {
+			t.Fatalf("parseSizeSetting(%q) = %d, %v; want %d", raw, got, err, want)
+		}
+	}
+}
+
+func TestValidateConfigDetectors(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("detectors", []string{"repetition", "entropy"})
+	if err := validateConfig(); err != nil {
+		t.Fatalf("expected built-in detectors to validate, got %v", err)
+	}
+
+	viper.Set("profiles.light.detectors", []string{"repetition", "telepathy"})
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for unknown detector in profiles.light.detectors")
+	}
+}
+
+func TestValidateConfigDecider(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("decider", "adaptive")
+	viper.Set("adaptive-z-score", 2.5)
+	viper.Set("profiles.heavy.decider", "threshold")
+	if err := validateConfig(); err != nil {
+		t.Fatalf("expected decider config to validate, got %v", err)
+	}
+
+	viper.Set("profiles.heavy.decider", "magic")
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for profiles.heavy.decider")
+	}
+
+	viper.Set("profiles.heavy.decider", "threshold")
+	viper.Set("adaptive-min-runs", 0)
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected validation error for adaptive-min-runs")
+	}
+}
+
+func TestValidateConfigRulesFile(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	dir := t.TempDir()
+	good := filepath.Join(dir, "rules.yaml")
+	bad := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(good, []byte("rules:\n  - name: oom\n    pattern: 'CUDA out of memory'\n    action: kill\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("rules:\n  - name: oom\n    pattern: 'CUDA'\n    action: explode\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("rules-file", good)
+	viper.Set("profiles.heavy.rules-file", good)
+	if err := validateConfig(); err != nil {
+		t.Fatalf("expected rules-file to validate, got %v", err)
+	}
+
+	viper.Set("profiles.heavy.rules-file", bad)
+	if err := validateConfig(); err == nil || !strings.Contains(err.Error(), "action must be") {
+		t.Fatalf("expected the bad rule to fail validation, got %v", err)
+	}
+
+	viper.Set("profiles.heavy.rules-file", filepath.Join(dir, "missing.yaml"))
+	if err := validateConfig(); err == nil {
+		t.Fatal("expected a missing rules file to fail validation")
+	}
+}
+
+func TestValidateConfigBudgets(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("max-tokens", 200000)
+	viper.Set("max-cost-usd", "$5")
+	viper.Set("budget-action", "pause")
+	viper.Set("profiles.heavy.max-cost-usd", 25.5)
+	if err := validateConfig(); err != nil {
+		t.Fatalf("expected budgets to validate, got %v", err)
+	}
+
+	for key, value := range map[string]interface{}{
+		"max-tokens":                   -1,
+		"profiles.heavy.max-cost-usd":  "five dollars",
+		"budget-action":                "restart",
+		"profiles.light.budget-action": "stop",
+		"budget-alert-percent":         120,
+	} {
+		viper.Reset()
+		viper.Set(key, value)
+		if err := validateConfig(); err == nil {
+			t.Fatalf("expected validation error for %s=%v", key, value)
+		}
+	}
+}
+
+func TestValidateConfigPatternSettings(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("pattern-action", "Kill")
+	viper.Set("pattern-ttl", "720h")
+	if err := validateConfig(); err != nil {
+		t.Fatalf("expected pattern settings to validate, got %v", err)
+	}
+	action, ttl, err := resolvePatternLearning()
+	if err != nil || action != "kill" || ttl != 720*time.Hour {
+		t.Fatalf("expected kill/720h, got %q/%s, %v", action, ttl, err)
+	}
+
+	for key, value := range map[string]interface{}{
+		"pattern-action": "pause",
+		"pattern-ttl":    "a month",
+	} {
+		viper.Reset()
+		viper.Set(key, value)
+		if err := validateConfig(); err == nil {
+			t.Fatalf("expected validation error for %s=%v", key, value)
+		}
+	}
+}
+
+func TestResolveBudgetPrefersFlags(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { maxTokensFlag, maxCostFlag = "", "" })
+
+	viper.Set("max-tokens", 200000)
+	viper.Set("max-cost-usd", 5)
+	maxCostFlag = "$1.50"
+	b, err := resolveBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.MaxTokens != 200000 || b.MaxCostUSD != 1.5 || b.AlertPercent != defaultBudgetAlertPercent || b.Pause {
+		t.Fatalf("unexpected budget %+v", b)
+	}
+	if got, want := b.String(), "max-tokens=200000, max-cost-usd=$1.50 (alerts at 80%, then kill)"; got != want {
+		t.Fatalf("unexpected description\nexpected: %q\ngot:      %q", want, got)
+	}
+
+	viper.Set("budget-alert-percent", 0)
+	viper.Set("budget-action", "pause")
+	if b, err = resolveBudget(); err != nil || b.AlertPercent != 0 || !b.Pause {
+		t.Fatalf("expected no alert and pause, got %+v (%v)", b, err)
+	}
+}
+
+func TestValidateConfigNormalizeRules(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("normalize-rules", []interface{}{
+		map[string]interface{}{"name": "request-id", "pattern": `req_[A-Za-z0-9]+`, "replace": "<REQ>"},
+		map[string]interface{}{"pattern": `\s+\(cached\)`, "replace": ""},
+	})
+	if err := validateConfig(); err != nil {
+		t.Fatalf("expected normalize-rules to validate, got %v", err)
+	}
+	n, err := loadNormalizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := n.Normalize("req_9fK2 done (cached) in 3 steps"); got != "<REQ> done in <NUM> steps" {
+		t.Fatalf("unexpected normalization %q", got)
+	}
+
+	for _, rules := range []interface{}{
+		"req_.*",
+		[]interface{}{map[string]interface{}{"pattern": `req_(`, "replace": "<REQ>"}},
+		[]interface{}{map[string]interface{}{"pattern": `req_\w+`}},
+		[]interface{}{map[string]interface{}{"pattern": `req_\w+`, "replace": "<REQ>", "flags": "i"}},
+	} {
+		viper.Reset()
+		viper.Set("normalize-rules", rules)
+		if err := validateConfig(); err == nil || !strings.Contains(err.Error(), "normalize") {
+			t.Fatalf("expected normalize-rules=%v to fail validation, got %v", rules, err)
+		}
+	}
+}
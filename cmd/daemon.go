@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,6 +17,8 @@ import (
 
 	"flowforge/internal/api"
 	"flowforge/internal/daemon"
+	"flowforge/internal/database"
+	"flowforge/internal/state"
 
 	"github.com/spf13/cobra"
 )
@@ -264,6 +267,11 @@ func runDaemon(port string) error {
 		})
 	}()
 
+	if err := database.InitDB(); err != nil {
+		fmt.Printf("Warning: Failed to initialize database: %v\n", err)
+	}
+	defer database.CloseDB()
+
 	stop := api.Start(port)
 	if err := waitForDaemonReady(port, pid, 5*time.Second); err != nil {
 		finalStatus = "failed"
@@ -272,6 +280,16 @@ func runDaemon(port string) error {
 		return err
 	}
 
+	ipc, err := daemon.ListenIPC(paths.SocketFile, daemonRunHandler{})
+	if err != nil {
+		finalStatus = "failed"
+		finalErr = err.Error()
+		stop()
+		return err
+	}
+	defer ipc.Close()
+	fmt.Printf("[daemon] accepting runs on %s\n", paths.SocketFile)
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(sigCh)
@@ -281,6 +299,50 @@ func runDaemon(port string) error {
 	return nil
 }
 
+// finishedRunRetention is how long the daemon keeps listing a run after it
+// exits or disconnects, so its final state can still be read.
+const finishedRunRetention = 10 * time.Minute
+
+// daemonRunHandler mirrors traffic from `flowforge run` clients into the
+// daemon's worker registry so one API and dashboard see every run.
+type daemonRunHandler struct{}
+
+func (daemonRunHandler) RegisterRun(session *daemon.Session, msg daemon.Message) {
+	state.Worker(msg.RunID).UpdateState(0, "", "RUNNING", msg.Command, msg.Args, msg.Dir, msg.PID)
+	api.RegisterExternalWorkerWithID(msg.RunID, msg.Command, msg.Args, msg.Dir, session)
+	fmt.Printf("[daemon] run %s registered (pid=%d): %s\n", msg.RunID, msg.PID, msg.Command)
+}
+
+func (daemonRunHandler) HandleRunMessage(session *daemon.Session, msg daemon.Message) {
+	tracker := state.Worker(msg.RunID)
+	switch msg.Type {
+	case daemon.MsgTelemetry:
+		st := tracker.Get()
+		tracker.UpdateState(msg.CPU, msg.LastLine, msg.Status, st.Command, st.Args, st.Dir, msg.PID)
+	case daemon.MsgTree:
+		tracker.UpdateTree(msg.RSSMB, msg.Threads, msg.FDs, msg.Processes)
+	case daemon.MsgSpend:
+		tracker.UpdateSpend(msg.Tokens, msg.CostUSD, msg.MaxTokens, msg.MaxCostUSD)
+	case daemon.MsgDecision:
+		tracker.UpdateDecision(msg.Reason, msg.CPUScore, msg.EntropyScore, msg.ConfidenceScore)
+	case daemon.MsgLifecycle:
+		tracker.UpdateLifecycle(msg.Lifecycle, msg.Status, msg.PID)
+		switch msg.Lifecycle {
+		case "PAUSED":
+			api.SetWorkerPaused(msg.RunID, true, "paused by run")
+		case "RUNNING":
+			api.SetWorkerPaused(msg.RunID, false, "resumed by run")
+		}
+	case daemon.MsgExit:
+		fmt.Printf("[daemon] run %s exited (code=%d)\n", msg.RunID, msg.ExitCode)
+	}
+}
+
+// EndRun covers both a reported exit and a run that went away without one.
+func (daemonRunHandler) EndRun(session *daemon.Session) {
+	api.RetireExternalWorker(session.RunID(), session, finishedRunRetention)
+}
+
 func waitForDaemonReady(port string, pid int, wait time.Duration) error {
 	if wait <= 0 {
 		wait = 5 * time.Second
@@ -456,6 +518,14 @@ func tailFileLines(path string, n int) ([]string, error) {
 }
 
 func followFile(path string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return tailFile(ctx, path, os.Stdout, 500*time.Millisecond)
+}
+
+// tailFile copies data appended to path into w until ctx is done. It starts
+// at the current end of the file and rewinds when the file is truncated.
+func tailFile(ctx context.Context, path string, w io.Writer, interval time.Duration) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return err
@@ -467,16 +537,12 @@ func followFile(path string) error {
 		return err
 	}
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(sigCh)
-
-	ticker := time.NewTicker(500 * time.Millisecond)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-sigCh:
+		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
 			info, err := f.Stat()
@@ -493,7 +559,7 @@ func followFile(path string) error {
 			if _, err := f.Seek(offset, io.SeekStart); err != nil {
 				return err
 			}
-			if _, err := io.Copy(os.Stdout, io.LimitReader(f, size-offset)); err != nil {
+			if _, err := io.Copy(w, io.LimitReader(f, size-offset)); err != nil {
 				return err
 			}
 			offset = size
@@ -23,6 +23,7 @@ var (
 	evidenceAuditLimit    int
 	evidenceDecisionLimit int
 	evidenceChainLimit    int
+	evidenceRecordings    []string
 	evidenceSigningKeyRaw string
 	evidenceVerifyDir     string
 	hexKeyPattern         = regexp.MustCompile(`^[0-9a-fA-F]+$`)
@@ -71,6 +72,7 @@ func init() {
 	evidenceExportCmd.Flags().IntVar(&evidenceAuditLimit, "audit-limit", 500, "Audit event export limit")
 	evidenceExportCmd.Flags().IntVar(&evidenceDecisionLimit, "decision-limit", 500, "Decision trace export limit")
 	evidenceExportCmd.Flags().IntVar(&evidenceChainLimit, "chain-limit", 500, "Incident chain export limit")
+	evidenceExportCmd.Flags().StringArrayVar(&evidenceRecordings, "recording", nil, "Recording from run --record to include in the bundle (repeatable)")
 	evidenceExportCmd.Flags().StringVar(&evidenceSigningKeyRaw, "key", "", "Signing key override (supports plain, hex:<key>, base64:<key>)")
 
 	evidenceVerifyCmd.Flags().StringVar(&evidenceVerifyDir, "bundle-dir", "", "Evidence bundle directory to verify")
@@ -102,6 +104,7 @@ func runEvidenceExport() {
 		AuditLimit:    evidenceAuditLimit,
 		DecisionLimit: evidenceDecisionLimit,
 		ChainLimit:    evidenceChainLimit,
+		Recordings:    evidenceRecordings,
 	}, key)
 	if err != nil {
 		fmt.Printf("Error: evidence export failed: %v\n", err)
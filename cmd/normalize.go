@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"flowforge/internal/detect"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// loadNormalizer compiles the `normalize-rules` config key ahead of the
+// built-in token classes:
+//
+//	normalize-rules:
+//	  - name: request-id        # optional, for error messages
+//	    pattern: 'req_[A-Za-z0-9]+'
+//	    replace: '<REQ>'        # required; '' removes the match
+//
+// It is top-level only: the pattern registry is shared by every profile,
+// so all runs must normalize lines alike.
+func loadNormalizer() (*detect.Normalizer, error) {
+	var items []map[string]interface{}
+	switch raw := viper.Get("normalize-rules").(type) {
+	case nil:
+	case []map[string]interface{}:
+		items = raw
+	case []interface{}:
+		for i, item := range raw {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("normalize-rules[%d] must be a mapping with pattern and replace", i)
+			}
+			items = append(items, m)
+		}
+	default:
+		return nil, fmt.Errorf("normalize-rules must be a list of rules with pattern and replace")
+	}
+
+	rules := make([]detect.NormalizeRule, 0, len(items))
+	for i, m := range items {
+		var r detect.NormalizeRule
+		for key, value := range m {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("normalize-rules[%d].%s must be a string", i, key)
+			}
+			switch key {
+			case "name":
+				r.Name = s
+			case "pattern":
+				r.Pattern = s
+			case "replace":
+				r.Replace = s
+			default:
+				return nil, fmt.Errorf("normalize-rules[%d]: unknown key %q", i, key)
+			}
+		}
+		if _, ok := m["replace"]; !ok {
+			return nil, fmt.Errorf("normalize-rules[%d] needs a replace value ('' removes the match)", i)
+		}
+		rules = append(rules, r)
+	}
+	return detect.NewNormalizer(rules)
+}
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flowforge/internal/database"
+	"flowforge/internal/detect"
+	"flowforge/internal/patterns"
+	"flowforge/internal/policy"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	patternsListAll  bool
+	patternsListJSON bool
+)
+
+var patternsCmd = &cobra.Command{
+	Use:   "patterns",
+	Short: "Manage the known bad pattern registry",
+	Long: `Lists and manages the registry of known bad output patterns. Runs learn a
+pattern when they are stopped or alerted for a repeating line, and warn,
+alert or kill when a later run's output matches it, per the pattern's action.`,
+}
+
+var patternsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List known bad patterns",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		withPatternDB(func() error { return listPatterns(os.Stdout, patternsListAll, patternsListJSON) })
+	},
+}
+
+var patternsDisableCmd = &cobra.Command{
+	Use:   "disable ID...",
+	Short: "Stop matching patterns without deleting them",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		withPatternDB(func() error { return setPatternsEnabled(args, false) })
+	},
+}
+
+var patternsEnableCmd = &cobra.Command{
+	Use:   "enable ID...",
+	Short: "Resume matching disabled patterns",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		withPatternDB(func() error { return setPatternsEnabled(args, true) })
+	},
+}
+
+var patternsImportCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Merge patterns from an export or a legacy pattern_blacklist.json",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		withPatternDB(func() error { return importPatterns(args[0]) })
+	},
+}
+
+var patternsExportCmd = &cobra.Command{
+	Use:   "export [FILE]",
+	Short: "Write every pattern as JSON to FILE or stdout",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		withPatternDB(func() error {
+			if len(args) == 0 {
+				return exportPatterns(os.Stdout)
+			}
+			f, err := os.Create(args[0])
+			if err != nil {
+				return err
+			}
+			if err := exportPatterns(f); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(patternsCmd)
+	patternsCmd.AddCommand(patternsListCmd, patternsDisableCmd, patternsEnableCmd, patternsImportCmd, patternsExportCmd)
+	patternsListCmd.Flags().BoolVar(&patternsListAll, "all", false, "Include disabled and expired patterns")
+	patternsListCmd.Flags().BoolVar(&patternsListJSON, "json", false, "Print JSON instead of a table")
+}
+
+func withPatternDB(fn func() error) {
+	if err := database.InitDB(); err != nil {
+		fmt.Printf("Error: failed to initialize database: %v\n", err)
+		os.Exit(1)
+	}
+	err := fn()
+	database.CloseDB()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func listPatterns(w io.Writer, all, asJSON bool) error {
+	ps, err := database.ListPatterns()
+	if err != nil {
+		return err
+	}
+	shown := make([]database.Pattern, 0, len(ps))
+	for _, p := range ps {
+		if all || (p.Enabled && !p.Expired) {
+			shown = append(shown, p)
+		}
+	}
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false) // Normalized patterns are full of <NUM>
+		return enc.Encode(shown)
+	}
+	if len(shown) == 0 {
+		fmt.Fprintln(w, "No patterns.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tACTION\tSTATE\tHITS\tLAST SEEN\tPATTERN")
+	for _, p := range shown {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\n", p.ID, p.Action, patternState(p), p.HitCount, p.LastSeen, shortPattern(p.Pattern))
+	}
+	return tw.Flush()
+}
+
+// shortPattern keeps the table readable; list --json prints patterns whole.
+func shortPattern(s string) string {
+	if r := []rune(s); len(r) > 60 {
+		return string(r[:57]) + "..."
+	}
+	return s
+}
+
+func patternState(p database.Pattern) string {
+	switch {
+	case !p.Enabled:
+		return "disabled"
+	case p.Expired:
+		return "expired"
+	default:
+		return "enabled"
+	}
+}
+
+func setPatternsEnabled(args []string, enabled bool) error {
+	verb := "Disabled"
+	if enabled {
+		verb = "Enabled"
+	}
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid pattern ID %q", arg)
+		}
+		if err := database.SetPatternEnabled(id, enabled); err != nil {
+			if errors.Is(err, database.ErrPatternNotFound) {
+				return fmt.Errorf("pattern %d not found", id)
+			}
+			return err
+		}
+		fmt.Printf("%s pattern %d\n", verb, id)
+	}
+	return nil
+}
+
+func importPatterns(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	ps, err := patterns.Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	added, err := database.ImportPatterns(ps)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	fmt.Printf("Imported %d patterns from %s (%d new)\n", len(ps), path, added)
+	return nil
+}
+
+func exportPatterns(w io.Writer) error {
+	ps, err := database.ListPatterns()
+	if err != nil {
+		return err
+	}
+	return patterns.Export(w, ps)
+}
+
+// resolvePatternLearning returns the action and TTL given to patterns runs
+// learn, from `pattern-action` (default warn) and `pattern-ttl` (default
+// never expires).
+func resolvePatternLearning() (string, time.Duration, error) {
+	action := strings.ToLower(strings.TrimSpace(viper.GetString("pattern-action")))
+	if action == "" {
+		action = database.PatternActionWarn
+	}
+	if !database.ValidPatternAction(action) {
+		return "", 0, fmt.Errorf("pattern-action must be warn, alert or kill, got %q", action)
+	}
+	ttl, err := parseDurationSetting(viper.GetString("pattern-ttl"))
+	if err != nil {
+		return "", 0, fmt.Errorf("pattern-ttl %v", err)
+	}
+	return action, ttl, nil
+}
+
+// patternWatch matches a run's output against the known pattern registry
+// and learns the patterns the run is stopped or alerted for.
+type patternWatch struct {
+	registry *patterns.Registry
+	action   string        // Given to learned patterns
+	ttl      time.Duration // Given to learned patterns
+	checked  int64         // Output lines already matched
+}
+
+// loadPatternWatch loads the active known patterns. A registry that cannot
+// be read leaves the run without pattern matching, as a missing database
+// always has.
+func loadPatternWatch() (*patternWatch, error) {
+	action, ttl, err := resolvePatternLearning()
+	if err != nil {
+		return nil, err
+	}
+	registry, err := patterns.Load()
+	if err != nil && database.GetDB() != nil {
+		fmt.Printf("[FlowForge] Warning: could not load known patterns: %v\n", err)
+	}
+	fmt.Printf("[FlowForge] 📋 Loaded %d known bad patterns\n", registry.Len())
+	return &patternWatch{registry: registry, action: action, ttl: ttl}, nil
+}
+
+// Check matches the lines written since the previous Check. Each match
+// prints an early warning and counts a hit; matches of alert and kill
+// patterns are returned for the decider, once per pattern.
+func (w *patternWatch) Check(o *LogObserver) []policy.PatternMatch {
+	total := o.TotalLines()
+	fresh := total - w.checked
+	w.checked = total
+	if w.registry.Len() == 0 || fresh <= 0 {
+		return nil
+	}
+
+	var out []policy.PatternMatch
+	seen := make(map[int64]bool)
+	for _, line := range o.GetLastLines(int(fresh)) {
+		normalized := detect.NormalizeLog(line)
+		p, ok := w.registry.Match(normalized)
+		if !ok || seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+		fmt.Printf("\n⚡ EARLY WARNING: Output matches known bad pattern #%d (%s)\n", p.ID, p.Action)
+		fmt.Println("Pattern:", normalized)
+		_ = database.RecordPatternHit(p.ID)
+		if p.Action != database.PatternActionWarn {
+			out = append(out, policy.PatternMatch{ID: p.ID, Pattern: p.Pattern, Kill: p.Action == database.PatternActionKill})
+		}
+	}
+	return out
+}
+
+// Learn adds a single-line loop pattern to the registry. Cycle, tool-call
+// and stack-trace patterns describe several lines or decoded JSON, so they
+// would never match the per-line check.
+func (w *patternWatch) Learn(pattern, command string, t policy.Telemetry) {
+	if t.LoopPeriod == 0 && t.LoopTool == "" && !t.LoopErrors && t.RepeatedTrace == 0 {
+		_ = w.registry.Learn(pattern, command, w.action, w.ttl)
+	}
+}
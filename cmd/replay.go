@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"flowforge/internal/database"
+	"flowforge/internal/detect"
+	"flowforge/internal/policy"
+	"flowforge/internal/recording"
+	"flowforge/internal/redact"
+	"flowforge/internal/supervisor"
+	"flowforge/internal/tokens"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var replayRecordingFile string
+var replayPolicyFile string
+
+var replayCmd = &cobra.Command{
+	Use:   "replay --recording FILE [--policy flowforge.yaml]",
+	Short: "Replay a recorded run through detection and the policy",
+	Long: `Feeds a recording's output lines and telemetry samples through the same
+detectors and decider that "flowforge run" uses, on the recording's own
+clock, and prints every decision with its reason. Nothing is executed or
+stopped; use it to see how a policy change would have treated a real run.
+Example:
+  flowforge replay --recording agent.jsonl
+  flowforge replay --recording agent.jsonl --policy tuned.yaml --profile heavy`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if replayPolicyFile != "" {
+			if err := loadPolicyFile(replayPolicyFile); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		rec, err := recording.ReadFile(replayRecordingFile)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if strings.EqualFold(viper.GetString("decider"), "adaptive") {
+			// The adaptive decider reads the command's learned baseline.
+			if err := database.InitDB(); err == nil {
+				defer database.CloseDB()
+			}
+		}
+		if _, err := replayRun(os.Stdout, rec); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringVar(&replayRecordingFile, "recording", "", "Recording to replay (JSON Lines, as written by run --record)")
+	replayCmd.Flags().StringVar(&replayPolicyFile, "policy", "", "Config file whose policy settings replace the active config for this replay")
+	replayCmd.Flags().StringVar(&rulesFileFlag, "rules", "", "YAML file of detection rules to apply (overrides rules-file)")
+	replayCmd.MarkFlagRequired("recording")
+}
+
+// loadPolicyFile replaces the active config with path, applying the
+// profile and validation the same way startup does.
+func loadPolicyFile(path string) error {
+	viper.Reset()
+	viper.SetConfigFile(path)
+	viper.AutomaticEnv()
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read policy file %q: %v", path, err)
+	}
+	resolveProfile()
+	return validateConfig()
+}
+
+// replayDecision is a decision and when, relative to the recording start,
+// it would have been taken.
+type replayDecision struct {
+	At       time.Duration
+	Decision policy.Decision
+}
+
+// replaySummary counts a replay's decisions and notes the first alert and
+// the first decision that would have stopped, restarted or paused the run.
+type replaySummary struct {
+	Decisions  map[policy.Action]int
+	FirstAlert *replayDecision
+	FirstStop  *replayDecision
+}
+
+// replayRun drives the recording through detection and the decider built
+// from the active config, writing one line per decision and a summary.
+func replayRun(w io.Writer, rec *recording.Recording) (replaySummary, error) {
+	summary := replaySummary{Decisions: make(map[policy.Action]int)}
+	if rec.Count(recording.KindSample) == 0 {
+		return summary, fmt.Errorf("recording has no telemetry samples to replay")
+	}
+
+	if v := viper.GetFloat64("max-cpu"); v > 0 {
+		maxCpu = v
+	}
+	pollInterval := viper.GetInt("poll-interval")
+	if pollInterval <= 0 {
+		pollInterval = 500
+	}
+	logWindow := viper.GetInt("log-window")
+	if logWindow <= 0 {
+		logWindow = 10
+	}
+	structuredLogs = viper.GetBool("structured-logs")
+	pauseOnBreach = viper.GetBool("pause-on-breach")
+	rolloutMode, canaryPercent := resolvePolicyRolloutConfig()
+	restartCfg, err := resolveRestartConfig()
+	if err != nil {
+		return summary, err
+	}
+	pol, err := newRunPolicy(pollInterval, logWindow, rolloutMode, canaryPercent, restartCfg.Policy.ShouldRestart(supervisor.ExitPolicyBreach))
+	if err != nil {
+		return summary, err
+	}
+	engine, err := newDetectionEngine(logWindow, structuredLogs)
+	if err != nil {
+		return summary, err
+	}
+	decider, err := newDecider(rec.Header.Command)
+	if err != nil {
+		return summary, err
+	}
+	ruleSet, err := loadRules()
+	if err != nil {
+		return summary, err
+	}
+
+	fmt.Fprintf(w, "[replay] %s: %d lines, %d samples over %s\n",
+		replayName(rec), rec.Count(recording.KindLine), rec.Count(recording.KindSample), rec.Span().Round(100*time.Millisecond))
+	deciderName := strings.ToLower(strings.TrimSpace(viper.GetString("decider")))
+	if deciderName == "" {
+		deciderName = "threshold"
+	}
+	fmt.Fprintf(w, "[replay] policy: max-cpu=%.1f%%, log-window=%d, decider=%s, rollout=%s\n",
+		maxCpu, logWindow, deciderName, rolloutMode)
+
+	// The monitor's per-tick state, on the recording's clock.
+	var cpuOver cpuOverClock
+	var lines counterRate
+	var totalLines, totalTokens int64
+	model := rec.Header.Model
+	if model == "" {
+		model = modelName
+	}
+	lastActivity := rec.Start
+	for _, ev := range rec.Events {
+		switch ev.Kind {
+		case recording.KindLine:
+			entry := engineLine(redact.Line(ev.Text), ev.Stream, structuredLogs, ev.Time)
+			engine.ObserveLine(entry)
+			if ruleSet != nil {
+				ruleSet.Observe(entry)
+			}
+			totalLines++
+			totalTokens += int64(tokens.Count(entry.Text, model))
+			lastActivity = ev.Time
+			continue
+		case recording.KindSample:
+		default:
+			continue
+		}
+
+		s := ev.Sample
+		if s.Paused {
+			cpuOver.Reset()
+			lastActivity = ev.Time
+			continue
+		}
+		detection := engine.Evaluate(detect.Sample{
+			Time:               ev.Time,
+			CPUPercent:         s.CPUPercent,
+			CPUThreshold:       maxCpu,
+			CPUOverFor:         cpuOver.Observe(ev.Time, s.CPUPercent, maxCpu),
+			MemoryMB:           s.MemoryMB,
+			Runtime:            ev.Time.Sub(rec.Start),
+			SilentFor:          ev.Time.Sub(lastActivity),
+			StuckFor:           s.StuckFor(),
+			StuckState:         s.StuckState,
+			WriteBytesPerSec:   s.WriteBytesPerSec,
+			WriteBytes:         s.WriteBytes,
+			LinesPerSec:        lines.Observe(ev.Time, totalLines),
+			ProgressAdvancedAt: s.ProgressAdvancedAt(ev.Time),
+			Rules:              ruleMatches(ruleSet.Take()),
+			Tokens:             totalTokens,
+			CostUSD:            tokens.EstimateCost(int(totalTokens), model),
+		})
+		_, decision, judged := judge(decider, pol, detection, rec.Header.RunID)
+		if !judged {
+			continue
+		}
+
+		at := ev.Time.Sub(rec.Start)
+		summary.Decisions[decision.Action]++
+		line := fmt.Sprintf("%9s  %-8s %s", "+"+formatOffset(at), decision.Action.String(), decision.Reason)
+		if decision.Action != policy.ActionContinue && detection.Pattern != "" && decision.Breach == "" {
+			line += fmt.Sprintf(" [pattern: %q]", detection.Pattern)
+		}
+		fmt.Fprintln(w, line)
+
+		if decision.Action == policy.ActionAlert && summary.FirstAlert == nil {
+			summary.FirstAlert = &replayDecision{At: at, Decision: decision}
+		}
+		if isStopAction(decision.IntendedAction) && summary.FirstStop == nil {
+			summary.FirstStop = &replayDecision{At: at, Decision: decision}
+		}
+	}
+
+	writeReplaySummary(w, rec, summary)
+	return summary, nil
+}
+
+// isStopAction reports whether a would end or freeze the run.
+func isStopAction(a policy.Action) bool {
+	return a == policy.ActionKill || a == policy.ActionRestart || a == policy.ActionPause
+}
+
+func writeReplaySummary(w io.Writer, rec *recording.Recording, s replaySummary) {
+	var counts []string
+	total := 0
+	for _, a := range []policy.Action{policy.ActionContinue, policy.ActionAlert, policy.ActionLogOnly, policy.ActionPause, policy.ActionRestart, policy.ActionKill} {
+		if n := s.Decisions[a]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%s %d", a.String(), n))
+			total += n
+		}
+	}
+	fmt.Fprintf(w, "\nSummary: %d decisions (%s)\n", total, strings.Join(counts, ", "))
+
+	if s.FirstAlert != nil {
+		fmt.Fprintf(w, "  First alert: +%s  %s\n", formatOffset(s.FirstAlert.At), s.FirstAlert.Decision.Reason)
+	} else {
+		fmt.Fprintln(w, "  First alert: none")
+	}
+	if s.FirstStop == nil {
+		fmt.Fprintln(w, "  First kill:  none; the policy would have let the run finish")
+		return
+	}
+	d := s.FirstStop.Decision
+	label := strings.ToLower(d.IntendedAction.String())
+	if d.Action == policy.ActionLogOnly {
+		label += ", log-only"
+	}
+	fmt.Fprintf(w, "  First kill:  +%s  (%s) %s\n", formatOffset(s.FirstStop.At), label, d.Reason)
+	if remaining := rec.Span() - s.FirstStop.At; remaining > 0 {
+		fmt.Fprintf(w, "  The recording continues %s past that point.\n", formatOffset(remaining))
+	}
+}
+
+// replayName names the recorded run for the replay header.
+func replayName(rec *recording.Recording) string {
+	name := rec.Header.Command
+	if name == "" {
+		name = "recording"
+	}
+	if !rec.Start.IsZero() {
+		name += " (started " + rec.Start.Format(time.RFC3339) + ")"
+	}
+	return name
+}
+
+// formatOffset renders a replay offset with tenths of a second.
+func formatOffset(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
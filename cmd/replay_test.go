@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"flowforge/internal/policy"
+	"flowforge/internal/recording"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// loopRecording is a run that idles for idle, then spins at 97% CPU while
+// printing the same retry line every 100ms until end.
+func loopRecording(idle, end time.Duration) *recording.Recording {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rec := &recording.Recording{
+		Header: recording.Header{Version: recording.Version, Command: "python3 agent.py", RunID: "rec-1"},
+		Start:  start,
+	}
+	for at := time.Duration(0); at <= end; at += 100 * time.Millisecond {
+		now := start.Add(at)
+		text := "step finished, loading next batch " + at.String()
+		if at >= idle {
+			text = "retrying request to api.example.com"
+		}
+		rec.Events = append(rec.Events, recording.Event{Kind: recording.KindLine, Time: now, Stream: "stdout", Text: text})
+		if at%(500*time.Millisecond) == 0 {
+			cpu := 12.0
+			if at >= idle {
+				cpu = 97
+			}
+			rec.Events = append(rec.Events, recording.Event{Kind: recording.KindSample, Time: now, Sample: &recording.Sample{CPUPercent: cpu, MemoryMB: 80}})
+		}
+	}
+	return rec
+}
+
+func withReplayConfig(t *testing.T, settings map[string]any) {
+	t.Helper()
+	oldMaxCPU, oldShadow := maxCpu, shadowMode
+	t.Cleanup(func() {
+		maxCpu, shadowMode = oldMaxCPU, oldShadow
+		viper.Reset()
+	})
+	viper.Reset()
+	maxCpu, shadowMode = 60, false
+	for k, v := range settings {
+		viper.Set(k, v)
+	}
+}
+
+func TestReplayReportsFirstKill(t *testing.T) {
+	withReplayConfig(t, map[string]any{"max-cpu": 80, "cpu-window-seconds": 3})
+
+	var out bytes.Buffer
+	summary, err := replayRun(&out, loopRecording(10*time.Second, 30*time.Second))
+	if err != nil {
+		t.Fatalf("replayRun: %v", err)
+	}
+	if summary.FirstStop == nil {
+		t.Fatalf("expected a kill, got:\n%s", out.String())
+	}
+	// CPU goes over at 10s and must stay there for the 3s window.
+	if at := summary.FirstStop.At; at < 13*time.Second || at > 16*time.Second {
+		t.Fatalf("expected the first kill a few seconds after the loop starts, got +%s", at)
+	}
+	if summary.FirstStop.Decision.IntendedAction != policy.ActionKill {
+		t.Fatalf("expected a kill, got %s", summary.FirstStop.Decision.IntendedAction)
+	}
+	if summary.Decisions[policy.ActionContinue] == 0 {
+		t.Fatal("expected the idle phase to produce CONTINUE decisions")
+	}
+	for _, want := range []string{"KILL", "CPU exceeded 80% for 3s", "First kill:  +", "past that point"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in replay output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestReplayHonorsShadowRollout(t *testing.T) {
+	withReplayConfig(t, map[string]any{"max-cpu": 80, "cpu-window-seconds": 3, "policy-rollout": "shadow"})
+
+	var out bytes.Buffer
+	summary, err := replayRun(&out, loopRecording(5*time.Second, 20*time.Second))
+	if err != nil {
+		t.Fatalf("replayRun: %v", err)
+	}
+	if summary.Decisions[policy.ActionKill] != 0 || summary.FirstStop == nil {
+		t.Fatalf("expected log-only kills under shadow rollout, got %v", summary.Decisions)
+	}
+	if !strings.Contains(out.String(), "(kill, log-only)") {
+		t.Fatalf("expected the summary to mark the kill as log-only:\n%s", out.String())
+	}
+}
+
+func TestReplayWithoutBreachLetsRunFinish(t *testing.T) {
+	withReplayConfig(t, map[string]any{"max-cpu": 99})
+
+	var out bytes.Buffer
+	summary, err := replayRun(&out, loopRecording(5*time.Second, 15*time.Second))
+	if err != nil {
+		t.Fatalf("replayRun: %v", err)
+	}
+	if summary.FirstStop != nil {
+		t.Fatalf("expected no kill below the CPU limit, got +%s %s", summary.FirstStop.At, summary.FirstStop.Decision.Reason)
+	}
+	if !strings.Contains(out.String(), "would have let the run finish") {
+		t.Fatalf("expected the summary to say the run would finish:\n%s", out.String())
+	}
+}
+
+func TestReplayAppliesRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  - name: retry-storm\n    pattern: '^retrying request'\n    min_hits: 20\n    window: 5s\n    action: pause\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	withReplayConfig(t, map[string]any{"max-cpu": 99, "rules-file": path})
+
+	var out bytes.Buffer
+	summary, err := replayRun(&out, loopRecording(5*time.Second, 15*time.Second))
+	if err != nil {
+		t.Fatalf("replayRun: %v", err)
+	}
+	// Retries start at 5s, one every 100ms: the 20th is at 6.9s and is
+	// judged at the 7s sample.
+	if summary.FirstStop == nil || summary.FirstStop.At != 7*time.Second {
+		t.Fatalf("expected the rule to pause the run at +7s, got %+v\n%s", summary.FirstStop, out.String())
+	}
+	d := summary.FirstStop.Decision
+	if d.Action != policy.ActionPause || d.Breach != policy.BreachRule || !strings.Contains(d.Reason, `rule "retry-storm" matched 20 times in 5s`) {
+		t.Fatalf("unexpected decision: %+v", d)
+	}
+}
+
+func TestReplayEnforcesTokenBudget(t *testing.T) {
+	withReplayConfig(t, map[string]any{"max-cpu": 99, "max-tokens": 2000})
+
+	var out bytes.Buffer
+	summary, err := replayRun(&out, loopRecording(30*time.Second, 30*time.Second))
+	if err != nil {
+		t.Fatalf("replayRun: %v", err)
+	}
+	// Tokens are recounted from the recorded lines, about ten per line.
+	if !strings.Contains(out.String(), "of 2000 token budget (8") {
+		t.Fatalf("expected a soft budget alert at 80%%, got:\n%s", out.String())
+	}
+	if summary.FirstStop == nil || summary.FirstStop.At < 10*time.Second {
+		t.Fatalf("expected the budget to stop the run late in the recording, got %+v\n%s", summary.FirstStop, out.String())
+	}
+	d := summary.FirstStop.Decision
+	if d.Action != policy.ActionKill || d.Breach != policy.BreachBudget || !strings.Contains(d.Reason, "token budget exhausted") {
+		t.Fatalf("unexpected decision: %+v", d)
+	}
+}
+
+func TestReplayRequiresSamples(t *testing.T) {
+	withReplayConfig(t, nil)
+	rec := &recording.Recording{Start: time.Now(), Events: []recording.Event{{Kind: recording.KindLine, Time: time.Now(), Text: "hello"}}}
+	if _, err := replayRun(&bytes.Buffer{}, rec); err == nil {
+		t.Fatal("expected a recording without samples to be rejected")
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"flowforge/internal/detect"
 	"fmt"
 	"os"
 
@@ -61,6 +62,15 @@ func initConfig() {
 		fmt.Printf("Configuration validation failed: %v\n", err)
 		os.Exit(1)
 	}
+
+	// One normalizer for the whole process, so detection, rules, the pattern
+	// registry and incident patterns all see lines alike.
+	normalizer, err := loadNormalizer()
+	if err != nil {
+		fmt.Printf("Configuration validation failed: %v\n", err)
+		os.Exit(1)
+	}
+	detect.SetNormalizer(normalizer)
 }
 
 // resolveProfile merges the active profile's settings into the top-level Viper keys.
@@ -98,6 +108,19 @@ func resolveProfile() {
 			viper.Set("log-window", logWindow)
 		}
 
+		// Hard limits and breach/restart policy; top-level keys win over the profile.
+		for _, key := range []string{
+			"cgroup", "max-memory-mb", "cpu-limit-percent", "max-pids", "pause-on-breach",
+			"restart", "restart-max-attempts", "restart-backoff-ms", "restart-backoff-max-ms", "restart-jitter",
+			"max-runtime", "max-silence", "max-stuck", "max-write-bytes-per-sec", "max-write-bytes", "detectors", "detector-settings", "structured-logs",
+			"decider", "adaptive-z-score", "adaptive-min-runs", "rules-file",
+			"max-tokens", "max-cost-usd", "budget-alert-percent", "budget-action",
+		} {
+			if !viper.IsSet(key) && viper.IsSet(prefix+"."+key) {
+				viper.Set(key, viper.Get(prefix+"."+key))
+			}
+		}
+
 		if verbose {
 			fmt.Printf("Active profile: %s (max-cpu=%.1f, poll-interval=%dms, log-window=%d)\n",
 				active,
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"flowforge/internal/database"
+	"flowforge/internal/policy"
+	"flowforge/internal/rules"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var rulesFileFlag string
+
+// loadRules compiles the rules file named by --rules or `rules-file`; nil
+// when neither is set.
+func loadRules() (*rules.Set, error) {
+	path := strings.TrimSpace(rulesFileFlag)
+	if path == "" {
+		path = strings.TrimSpace(viper.GetString("rules-file"))
+	}
+	if path == "" {
+		return nil, nil
+	}
+	set, err := rules.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[FlowForge] Loaded %d detection rules from %s\n", set.Len(), path)
+	return set, nil
+}
+
+// ruleMatches converts firings for the policy decider.
+func ruleMatches(firings []rules.Firing) []policy.RuleMatch {
+	var out []policy.RuleMatch
+	for _, f := range firings {
+		out = append(out, f.Match())
+	}
+	return out
+}
+
+// logRuleFirings prints each firing and records it as a rule_match event.
+func logRuleFirings(command string, pid int, firings []rules.Firing) {
+	for _, f := range firings {
+		fmt.Printf("\n[FlowForge] Rule %q matched %d times (%s, action %s)\n", f.Rule, f.Hits, f.Severity, f.Action)
+		_ = database.LogRuleMatch(command, database.RuleMatch{
+			Rule:          f.Rule,
+			Severity:      f.Severity,
+			Action:        f.Action,
+			Hits:          f.Hits,
+			WindowSeconds: f.Window.Seconds(),
+			Stream:        f.Stream,
+			Line:          f.Line,
+		}, pid)
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"flowforge/internal/api"
+	"flowforge/internal/daemon"
 	"flowforge/internal/database"
+	"flowforge/internal/detect"
 	"flowforge/internal/feedback"
-	"flowforge/internal/patterns"
 	"flowforge/internal/policy"
+	"flowforge/internal/recording"
 	"flowforge/internal/redact"
+	"flowforge/internal/rules"
 	"flowforge/internal/state"
 	"flowforge/internal/supervisor"
 	"flowforge/internal/sysmon"
@@ -17,18 +20,13 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/adrg/strutil"
-	"github.com/adrg/strutil/metrics"
 	"github.com/google/uuid"
-	"github.com/shirou/gopsutil/v3/process"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -41,7 +39,20 @@ var policyRollout string
 var policyCanaryPercent int
 var injectFeedback string
 var deepWatch bool
-var firstNumberRegex = regexp.MustCompile(`\d+`)
+var standaloneRun bool
+var useCgroup bool
+var pauseOnBreach bool
+var restartPolicyFlag string
+var restartMaxAttempts int
+var maxRuntimeFlag string
+var maxSilenceFlag string
+var maxStuckFlag string
+var maxWriteRateFlag string
+var maxWriteBytesFlag string
+var maxTokensFlag string
+var maxCostFlag string
+var structuredLogs bool
+var recordPath string
 
 // runCmd represents the run command
 var runCmd = &cobra.Command{
@@ -62,6 +73,15 @@ Example:
 				maxCpu = configCpu
 			}
 		}
+		if !cmd.Flags().Changed("cgroup") {
+			useCgroup = viper.GetBool("cgroup")
+		}
+		if !cmd.Flags().Changed("pause-on-breach") {
+			pauseOnBreach = viper.GetBool("pause-on-breach")
+		}
+		if !cmd.Flags().Changed("structured-logs") {
+			structuredLogs = viper.GetBool("structured-logs")
+		}
 		runProcess(args)
 	},
 }
@@ -73,11 +93,26 @@ func init() {
 	runCmd.Flags().Float64Var(&maxCpu, "max-cpu", 60.0, "Maximum CPU usage threshold (Default: 60.0)")
 	runCmd.Flags().StringVar(&modelName, "model", "gpt-4", "Model name for ROI calculation")
 	runCmd.Flags().BoolVar(&noKill, "no-kill", false, "Watchdog mode: log & alert on loops but don't kill the process")
+	runCmd.Flags().BoolVar(&pauseOnBreach, "pause-on-breach", false, "Freeze the process group (SIGSTOP) instead of killing it on a policy breach; resume via POST /v1/process/resume")
 	runCmd.Flags().BoolVar(&shadowMode, "shadow-mode", false, "Policy dry-run mode: evaluate actions but log-only for intervention")
 	runCmd.Flags().StringVar(&policyRollout, "policy-rollout", "", "Policy rollout mode: shadow, canary, enforce (default: enforce; shadow-mode remains backward-compatible)")
 	runCmd.Flags().IntVar(&policyCanaryPercent, "policy-canary-percent", -1, "Policy canary enforcement percentage (0-100). In canary mode, unsampled runs are log-only")
 	runCmd.Flags().StringVar(&injectFeedback, "inject-feedback", "", "Path to feedback file to inject into subprocess stdin")
 	runCmd.Flags().BoolVar(&deepWatch, "deep", false, "Enable Deep Watch (syscall monitoring)")
+	runCmd.Flags().StringVar(&restartPolicyFlag, "restart", "", "Restart policy when the command exits: never, on-failure, always, on-policy-breach (default: never)")
+	runCmd.Flags().IntVar(&restartMaxAttempts, "restart-max-attempts", -1, "Restarts allowed before the run enters CRASH_LOOP (default: 5)")
+	runCmd.Flags().StringVar(&maxRuntimeFlag, "max-runtime", "", "Wall-clock deadline for the whole run, e.g. 30m (alerts at 80%, then DEADLINE_EXCEEDED)")
+	runCmd.Flags().StringVar(&maxSilenceFlag, "max-silence", "", "Longest allowed gap between outputs, e.g. 5m (alerts at 80%, then OUTPUT_STALLED)")
+	runCmd.Flags().StringVar(&maxStuckFlag, "max-stuck", "", "Longest time the process tree may show no CPU, output, I/O or context-switch progress, e.g. 2m (alerts at 80%, then PROCESS_HUNG)")
+	runCmd.Flags().StringVar(&maxWriteRateFlag, "max-write-bytes-per-sec", "", "Highest storage write rate for the process tree, e.g. 50MB (then DISK_WRITE_LIMIT)")
+	runCmd.Flags().StringVar(&maxWriteBytesFlag, "max-write-bytes", "", "Most the process tree may write to storage per attempt, e.g. 2GB (then DISK_WRITE_LIMIT)")
+	runCmd.Flags().StringVar(&maxTokensFlag, "max-tokens", "", "Most tokens the run may produce across restarts, e.g. 200000 (alerts at budget-alert-percent, then BUDGET_EXCEEDED)")
+	runCmd.Flags().StringVar(&maxCostFlag, "max-cost-usd", "", "Most the run may spend at the --model price across restarts, e.g. 5 (alerts at budget-alert-percent, then BUDGET_EXCEEDED)")
+	runCmd.Flags().BoolVar(&useCgroup, "cgroup", false, "Enforce max-memory-mb, cpu-limit-percent and max-pids with a per-run cgroup v2 group (falls back to polling when not delegated)")
+	runCmd.Flags().BoolVar(&structuredLogs, "structured-logs", false, "Parse JSON output lines and detect repeated identical tool calls and errors (enables the tool-calls detector)")
+	runCmd.Flags().StringVar(&rulesFileFlag, "rules", "", "YAML file of detection rules checked against every output line (overrides rules-file)")
+	runCmd.Flags().StringVar(&recordPath, "record", "", "Write every output line, telemetry sample and decision to this file for flowforge replay")
+	runCmd.Flags().BoolVar(&standaloneRun, "standalone", false, "Serve the API from this run on port 8080 instead of reporting to a running daemon")
 }
 
 // LogObserver is a thread-safe bounded ring buffer for log lines.
@@ -89,9 +124,20 @@ type LogObserver struct {
 	isFull      bool         // Whether the buffer has wrapped
 	buf         bytes.Buffer // Partial line buffer
 	totalTokens int64
+	totalLines  int64
 	modelName   string
+	lastOutput  time.Time // Last Write of any bytes, complete line or not
+	engine      *detect.Engine
+	structured  bool              // Decode JSON object lines for the engine
+	recorder    *recording.Writer // Receives every complete line when recording
+	rules       *rules.Set        // User-defined rules checked on every line
+	progress    detect.ProgressTracker
 }
 
+// maxLineBytes caps a partial line. Output that goes this long without a
+// newline or carriage return is cut into lines of this size.
+const maxLineBytes = 64 << 10
+
 func NewLogObserver(capacity int, model string) *LogObserver {
 	if capacity <= 0 {
 		capacity = 10 // Safety floor
@@ -106,27 +152,78 @@ func NewLogObserver(capacity int, model string) *LogObserver {
 func (l *LogObserver) Write(p []byte) (n int, err error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	return l.write(&l.buf, "", p), nil
+}
+
+// Stream returns a writer that feeds the observer and tags its lines with
+// the given stream name ("stdout" or "stderr"). Each stream keeps its own
+// partial-line buffer, so interleaved writes do not splice lines together.
+func (l *LogObserver) Stream(name string) io.Writer {
+	return &streamWriter{observer: l, stream: name}
+}
+
+type streamWriter struct {
+	observer *LogObserver
+	stream   string
+	buf      bytes.Buffer
+}
 
-	n, _ = l.buf.Write(p)
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.observer.mu.Lock()
+	defer w.observer.mu.Unlock()
+	return w.observer.write(&w.buf, w.stream, p), nil
+}
+
+func (l *LogObserver) write(buf *bytes.Buffer, stream string, p []byte) int {
+	n, _ := buf.Write(p)
+	if n > 0 {
+		l.lastOutput = time.Now()
+	}
 
 	// Process lines from buffer
+scan:
 	for {
-		i := bytes.IndexByte(l.buf.Bytes(), '\n')
+		data := buf.Bytes()
+		i := bytes.IndexAny(data, "\r\n")
 		if i < 0 {
 			break
 		}
+		switch {
+		case data[i] == '\n':
+			l.addLine(string(data[:i]), stream)
+			buf.Next(i + 1)
+		case i+1 == len(data):
+			// Wait for the next write to tell \r\n from a lone \r.
+			break scan
+		case data[i+1] == '\n':
+			l.addLine(string(data[:i]), stream)
+			buf.Next(i + 2)
+		default:
+			// A lone \r redraws the line in place, as progress bars do: each
+			// revision is read for progress, and only the last one written
+			// before the newline becomes the line.
+			l.reviseLine(string(data[:i]))
+			buf.Next(i + 1)
+		}
+	}
+	for buf.Len() > maxLineBytes {
+		l.addLine(string(buf.Next(maxLineBytes)), stream)
+	}
 
-		line := l.buf.String()[:i]
-		l.addLine(line)
+	return n
+}
 
-		// Advance buffer
-		l.buf.Next(i + 1)
+// reviseLine takes an in-place revision of the current line. Revisions are
+// not lines: they only update the progress reading. Complete lines are
+// never read for progress, since a counter that moves across lines, like
+// "retry 2/3", is as likely a loop as a step forward.
+func (l *LogObserver) reviseLine(text string) {
+	if p, ok := detect.ParseProgress(text); ok {
+		l.progress.Observe(p, time.Now())
 	}
-
-	return n, nil
 }
 
-func (l *LogObserver) addLine(line string) {
+func (l *LogObserver) addLine(line, stream string) {
 	// Prevent token/key leakage to state/dashboard surfaces.
 	line = redact.Line(line)
 
@@ -137,15 +234,87 @@ func (l *LogObserver) addLine(line string) {
 		l.isFull = true
 	}
 
+	atomic.AddInt64(&l.totalLines, 1)
+
 	// Count tokens
 	count := tokens.Count(line, l.modelName)
 	atomic.AddInt64(&l.totalTokens, int64(count))
+
+	now := time.Now()
+	entry := engineLine(line, stream, l.structured, now)
+	if l.engine != nil {
+		l.engine.ObserveLine(entry)
+	}
+	if l.rules != nil {
+		l.rules.Observe(entry)
+	}
+	if l.recorder != nil {
+		l.recorder.Line(now, stream, line)
+	}
+}
+
+// engineLine is what the detection engine sees for one redacted line.
+func engineLine(line, stream string, structured bool, at time.Time) detect.Line {
+	entry := detect.Line{Text: line, Stream: stream, Time: at}
+	if structured {
+		entry.Fields = detect.ParseFields(line)
+	}
+	return entry
+}
+
+// FeedEngine forwards every complete, redacted line to e.
+func (l *LogObserver) FeedEngine(e *detect.Engine) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.engine = e
+}
+
+// ApplyRules checks every complete, redacted line against s.
+func (l *LogObserver) ApplyRules(s *rules.Set) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = s
+}
+
+// Record writes every complete, redacted line to w.
+func (l *LogObserver) Record(w *recording.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.recorder = w
+}
+
+// ParseJSON makes the observer decode JSON object lines before handing them
+// to the engine, for structured-log mode.
+func (l *LogObserver) ParseJSON(on bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.structured = on
 }
 
 func (l *LogObserver) TotalTokens() int64 {
 	return atomic.LoadInt64(&l.totalTokens)
 }
 
+// TotalLines returns how many complete lines the process has written.
+func (l *LogObserver) TotalLines() int64 {
+	return atomic.LoadInt64(&l.totalLines)
+}
+
+// ProgressAdvancedAt returns when a progress bar or counter in the output
+// last moved forward, or the zero time.
+func (l *LogObserver) ProgressAdvancedAt() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.progress.AdvancedAt()
+}
+
+// LastOutput returns when the process last wrote anything, or the zero time.
+func (l *LogObserver) LastOutput() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastOutput
+}
+
 func (l *LogObserver) GetLastLines(n int) []string {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -175,147 +344,9 @@ func (l *LogObserver) GetLastLines(n int) []string {
 	return result
 }
 
+// NormalizeLog is detect.NormalizeLog, kept for callers outside cmd.
 func NormalizeLog(line string) string {
-	// 1. Hex addresses: 0x...
-	reHex := regexp.MustCompile(`0x[0-9a-fA-F]+`)
-	line = reHex.ReplaceAllString(line, "<HEX>")
-
-	// 2. ISO 8601 Timestamps, local times:
-	reTime := regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T\s]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
-	line = reTime.ReplaceAllString(line, "<TIME>")
-
-	// Catch simple times like 12:34:56
-	reSimpleTime := regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}\b`)
-	line = reSimpleTime.ReplaceAllString(line, "<TIME>")
-
-	// 3. Numbers (integers and floats)
-	reNum := regexp.MustCompile(`\b\d+(\.\d+)?\b`)
-	line = reNum.ReplaceAllString(line, "<NUM>")
-
-	return line
-}
-
-func calculateDecisionScores(cpuUsage, threshold float64, lines []string) (cpuScore, entropyScore, confidence float64) {
-	if threshold <= 0 {
-		threshold = 100
-	}
-	cpuScore = (cpuUsage / threshold) * 100.0
-	if cpuScore > 100 {
-		cpuScore = 100
-	}
-	if cpuScore < 0 {
-		cpuScore = 0
-	}
-
-	if len(lines) == 0 {
-		entropyScore = 100
-	} else {
-		uniq := make(map[string]struct{}, len(lines))
-		for _, line := range lines {
-			uniq[NormalizeLog(line)] = struct{}{}
-		}
-		entropyScore = (float64(len(uniq)) / float64(len(lines))) * 100.0
-	}
-
-	// Confidence increases with CPU pressure and repetitive output (low entropy).
-	confidence = 0.65*cpuScore + 0.35*(100.0-entropyScore)
-	if confidence > 100 {
-		confidence = 100
-	}
-	if confidence < 0 {
-		confidence = 0
-	}
-
-	return cpuScore, entropyScore, confidence
-}
-
-func calculateRepetitionScore(lines []string) (firstNormalized string, repetitionScore float64) {
-	if len(lines) == 0 {
-		return "", 0
-	}
-	if len(lines) == 1 {
-		return NormalizeLog(lines[0]), 0
-	}
-
-	firstNormalized = NormalizeLog(lines[0])
-	stagnantMatches := 0
-	lev := metrics.NewLevenshtein()
-	for _, line := range lines[1:] {
-		currentNormalized := NormalizeLog(line)
-		if strutil.Similarity(firstNormalized, currentNormalized, lev) >= 0.9 {
-			stagnantMatches++
-		}
-	}
-
-	repetitionScore = float64(stagnantMatches) / float64(len(lines)-1)
-	return firstNormalized, repetitionScore
-}
-
-func rawDiversityScore(lines []string) float64 {
-	if len(lines) == 0 {
-		return 1.0
-	}
-	uniq := make(map[string]struct{}, len(lines))
-	for _, line := range lines {
-		uniq[line] = struct{}{}
-	}
-	return float64(len(uniq)) / float64(len(lines))
-}
-
-func detectProgressLikeOutput(lines []string) bool {
-	if len(lines) < 4 {
-		return false
-	}
-
-	progressHints := 0
-	numericLines := 0
-	increaseCount := 0
-	comparisons := 0
-
-	var prevNumber int
-	hasPrevNumber := false
-
-	for _, line := range lines {
-		lower := strings.ToLower(line)
-		if strings.Contains(lower, "progress") ||
-			strings.Contains(lower, "step=") ||
-			strings.Contains(lower, "tick=") ||
-			strings.Contains(lower, "phase=") ||
-			strings.Contains(lower, "heartbeat") {
-			progressHints++
-		}
-
-		match := firstNumberRegex.FindString(lower)
-		if match == "" {
-			continue
-		}
-
-		numericLines++
-		value, err := strconv.Atoi(match)
-		if err != nil {
-			continue
-		}
-
-		if hasPrevNumber {
-			comparisons++
-			if value > prevNumber {
-				increaseCount++
-			}
-		}
-
-		prevNumber = value
-		hasPrevNumber = true
-	}
-
-	if comparisons == 0 {
-		return false
-	}
-
-	progressHintRatio := float64(progressHints) / float64(len(lines))
-	numericCoverage := float64(numericLines) / float64(len(lines))
-	increaseRatio := float64(increaseCount) / float64(comparisons)
-
-	return progressHintRatio >= 0.40 && numericCoverage >= 0.70 && increaseRatio >= 0.70
+	return detect.NormalizeLog(line)
 }
 
 func resolvePolicyRolloutConfig() (policy.RolloutMode, int) {
@@ -368,13 +399,12 @@ func runProcess(args []string) {
 	}
 	defer database.CloseDB()
 
-	// Start API server in background
-	fmt.Println("[FlowForge] Starting API server on port 8080...")
-	stopAPI := api.Start("8080")
-	defer stopAPI()
-
-	// Pull known bad patterns on startup
-	blacklist := patterns.PullPatterns()
+	// Load known bad patterns on startup
+	knownPatterns, err := loadPatternWatch()
+	if err != nil {
+		fmt.Printf("Invalid pattern settings: %v\n", err)
+		os.Exit(1)
+	}
 
 	cmdName := args[0]
 	cmdArgs := args[1:]
@@ -400,468 +430,349 @@ func runProcess(args []string) {
 	fmt.Printf("[FlowForge] Config: max-cpu=%.1f%%, poll-interval=%dms, log-window=%d, no-kill=%v, policy-rollout=%s, policy-canary=%d%%\n",
 		maxCpu, pollInterval, logWindow, noKill, rolloutMode, canaryPercent)
 
-	// Create a context that can be cancelled
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	cmd := exec.Command(cmdName, cmdArgs...)
-
 	// Initialize LogObserver with profile-based capacity
 	observer := NewLogObserver(logWindow*2, modelName)
+	engine, err := newDetectionEngine(logWindow, structuredLogs)
+	if err != nil {
+		fmt.Printf("Invalid detector configuration: %v\n", err)
+		os.Exit(1)
+	}
+	observer.FeedEngine(engine)
+	observer.ParseJSON(structuredLogs)
+	ruleSet, err := loadRules()
+	if err != nil {
+		fmt.Printf("Invalid rules: %v\n", err)
+		os.Exit(1)
+	}
+	observer.ApplyRules(ruleSet)
 
 	// MultiWriter to print to stdout and capture in observer
-	stdoutWriter := io.MultiWriter(os.Stdout, observer)
-	stderrWriter := io.MultiWriter(os.Stderr, observer)
-
-	cmd.Stdout = stdoutWriter
-	cmd.Stderr = stderrWriter
+	stdoutWriter := io.MultiWriter(os.Stdout, observer.Stream("stdout"))
+	stderrWriter := io.MultiWriter(os.Stderr, observer.Stream("stderr"))
 
 	// Handle --inject-feedback: pipe feedback into subprocess stdin
+	feedbackContent := ""
 	if injectFeedback != "" {
-		feedbackContent, err := feedback.ReadFeedback(injectFeedback)
+		content, err := feedback.ReadFeedback(injectFeedback)
 		if err != nil {
 			fmt.Printf("[FlowForge] Warning: Could not read feedback file: %v\n", err)
 		} else {
 			fmt.Printf("[FlowForge] 💉 Injecting feedback from: %s\n", injectFeedback)
-			cmd.Stdin = strings.NewReader(feedbackContent)
+			feedbackContent = content
 			// Clean up after injection
 			defer feedback.CleanupFeedback(injectFeedback)
 		}
 	}
 
-	procSupervisor := supervisor.New(cmd)
-	if err := procSupervisor.Start(); err != nil {
+	restartCfg, err := resolveRestartConfig()
+	if err != nil {
+		fmt.Printf("Invalid restart configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if restartCfg.Policy != supervisor.RestartNever {
+		fmt.Printf("[FlowForge] Restart policy: %s (max-attempts=%d)\n", restartCfg.Policy, restartCfg.MaxAttempts)
+	}
+	runPolicy, err := newRunPolicy(pollInterval, logWindow, rolloutMode, canaryPercent, restartCfg.Policy.ShouldRestart(supervisor.ExitPolicyBreach))
+	if err != nil {
+		fmt.Printf("Invalid policy configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if runPolicy.MaxRuntime > 0 || runPolicy.MaxSilence > 0 || runPolicy.MaxStuck > 0 {
+		fmt.Printf("[FlowForge] Limits: max-runtime=%s, max-silence=%s, max-stuck=%s\n", runPolicy.MaxRuntime, runPolicy.MaxSilence, runPolicy.MaxStuck)
+	}
+	decider, err := newDecider(fullCommand)
+	if err != nil {
+		fmt.Printf("Invalid policy configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if runPolicy.MaxWriteBytesPerSec > 0 || runPolicy.MaxWriteBytes > 0 {
+		fmt.Printf("[FlowForge] Disk limits: max-write-bytes-per-sec=%.0f, max-write-bytes=%d\n", runPolicy.MaxWriteBytesPerSec, runPolicy.MaxWriteBytes)
+	}
+	if budget, _ := resolveBudget(); budget.Enabled() {
+		fmt.Printf("[FlowForge] Budget: %s at %s pricing\n", budget, modelName)
+	}
+	var recorder *recording.Writer
+	if recordPath != "" {
+		recorder, err = recording.Create(recordPath, recording.Header{
+			Command:      fullCommand,
+			RunID:        agentID,
+			PollInterval: pollInterval,
+			LogWindow:    logWindow,
+			MaxCPU:       maxCpu,
+			Model:        modelName,
+		}, startTime)
+		if err != nil {
+			fmt.Printf("Failed to create recording: %v\n", err)
+			os.Exit(1)
+		}
+		observer.Record(recorder)
+		fmt.Printf("[FlowForge] Recording to %s\n", recordPath)
+	}
+
+	database.SetRunID(agentID)
+	runCgroup := createRunCgroup(agentID)
+
+	// startAttempt launches one run of the command. Restarts get a fresh
+	// exec.Cmd but share the observer, cgroup and run ID.
+	startAttempt := func() (*supervisor.Supervisor, error) {
+		cmd := exec.Command(cmdName, cmdArgs...)
+		cmd.Stdout = stdoutWriter
+		cmd.Stderr = stderrWriter
+		if feedbackContent != "" {
+			cmd.Stdin = strings.NewReader(feedbackContent)
+		}
+
+		sup := supervisor.New(cmd)
+		sup.OnEscape(func(e supervisor.Escape) {
+			reason := fmt.Sprintf("pid %d (%s) %s", e.PID, e.Name, e.Reason)
+			fmt.Printf("[FlowForge] Descendant left the process group: %s; it will still be stopped with the run.\n", reason)
+			_ = database.LogAuditEvent("flowforge", "PROCESS_ESCAPED", reason, "supervisor", e.PID, fullCommand)
+		})
+		if runCgroup != nil {
+			sup.UseCgroup(runCgroup)
+		}
+		if err := sup.Start(); err != nil {
+			return nil, err
+		}
+		if runCgroup != nil && sup.Cgroup() == nil {
+			fmt.Printf("[FlowForge] Warning: Could not move process into cgroup, using polling limits: %v\n", sup.CgroupError())
+			_ = runCgroup.Remove()
+			runCgroup = nil
+		}
+		return sup, nil
+	}
+
+	procSupervisor, err := startAttempt()
+	if err != nil {
+		if runCgroup != nil {
+			_ = runCgroup.Remove()
+		}
 		fmt.Printf("Failed to start command: %v\n", err)
 		os.Exit(1)
 	}
+	var activeSupervisor atomic.Pointer[supervisor.Supervisor]
+	activeSupervisor.Store(procSupervisor)
 
 	pid := procSupervisor.PID()
 	fmt.Printf("Process started with PID: %d\n", pid)
-	database.SetRunID(agentID)
 	startWD, _ := os.Getwd()
-	api.RegisterExternalWorker(fullCommand, args, startWD, procSupervisor)
-	api.SetWorkerSpec(fullCommand, args, startWD)
-	state.UpdateState(0, "", "RUNNING", fullCommand, args, startWD, pid)
-	state.UpdateLifecycle("RUNNING", "RUNNING", pid)
-
-	var maxObservedCpu float64 = 0.0
-	var lastWatchdogAlert time.Time
-	var lastDecisionTrace time.Time
-	var watchdogEscalationLevel int = 0
-	var initialFDs int = 0
+	reporter := newRunReporter(agentID, fullCommand, args, startWD)
+	defer reporter.Close()
+
+	// serveAPI starts the embedded API when no daemon is attached, at
+	// startup or after the daemon connection is lost mid-run.
+	var embeddedAPI sync.Once
+	var stopAPI func()
+	serveAPI := func() {
+		embeddedAPI.Do(func() {
+			fmt.Println("[FlowForge] Starting API server on port 8080...")
+			stopAPI = api.Start("8080")
+		})
+	}
+	defer func() {
+		embeddedAPI.Do(func() {}) // waits for a start still in progress
+		if stopAPI != nil {
+			stopAPI()
+		}
+	}()
+
+	var userTerminated atomic.Bool
+	if !standaloneRun {
+		client, err := connectToDaemon(agentID, fullCommand, args, startWD, pid)
+		if err == nil {
+			reporter.attachWithControls(client, func() controlledProcess { return activeSupervisor.Load() }, func() {
+				fmt.Println("\n[FlowForge] Stop requested by daemon. Cleaning up process group...")
+				userTerminated.Store(true)
+			})
+			reporter.recoverWith(func() (*daemon.Client, error) {
+				return connectToDaemon(agentID, fullCommand, args, startWD, activeSupervisor.Load().PID())
+			}, func() {
+				serveAPI()
+				api.RegisterExternalWorker(fullCommand, args, startWD, activeSupervisor.Load())
+				api.SetWorkerSpec(fullCommand, args, startWD)
+			})
+			fmt.Println("[FlowForge] Reporting to running daemon.")
+		}
+	}
+	if !reporter.Attached() {
+		// No daemon: serve the API from this run, as before.
+		serveAPI()
+	}
+
 	var flowforgeTerminated atomic.Bool
-	var highCPUStart time.Time
-
-	cpuWindow := time.Duration(viper.GetInt("cpu-window-seconds")) * time.Second
-	if cpuWindow <= 0 {
-		cpuWindow = time.Duration(pollInterval*logWindow) * time.Millisecond
-	}
-	policyDecider := policy.NewThresholdDecider()
-	policyConfig := policy.Policy{
-		MaxCPUPercent:     maxCpu,
-		CPUWindow:         cpuWindow,
-		MinLogEntropy:     0.20,
-		MaxLogRepetition:  0.80,
-		MaxMemoryMB:       viper.GetFloat64("max-memory-mb"),
-		RestartOnBreach:   false,
-		ShadowMode:        shadowMode,
-		RolloutMode:       rolloutMode,
-		CanaryPercent:     canaryPercent,
-		DryRunEventType:   "policy_dry_run",
-		DryRunActor:       "system",
-		DryRunEventPrefix: "Policy dry-run",
-	}
-
-	// Create Monitor instance
-	monitor := sysmon.NewMonitor()
-
-	// CPU Monitoring Goroutine
-	go func() {
-		ticker := time.NewTicker(time.Duration(pollInterval) * time.Millisecond)
-		defer ticker.Stop()
-
-		p, err := process.NewProcess(int32(pid))
-		if err != nil {
-			fmt.Printf("[FlowForge] Error attaching monitor to PID %d: %v\n", pid, err)
-			return
+	mon := &processMonitor{
+		observer:     observer,
+		engine:       engine,
+		reporter:     reporter,
+		decider:      decider,
+		policy:       runPolicy,
+		sysMonitor:   sysmon.NewMonitor(),
+		cgroup:       runCgroup,
+		terminated:   &flowforgeTerminated,
+		command:      fullCommand,
+		agentID:      agentID,
+		agentVersion: agentVersion,
+		startTime:    startTime,
+		pollInterval: pollInterval,
+		logWindow:    logWindow,
+		patterns:     knownPatterns,
+		recorder:     recorder,
+		rules:        ruleSet,
+	}
+
+	// enterCrashLoop is given the PID of the attempt that exited last.
+	enterCrashLoop := func(reason string, lastPID int) {
+		fmt.Printf("\n[FlowForge] 🛑 CRASH_LOOP: %s (last pid %d)\n", reason, lastPID)
+		api.MarkWorkerCrashLoop(state.DefaultWorkerID, reason)
+		reporter.UpdateLifecycle("CRASH_LOOP", "CRASH_LOOP", 0)
+		_ = database.LogAuditEvent("flowforge", "CRASH_LOOP", reason, "supervisor", lastPID, fullCommand)
+	}
+
+	// recordExit stores how an attempt ended as a run_exit event, linked to
+	// the incident that ended it.
+	recordExit := func(sup *supervisor.Supervisor, attempt int, incidentID string) {
+		stopClass := database.ExitClassOperatorStop
+		if flowforgeTerminated.Load() {
+			stopClass = database.ExitClassFlowForgeKill
+		}
+		exit := database.NewRunExit(sup.ExitInfo(), sup.PID(), attempt, stopClass)
+		fmt.Printf("[FlowForge] Exit: %s, %s (cpu %.2fs, max rss %.1fMB)\n", exit.Class, exit.Summary, exit.CPUSeconds, exit.MaxRSSMB)
+		_ = database.LogRunExit(fullCommand, exit, incidentID)
+		// Only attempts that finished on their own and cleanly teach the
+		// baseline what normal looks like.
+		if stats := mon.takeRunStats(); exit.Class == string(supervisor.ExitClassClean) && len(stats) > 0 {
+			_ = learnBaseline(fullCommand, stats)
 		}
+	}
+	var userIncidentID atomic.Value
+	attemptIncidentID := func() string {
+		if id, _ := userIncidentID.Load().(string); id != "" {
+			return id
+		}
+		return mon.StopIncidentID()
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				if procSupervisor.Exited() {
-					return
-				}
-				cpuUsage, err := p.CPUPercent()
-				if err != nil {
-					continue
-				}
-
-				if cpuUsage > maxObservedCpu {
-					maxObservedCpu = cpuUsage
-				}
-
-				// Broadcast Live Stats (with PID)
-				lastLines := observer.GetLastLines(1)
-				lastLine := ""
-				if len(lastLines) > 0 {
-					lastLine = lastLines[0]
-				}
-
-				// Deep Watch (Syscall Monitoring)
-				isProbing := false
-				sysStatsStr := ""
-				if deepWatch {
-					stats, err := monitor.GetStats(pid)
-					if err == nil {
-						// Check for probing (sudden spike)
-						// Use monitor's internal logic
-						probing, details := monitor.DetectProbing(pid, stats)
-						if probing {
-							isProbing = true
-							sysStatsStr = details
-						}
-
-						if initialFDs == 0 {
-							initialFDs = stats.OpenFDs
-						}
-
-						// Also keep local logic? No, monitor does it.
-						// But existing code check:
-						/*
-							if stats.OpenFDs > initialFDs*2 && stats.OpenFDs > 50 {
-								isProbing = true
-							}
-						*/
-						// Let's rely on Monitor's return
-						if !isProbing {
-							// Display stats if not probing for debug?
-							sysStatsStr = fmt.Sprintf("FDs: %d | Sockets: %d", stats.OpenFDs, stats.SocketCount)
-						}
-					}
-				}
-
-				status := "RUNNING"
-				if isProbing {
-					status = "PROBING_DETECTED"
-					fmt.Printf("\n[FlowForge] 🚨 PROBING DETECTED: %s\n", sysStatsStr)
-				}
-
-				wd, _ := os.Getwd()
-				state.UpdateState(
-					cpuUsage,
-					lastLine,
-					status,
-					fullCommand,
-					args,
-					wd,
-					pid,
-				)
-
-				// Early blacklist check (even before high CPU)
-				if len(blacklist) > 0 {
-					recentLines := observer.GetLastLines(3)
-					for _, line := range recentLines {
-						normalized := NormalizeLog(line)
-						if patterns.IsBlacklisted(normalized, blacklist) {
-							fmt.Printf("\n⚡ EARLY WARNING: Output matches a known bad pattern from blacklist!\n")
-							fmt.Println("Pattern:", normalized)
-							break
-						}
-					}
-				}
-
-				if cpuUsage > maxCpu {
-					if highCPUStart.IsZero() {
-						highCPUStart = time.Now()
-					}
-				} else {
-					highCPUStart = time.Time{}
-				}
-
-				windowLines := observer.GetLastLines(logWindow)
-				if len(windowLines) == logWindow {
-					firstNormalized, repetitionScore := calculateRepetitionScore(windowLines)
-
-					cpuScore, entropyScore, confidenceScore := calculateDecisionScores(cpuUsage, maxCpu, windowLines)
-					rawDiversity := rawDiversityScore(windowLines)
-					progressLike := detectProgressLikeOutput(windowLines)
-					cpuOverFor := time.Duration(0)
-					if !highCPUStart.IsZero() {
-						cpuOverFor = time.Since(highCPUStart)
-					}
-					memMB := 0.0
-					if memInfo, err := p.MemoryInfo(); err == nil {
-						memMB = float64(memInfo.RSS) / 1024.0 / 1024.0
-					}
-
-					decision := policyDecider.Evaluate(policy.Telemetry{
-						CPUPercent:    cpuUsage,
-						CPUOverFor:    cpuOverFor,
-						MemoryMB:      memMB,
-						LogRepetition: repetitionScore,
-						LogEntropy:    entropyScore / 100.0,
-						RawDiversity:  rawDiversity,
-						ProgressLike:  progressLike,
-						RolloutKey:    agentID,
-					}, policyConfig)
-					reason := decision.Reason
-
-					if time.Since(lastDecisionTrace) > 5*time.Second || decision.Action != policy.ActionContinue {
-						_ = database.LogDecisionTrace(fullCommand, pid, cpuScore, entropyScore, confidenceScore, decision.Action.String(), reason)
-						lastDecisionTrace = time.Now()
-					}
-					state.UpdateDecision(reason, cpuScore, entropyScore, confidenceScore)
-
-					switch decision.Action {
-					case policy.ActionContinue:
-						// no-op
-					case policy.ActionAlert:
-						// WATCHDOG MODE: Escalation Logic
-						cooldown := 30 * time.Second
-						if watchdogEscalationLevel == 1 {
-							cooldown = 15 * time.Second
-						} else if watchdogEscalationLevel >= 2 {
-							cooldown = 5 * time.Second
-						}
-
-						if time.Since(lastWatchdogAlert) > cooldown {
-							lastWatchdogAlert = time.Now()
-							watchdogEscalationLevel++
-							incidentID := uuid.NewString()
-
-							alertType := "WATCHDOG_ALERT"
-							if watchdogEscalationLevel == 2 {
-								alertType = "WATCHDOG_WARN"
-							} else if watchdogEscalationLevel > 2 {
-								alertType = "WATCHDOG_CRITICAL"
-							}
-
-							if repetitionScore >= policyConfig.MaxLogRepetition {
-								patterns.SyncPatterns(firstNormalized)
-							}
-
-							fmt.Printf("\n🔍 WATCHDOG [%s]: Policy alert. Escalation Level %d.\n", alertType, watchdogEscalationLevel)
-							fmt.Println("Pattern (Normalized):", firstNormalized)
-							fmt.Printf("[FlowForge] Decision: CPU=%.1f Entropy=%.1f Confidence=%.1f\n", cpuScore, entropyScore, confidenceScore)
-
-							finalTokens := int(observer.TotalTokens())
-							finalCost := tokens.EstimateCost(finalTokens, modelName)
-
-							_ = database.LogDecisionTraceWithIncident(fullCommand, pid, cpuScore, entropyScore, confidenceScore, decision.Action.String(), reason, incidentID)
-							_ = database.LogIncidentWithDecisionForIncident(
-								fullCommand,
-								modelName,
-								alertType,
-								cpuUsage,
-								firstNormalized,
-								time.Since(startTime).Seconds(),
-								finalTokens,
-								finalCost,
-								agentID,
-								agentVersion,
-								reason,
-								cpuScore,
-								entropyScore,
-								confidenceScore,
-								"watchdog",
-								0,
-								incidentID,
-							)
-							_ = database.LogAuditEventWithIncident("flowforge", "WATCHDOG_ALERT", reason, "monitor", pid, fullCommand, incidentID)
-
-							wd, _ := os.Getwd()
-							state.UpdateState(
-								cpuUsage,
-								fmt.Sprintf("WATCHDOG: Policy alert (%s)", alertType),
-								alertType,
-								fullCommand,
-								args,
-								wd,
-								pid,
-							)
-						}
-					case policy.ActionLogOnly:
-						fmt.Printf("\n[FlowForge] 🧪 %s\n", reason)
-						incidentID := uuid.NewString()
-						_ = database.LogDecisionTraceWithIncident(fullCommand, pid, cpuScore, entropyScore, confidenceScore, decision.Action.String(), reason, incidentID)
-						_ = database.LogPolicyDryRunWithIncident(fullCommand, pid, reason, confidenceScore, incidentID)
-					case policy.ActionKill, policy.ActionRestart:
-						if noKill {
-							// Legacy watchdog mode always suppresses destructive actions.
-							fmt.Printf("\n[FlowForge] WATCHDOG MODE: %s\n", reason)
-							incidentID := uuid.NewString()
-							blockedReason := "watchdog mode blocked destructive action: " + reason
-							_ = database.LogDecisionTraceWithIncident(fullCommand, pid, cpuScore, entropyScore, confidenceScore, "ACTION_BLOCKED", blockedReason, incidentID)
-							_ = database.LogPolicyDryRunWithIncident(fullCommand, pid, blockedReason, confidenceScore, incidentID)
-							continue
-						}
-						incidentID := uuid.NewString()
-
-						patterns.SyncPatterns(firstNormalized)
-						feedback.GenerateFeedback(feedback.FeedbackData{
-							Command:    fullCommand,
-							Pattern:    firstNormalized,
-							ExitReason: "LOOP_DETECTED",
-							MaxCPU:     cpuUsage,
-							ModelName:  modelName,
-							Savings:    0,
-						})
-
-						actionName := "AUTO_KILL"
-						exitReason := "LOOP_DETECTED"
-						if decision.Action == policy.ActionRestart {
-							actionName = "AUTO_RESTART"
-							exitReason = "RESTART_TRIGGERED"
-						}
-
-						fmt.Printf("\n🚨 %s: %s\n", actionName, reason)
-						finalTokens := int(observer.TotalTokens())
-						finalCost := tokens.EstimateCost(finalTokens, modelName)
-
-						_ = database.LogDecisionTraceWithIncident(fullCommand, pid, cpuScore, entropyScore, confidenceScore, decision.Action.String(), reason, incidentID)
-						_ = database.LogIncidentWithDecisionForIncident(
-							fullCommand,
-							modelName,
-							exitReason,
-							cpuUsage,
-							firstNormalized,
-							time.Since(startTime).Seconds(),
-							finalTokens,
-							finalCost,
-							agentID,
-							agentVersion,
-							reason,
-							cpuScore,
-							entropyScore,
-							confidenceScore,
-							"terminated",
-							0,
-							incidentID,
-						)
-						_ = database.LogAuditEventWithIncident("flowforge", actionName, reason, "monitor", pid, fullCommand, incidentID)
-
-						wd, _ := os.Getwd()
-						state.UpdateState(
-							cpuUsage,
-							"POLICY ACTION - Terminating process group...",
-							exitReason,
-							fullCommand,
-							args,
-							wd,
-							pid,
-						)
-
-						flowforgeTerminated.Store(true)
-						_ = procSupervisor.Stop(2 * time.Second)
-						cancel()
-						fmt.Println("[FlowForge] Process group terminated after policy decision.")
-						return
-					}
-				}
-
-				if cpuUsage > maxCpu {
-					fmt.Printf("[FlowForge] WARNING: High CPU (%.2f%%) detected. %s\n", cpuUsage, sysStatsStr)
-				}
-
-				// --- SAFETY CHOKE POINT ---
-				// 1. Memory Limit
-				maxMemMB := viper.GetFloat64("max-memory-mb")
-				if maxMemMB > 0 {
-					memInfo, err := p.MemoryInfo()
-					if err == nil {
-						memMB := float64(memInfo.RSS) / 1024.0 / 1024.0
-						if memMB > maxMemMB {
-							fmt.Printf("\n[FlowForge] 🛑 SAFETY CHOKE: Memory usage (%.2f MB) exceeded limit (%.2f MB). TERMINATING.\n", memMB, maxMemMB)
-							// ... (rest of logic same)
-							finalTokens := int(observer.TotalTokens())
-							finalCost := tokens.EstimateCost(finalTokens, modelName)
-							database.LogIncident(fullCommand, modelName, "SAFETY_LIMIT_EXCEEDED", cpuUsage, fmt.Sprintf("Memory Limit: %.2fMB", memMB), time.Since(startTime).Seconds(), finalTokens, finalCost, agentID, agentVersion)
-
-							flowforgeTerminated.Store(true)
-							_ = procSupervisor.Stop(2 * time.Second)
-							cancel()
-							return
-						}
-					}
-				}
-
-				// 2. Token Rate Limit (Choke)
-				maxTokensRate := viper.GetFloat64("max-tokens-per-min")
-				if maxTokensRate > 0 {
-					currentTokens := observer.TotalTokens()
-					elapsedMin := time.Since(startTime).Minutes()
-					if elapsedMin > 0.1 { // Warmup 6s
-						rate := float64(currentTokens) / elapsedMin
-						if rate > maxTokensRate {
-							fmt.Printf("\n[FlowForge] 🛑 SAFETY CHOKE: Token generation rate (%.0f/min) exceeded limit (%.0f/min). TERMINATING.\n", rate, maxTokensRate)
-
-							finalTokens := int(currentTokens)
-							finalCost := tokens.EstimateCost(finalTokens, modelName)
-							database.LogIncident(fullCommand, modelName, "SAFETY_LIMIT_EXCEEDED", cpuUsage, fmt.Sprintf("Token Rate: %.0f/min", rate), time.Since(startTime).Seconds(), finalTokens, finalCost, agentID, agentVersion)
-
-							flowforgeTerminated.Store(true)
-							_ = procSupervisor.Stop(2 * time.Second)
-							cancel()
-							return
-						}
-					}
-				}
-			}
+	var waitErr error
+	var attempt int
+	exitRecorded := false
+	for attempt = 0; ; attempt++ {
+		pid := procSupervisor.PID()
+		if attempt > 0 {
+			fmt.Printf("Process restarted with PID: %d (restart %d)\n", pid, attempt)
 		}
-	}()
+		if !reporter.Attached() {
+			api.RegisterExternalWorker(fullCommand, args, startWD, procSupervisor)
+			api.SetWorkerSpec(fullCommand, args, startWD)
+		}
+		reporter.UpdateState(0, "", "RUNNING", pid)
+		reporter.UpdateLifecycle("RUNNING", "RUNNING", pid)
+
+		// Create a context that can be cancelled
+		ctx, cancel := context.WithCancel(context.Background())
+		flowforgeTerminated.Store(false)
+		mon.stopIncidentID.Store("")
+
+		// CPU Monitoring Goroutine
+		mon.target = procSupervisor
+		mon.cgroup = runCgroup
+		go mon.run(ctx, cancel)
+
+		untrap := procSupervisor.TrapSignals(3*time.Second, func(sig os.Signal) {
+			fmt.Printf("\n[FlowForge] Received signal: %v. Cleaning up process group...\n", sig)
+
+			userTerminated.Store(true)
+			incidentID := uuid.NewString()
+			userIncidentID.Store(incidentID)
+			finalTokens := int(observer.TotalTokens())
+			finalCost := tokens.EstimateCost(finalTokens, modelName)
+			_ = database.LogIncidentWithDecisionForIncident(fullCommand, modelName, "USER_TERMINATED", mon.maxObservedCPU, "N/A", time.Since(startTime).Seconds(), finalTokens, finalCost, agentID, agentVersion, "received OS signal", 0, 0, 0, "terminated", 0, incidentID)
+			_ = database.LogAuditEventWithIncident("operator", "TERMINATE", "received OS signal", "cli", pid, fullCommand, incidentID)
+
+			reporter.UpdateState(
+				0,
+				"",
+				"STOPPED",
+				pid,
+			)
+			cancel()
+		}, os.Interrupt, syscall.SIGTERM)
+
+		waitErr = procSupervisor.Wait()
+		cancel()
+		untrap()
 
-	var userTerminated atomic.Bool
-	untrap := procSupervisor.TrapSignals(3*time.Second, func(sig os.Signal) {
-		fmt.Printf("\n[FlowForge] Received signal: %v. Cleaning up process group...\n", sig)
-
-		userTerminated.Store(true)
-		incidentID := uuid.NewString()
-		finalTokens := int(observer.TotalTokens())
-		finalCost := tokens.EstimateCost(finalTokens, modelName)
-		_ = database.LogIncidentWithDecisionForIncident(fullCommand, modelName, "USER_TERMINATED", maxObservedCpu, "N/A", time.Since(startTime).Seconds(), finalTokens, finalCost, agentID, agentVersion, "received OS signal", 0, 0, 0, "terminated", 0, incidentID)
-		_ = database.LogAuditEventWithIncident("operator", "TERMINATE", "received OS signal", "cli", pid, fullCommand, incidentID)
-
-		wd, _ := os.Getwd()
-		state.UpdateState(
+		// Write STOPPED state on exit
+		reporter.UpdateState(
 			0,
 			"",
 			"STOPPED",
-			fullCommand,
-			args,
-			wd,
 			pid,
 		)
-		cancel()
-	}, os.Interrupt, syscall.SIGTERM)
-	defer untrap()
-
-	err := procSupervisor.Wait()
-	cancel()
-
-	// Write STOPPED state on exit
-	wd, _ := os.Getwd()
-	state.UpdateState(
-		0,
-		"",
-		"STOPPED",
-		fullCommand,
-		args,
-		wd,
-		pid,
-	)
 
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			isSignal := strings.Contains(exitErr.String(), "signal: killed") || strings.Contains(exitErr.String(), "signal: interrupt")
+		// Any stop FlowForge did not issue itself (API kill, daemon stop,
+		// Ctrl+C) is an operator stop and must not be restarted.
+		operatorStop := userTerminated.Load() || (procSupervisor.StopRequested() && !flowforgeTerminated.Load())
+		cause := classifyAttemptExit(waitErr, operatorStop, flowforgeTerminated.Load())
+		if !restartCfg.Policy.ShouldRestart(cause) || mon.runLimitReached.Load() {
+			break
+		}
+		restart := attempt + 1
+		delay, crashReason := nextRestart(restartCfg, attempt, cause)
+		if crashReason != "" {
+			enterCrashLoop(crashReason, pid)
+			break
+		}
+		recordExit(procSupervisor, attempt, attemptIncidentID())
+		exitRecorded = true
+		reporter.UpdateLifecycle("STARTING", "STARTING", 0)
+		fmt.Printf("[FlowForge] 🔁 Restarting after %s (attempt %d/%d) in %s...\n", cause, restart, restartCfg.MaxAttempts, delay.Round(time.Millisecond))
+		if !sleepUnlessSignalled(delay) || userTerminated.Load() {
+			userTerminated.Store(true)
+			break
+		}
+
+		next, err := startAttempt()
+		if err != nil {
+			fmt.Printf("Failed to restart command: %v\n", err)
+			waitErr = err
+			break
+		}
+		procSupervisor = next
+		activeSupervisor.Store(next)
+		exitRecorded = false
+	}
+	err = waitErr
+	pid = procSupervisor.PID()
+	if runCgroup != nil {
+		if rmErr := runCgroup.Remove(); rmErr != nil {
+			fmt.Printf("[FlowForge] Warning: %v\n", rmErr)
+		}
+	}
+	reporter.ReportExit(err)
+	if recorder != nil {
+		observer.Record(nil)
+		if recErr := recorder.Close(); recErr != nil {
+			fmt.Printf("[FlowForge] Warning: recording %s is incomplete: %v\n", recordPath, recErr)
+		}
+	}
 
-			if !userTerminated.Load() && !isSignal {
+	if !exitRecorded {
+		// An attempt that failed on its own gets an incident typed by how it
+		// ended; stops already have the incident that caused them.
+		incidentID := attemptIncidentID()
+		if info := procSupervisor.ExitInfo(); incidentID == "" && !userTerminated.Load() {
+			if exitReason := exitIncidentReason(info.Class); exitReason != "" {
+				incidentID = uuid.NewString()
 				finalTokens := int(observer.TotalTokens())
 				finalCost := tokens.EstimateCost(finalTokens, modelName)
-				_ = database.LogIncident(fullCommand, modelName, "COMMAND_FAILURE", maxObservedCpu, "N/A", time.Since(startTime).Seconds(), finalTokens, finalCost, agentID, agentVersion)
+				_ = database.LogIncidentWithDecisionForIncident(fullCommand, modelName, exitReason, mon.maxObservedCPU, "N/A", time.Since(startTime).Seconds(), finalTokens, finalCost, agentID, agentVersion, info.String(), 0, 0, 0, "", attempt, incidentID)
 			}
+		}
+		recordExit(procSupervisor, attempt, incidentID)
+	}
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
 			if flowforgeTerminated.Load() {
 				os.Exit(1)
 			}
@@ -875,7 +786,7 @@ func runProcess(args []string) {
 			if !userTerminated.Load() {
 				finalTokens := int(observer.TotalTokens())
 				finalCost := tokens.EstimateCost(finalTokens, modelName)
-				_ = database.LogIncident(fullCommand, modelName, "COMMAND_FAILURE", maxObservedCpu, "N/A", time.Since(startTime).Seconds(), finalTokens, finalCost, agentID, agentVersion)
+				_ = database.LogIncident(fullCommand, modelName, "COMMAND_FAILURE", mon.maxObservedCPU, "N/A", time.Since(startTime).Seconds(), finalTokens, finalCost, agentID, agentVersion)
 			}
 			os.Exit(1)
 		}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"errors"
+	"flowforge/internal/cgroup"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// cgroupLimitsFromConfig maps the active profile's hard limits onto cgroup files.
+func cgroupLimitsFromConfig() cgroup.Limits {
+	return cgroup.Limits{
+		MemoryMaxBytes: int64(viper.GetFloat64("max-memory-mb") * 1024 * 1024),
+		CPUPercent:     viper.GetFloat64("cpu-limit-percent"),
+		PidsMax:        viper.GetInt64("max-pids"),
+	}
+}
+
+// createRunCgroup creates the per-run cgroup when the backend is enabled.
+// It returns nil when cgroups are disabled or unavailable, in which case the
+// run falls back to polling the root PID.
+func createRunCgroup(runID string) *cgroup.Group {
+	if !useCgroup {
+		return nil
+	}
+	limits := cgroupLimitsFromConfig()
+	g, err := cgroup.Create(cgroup.Config{
+		Parent: viper.GetString("cgroup-parent"),
+		Name:   "flowforge-" + runID,
+		Limits: limits,
+	})
+	if err != nil {
+		if errors.Is(err, cgroup.ErrUnavailable) {
+			fmt.Printf("[FlowForge] cgroup backend unavailable, using polling limits: %v\n", err)
+		} else {
+			fmt.Printf("[FlowForge] Warning: Failed to create cgroup, using polling limits: %v\n", err)
+		}
+		return nil
+	}
+	fmt.Printf("[FlowForge] cgroup enforcement: %s (memory.max=%d cpu=%.0f%% pids.max=%d)\n",
+		g.Path(), limits.MemoryMaxBytes, limits.CPUPercent, limits.PidsMax)
+	return g
+}
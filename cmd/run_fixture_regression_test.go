@@ -3,6 +3,7 @@ package cmd
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"os/exec"
 	"path/filepath"
 	"runtime"
@@ -10,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"flowforge/internal/detect"
 	"flowforge/internal/policy"
 )
 
@@ -72,8 +74,8 @@ func TestHealthySpikeFixtureRemainsNonDestructive(t *testing.T) {
 		t.Fatalf("expected at least 5 lines from healthy fixture, got %d", len(window))
 	}
 
-	rawDiversity := rawDiversityScore(window)
-	progressLike := detectProgressLikeOutput(window)
+	rawDiversity := detect.RawDiversity(window)
+	progressLike := detect.ProgressLike(window)
 	if !progressLike {
 		t.Fatalf("expected healthy spike fixture to look like progress; window=%v", window)
 	}
@@ -111,8 +113,8 @@ func TestInfiniteLooperFixtureStillDestructive(t *testing.T) {
 		t.Fatalf("expected at least 5 lines from infinite fixture, got %d", len(window))
 	}
 
-	rawDiversity := rawDiversityScore(window)
-	progressLike := detectProgressLikeOutput(window)
+	rawDiversity := detect.RawDiversity(window)
+	progressLike := detect.ProgressLike(window)
 	if progressLike {
 		t.Fatalf("expected infinite looper not to look like progress; window=%v", window)
 	}
@@ -139,3 +141,101 @@ func TestInfiniteLooperFixtureStillDestructive(t *testing.T) {
 		t.Fatalf("expected KILL for infinite looper, got %s", decision.Action.String())
 	}
 }
+
+func TestStructuredLogObserverFeedsToolCallDetector(t *testing.T) {
+	engine, err := newDetectionEngine(4, true)
+	if err != nil {
+		t.Fatalf("newDetectionEngine: %v", err)
+	}
+	observer := NewLogObserver(8, "gpt-4")
+	observer.FeedEngine(engine)
+	observer.ParseJSON(true)
+
+	for i := 0; i < 4; i++ {
+		line := `{"event":"tool_call","tool":"search","args":{"q":"retry policy","page":2}}` + "\n"
+		if i%2 == 1 {
+			line = "thinking...\n"
+		}
+		if _, err := observer.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	// Two calls stay below the default min-repeats of 3.
+	if r := engine.Evaluate(detect.Sample{}); r.Telemetry.LoopTool != "" {
+		t.Fatalf("expected no tool loop yet, got %+v", r.Telemetry)
+	}
+	_, _ = observer.Write([]byte(`{"event":"tool_call","tool":"search","args":{"page":2,"q":"retry policy"}}` + "\n"))
+	_, _ = observer.Write([]byte(`{"event":"tool_call","tool":"search","args":{"q":"retry policy","page":2}}` + "\n"))
+
+	r := engine.Evaluate(detect.Sample{})
+	if r.Telemetry.LoopTool != "search" || r.Pattern != `tool_call search {"page":2,"q":"retry policy"}` {
+		t.Fatalf("expected the search loop, got pattern %q telemetry %+v", r.Pattern, r.Telemetry)
+	}
+}
+
+func TestLogObserverStreamsKeepLinesApart(t *testing.T) {
+	engine := detect.NewEngine(4)
+	observer := NewLogObserver(8, "gpt-4")
+	observer.FeedEngine(engine)
+	stdout, stderr := observer.Stream("stdout"), observer.Stream("stderr")
+
+	trace := "Traceback (most recent call last):\n  File \"agent.py\", line 3, in <module>\n    step()\nRuntimeError: boom\n"
+	for i := 0; i < 3; i++ {
+		// Partial writes on one stream must not splice into the other.
+		_, _ = stdout.Write([]byte("working on step "))
+		_, _ = stderr.Write([]byte(trace))
+		_, _ = stdout.Write([]byte("7\n"))
+	}
+
+	lines := observer.GetLastLines(8)
+	if got := lines[len(lines)-1]; got != "working on step 7" {
+		t.Fatalf("expected the stdout line intact, got %q", got)
+	}
+	r := engine.Evaluate(detect.Sample{Time: time.Now()})
+	if r.Trace == nil || r.Trace.Count != 3 || r.Pattern != "python:RuntimeError @ agent.py:<module>" {
+		t.Fatalf("expected the stderr traceback three times, got pattern %q trace %+v", r.Pattern, r.Trace)
+	}
+}
+
+func TestLogObserverTreatsCarriageReturnsAsRevisions(t *testing.T) {
+	observer := NewLogObserver(8, "gpt-4")
+	stderr := observer.Stream("stderr")
+
+	// tqdm redraws with a leading \r and only ends the bar with a newline.
+	for i := 0; i <= 1000; i++ {
+		_, _ = stderr.Write([]byte(fmt.Sprintf("\rtraining: %3d%%|%-10s| %d/1000", i/10, strings.Repeat("#", i/100), i)))
+	}
+	if observer.TotalLines() != 0 {
+		t.Fatalf("expected revisions not to count as lines, got %d", observer.TotalLines())
+	}
+	if sw := stderr.(*streamWriter); sw.buf.Len() > 64 {
+		t.Fatalf("expected the partial line to stay bounded, got %d bytes", sw.buf.Len())
+	}
+	if observer.ProgressAdvancedAt().IsZero() {
+		t.Fatal("expected the bar to register as advancing progress")
+	}
+	_, _ = stderr.Write([]byte("\n"))
+	_, _ = observer.Write([]byte("windows line\r"))
+	_, _ = observer.Write([]byte("\nlast\r\n"))
+
+	lines := observer.GetLastLines(8)
+	want := []string{"training: 100%|##########| 1000/1000", "windows line", "last"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected the final revision and CRLF lines, got %q", lines)
+	}
+
+	_, _ = observer.Write(bytes.Repeat([]byte("x"), maxLineBytes+10))
+	if observer.TotalLines() != 4 {
+		t.Fatalf("expected an overlong partial line to be cut, got %d lines", observer.TotalLines())
+	}
+}
+
+func TestLogObserverIgnoresCountersAcrossLines(t *testing.T) {
+	observer := NewLogObserver(8, "gpt-4")
+	for i := 1; i <= 3; i++ {
+		_, _ = fmt.Fprintf(observer, "request failed, retry %d/3\n", i)
+	}
+	if !observer.ProgressAdvancedAt().IsZero() {
+		t.Fatal("expected a retry counter in complete lines not to count as progress")
+	}
+}
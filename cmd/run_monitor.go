@@ -0,0 +1,812 @@
+package cmd
+
+import (
+	"context"
+	"flowforge/internal/api"
+	"flowforge/internal/cgroup"
+	"flowforge/internal/database"
+	"flowforge/internal/detect"
+	"flowforge/internal/feedback"
+	"flowforge/internal/policy"
+	"flowforge/internal/recording"
+	"flowforge/internal/rules"
+	"flowforge/internal/state"
+	"flowforge/internal/sysmon"
+	"flowforge/internal/tokens"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// monitoredProcess is what the policy loop needs from the process it
+// watches: a child started by `run` or a foreign PID joined by `attach`.
+type monitoredProcess interface {
+	PID() int
+	Exited() bool
+	Paused() bool
+	Pause() error
+	Stop(grace time.Duration) error
+}
+
+// processMonitor samples a process tree every poll interval, feeds the
+// policy decider and applies its decision. Counters that span restarts
+// (escalation level, peak CPU) live on the struct; each run call starts a
+// fresh CPU window for the current target.
+type processMonitor struct {
+	target     monitoredProcess
+	observer   *LogObserver
+	engine     *detect.Engine // Fed by observer
+	reporter   *runReporter
+	decider    policy.Decider
+	policy     policy.Policy
+	sysMonitor *sysmon.Monitor
+	cgroup     *cgroup.Group
+	terminated *atomic.Bool // Set before FlowForge stops the target itself
+
+	command      string
+	agentID      string
+	agentVersion string
+	startTime    time.Time
+	pollInterval int
+	logWindow    int
+	patterns     *patternWatch
+	recorder     *recording.Writer // Set by run --record; nil otherwise
+	rules        *rules.Set        // Fed by observer; nil without a rules file
+
+	maxObservedCPU          float64
+	lastWatchdogAlert       time.Time
+	lastDecisionTrace       time.Time
+	watchdogEscalationLevel int
+	lastCgroupStats         cgroup.Stats
+	lastThrottleNotice      time.Time
+
+	// runLimitReached is set when max-runtime or a run budget stops the
+	// target; a restart would still be past the limit.
+	runLimitReached atomic.Bool
+	// stopIncidentID is the incident that made FlowForge stop the current
+	// target, so its run_exit event can point at it.
+	stopIncidentID atomic.Value
+
+	// runStats accumulates the current attempt's telemetry for learned
+	// baselines; see takeRunStats.
+	statsMu  sync.Mutex
+	runStats policy.RunStats
+}
+
+// stopTarget stops the target on behalf of the incident that required it.
+func (m *processMonitor) stopTarget(incidentID string) {
+	m.stopIncidentID.Store(incidentID)
+	m.terminated.Store(true)
+	_ = m.target.Stop(2 * time.Second)
+}
+
+// StopIncidentID returns the incident behind the last FlowForge stop of the
+// current target, or "".
+func (m *processMonitor) StopIncidentID() string {
+	id, _ := m.stopIncidentID.Load().(string)
+	return id
+}
+
+// observeRunStats records one sample of the current attempt for the
+// command's learned baseline.
+func (m *processMonitor) observeRunStats(t policy.Telemetry, withOutput bool) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	if m.runStats == nil {
+		m.runStats = make(policy.RunStats)
+	}
+	m.runStats.Observe(t, withOutput)
+}
+
+// takeRunStats returns what the current attempt recorded and starts afresh
+// for the next one.
+func (m *processMonitor) takeRunStats() policy.RunStats {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	stats := m.runStats
+	m.runStats = nil
+	return stats
+}
+
+// newRunPolicy builds the decider policy from the active config.
+func newRunPolicy(pollInterval, logWindow int, rolloutMode policy.RolloutMode, canaryPercent int, restartOnBreach bool) (policy.Policy, error) {
+	maxRuntime, err := resolveDurationSetting(maxRuntimeFlag, "max-runtime")
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	maxSilence, err := resolveDurationSetting(maxSilenceFlag, "max-silence")
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	maxStuck, err := resolveDurationSetting(maxStuckFlag, "max-stuck")
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	maxWriteRate, err := resolveSizeSetting(maxWriteRateFlag, "max-write-bytes-per-sec")
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	maxWriteBytes, err := resolveSizeSetting(maxWriteBytesFlag, "max-write-bytes")
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	budget, err := resolveBudget()
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	cpuWindow := time.Duration(viper.GetInt("cpu-window-seconds")) * time.Second
+	if cpuWindow <= 0 {
+		cpuWindow = time.Duration(pollInterval*logWindow) * time.Millisecond
+	}
+	return policy.Policy{
+		MaxCPUPercent:       maxCpu,
+		CPUWindow:           cpuWindow,
+		MinLogEntropy:       0.20,
+		MaxLogRepetition:    0.80,
+		MaxMemoryMB:         viper.GetFloat64("max-memory-mb"),
+		RestartOnBreach:     restartOnBreach,
+		PauseOnBreach:       pauseOnBreach,
+		ShadowMode:          shadowMode,
+		RolloutMode:         rolloutMode,
+		CanaryPercent:       canaryPercent,
+		MaxRuntime:          maxRuntime,
+		MaxSilence:          maxSilence,
+		MaxStuck:            maxStuck,
+		MaxWriteBytesPerSec: float64(maxWriteRate),
+		MaxWriteBytes:       maxWriteBytes,
+		MaxTokens:           budget.MaxTokens,
+		MaxCostUSD:          budget.MaxCostUSD,
+		BudgetAlertPercent:  budget.AlertPercent,
+		PauseOnBudget:       budget.Pause,
+		DryRunEventType:     "policy_dry_run",
+		DryRunActor:         "system",
+		DryRunEventPrefix:   "Policy dry-run",
+	}, nil
+}
+
+// recordCrashSignature stores the recurring stack trace behind an
+// output-driven incident, when the traceback detector found one.
+func recordCrashSignature(command string, detection detect.Result, breach, incidentID string) {
+	if detection.Trace == nil || breach != "" {
+		return
+	}
+	t := detection.Trace
+	_ = database.LogCrashSignature(command, database.CrashSignature{
+		Fingerprint: t.Fingerprint,
+		Language:    t.Language,
+		Exception:   t.Exception,
+		Frames:      t.Frames,
+		Count:       t.Count,
+		Sample:      strings.Join(t.Lines, "\n"),
+	}, incidentID)
+}
+
+// newDetectionEngine builds the detection engine from the `detectors` and
+// `detector-settings` config keys; without them it runs the defaults.
+// Structured-log mode adds the tool-calls detector when it is not listed.
+func newDetectionEngine(logWindow int, structured bool) (*detect.Engine, error) {
+	names := detect.DefaultNames()
+	if viper.IsSet("detectors") {
+		names = viper.GetStringSlice("detectors")
+	}
+	if structured && !slices.Contains(names, "tool-calls") {
+		names = append(names, "tool-calls")
+	}
+	detectors, err := detect.Build(names, detectorSettings())
+	if err != nil {
+		return nil, err
+	}
+	return detect.NewEngine(logWindow, detectors...), nil
+}
+
+// detectorSettings returns the per-detector blocks under detector-settings.
+func detectorSettings() map[string]map[string]interface{} {
+	out := make(map[string]map[string]interface{})
+	for name := range viper.GetStringMap("detector-settings") {
+		out[name] = viper.GetStringMap("detector-settings." + name)
+	}
+	return out
+}
+
+// resolveDurationSetting prefers the flag value, then config/profile.
+func resolveDurationSetting(flagValue, key string) (time.Duration, error) {
+	raw := flagValue
+	if raw == "" {
+		raw = viper.GetString(key)
+	}
+	d, err := parseDurationSetting(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s %v", key, err)
+	}
+	return d, nil
+}
+
+// resolveSizeSetting prefers the flag value, then config/profile.
+func resolveSizeSetting(flagValue, key string) (uint64, error) {
+	raw := flagValue
+	if raw == "" {
+		raw = viper.GetString(key)
+	}
+	n, err := parseSizeSetting(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s %v", key, err)
+	}
+	return n, nil
+}
+
+// judge consults the decider on one detection result, as every poll does.
+// It reports false for ticks the policy sits out: before the log window
+// fills, unless a wall-clock, disk or budget limit needs checking anyway.
+func judge(decider policy.Decider, p policy.Policy, detection detect.Result, rolloutKey string) (policy.Telemetry, policy.Decision, bool) {
+	telemetry := detection.Telemetry
+	telemetry.RolloutKey = rolloutKey
+	active := p
+	if !detection.WindowFull {
+		if p.MaxRuntime == 0 && p.MaxSilence == 0 && p.MaxStuck == 0 && p.MaxWriteBytesPerSec == 0 && p.MaxWriteBytes == 0 && p.MaxTokens == 0 && p.MaxCostUSD == 0 && len(telemetry.Rules) == 0 && len(telemetry.KnownPatterns) == 0 {
+			return telemetry, policy.Decision{}, false
+		}
+		active = livenessOnly(p)
+	}
+	return telemetry, decider.Evaluate(telemetry, active), true
+}
+
+// progressAge is how long before now progress last moved forward, for
+// recordings; 0 means it never has.
+func progressAge(now, at time.Time) float64 {
+	if at.IsZero() {
+		return 0
+	}
+	return max(now.Sub(at).Seconds(), 0.001)
+}
+
+// cpuOverClock measures how long CPU has stayed above the threshold.
+type cpuOverClock struct {
+	since time.Time
+}
+
+// Observe records a reading at now and returns how long CPU has been over
+// threshold without a break; zero when this reading is not over.
+func (c *cpuOverClock) Observe(now time.Time, cpu, threshold float64) time.Duration {
+	if cpu <= threshold {
+		c.since = time.Time{}
+		return 0
+	}
+	if c.since.IsZero() {
+		c.since = now
+	}
+	return now.Sub(c.since)
+}
+
+// Reset restarts the clock, for example after the target was frozen.
+func (c *cpuOverClock) Reset() {
+	c.since = time.Time{}
+}
+
+// livenessOnly keeps just the wall-clock, disk-write and budget limits, for
+// ticks where there is not yet enough output to judge loops. Disk counters
+// do not depend on output, and a runaway writer is often silent.
+func livenessOnly(p policy.Policy) policy.Policy {
+	p.MaxCPUPercent = 0
+	p.MaxMemoryMB = 0
+	p.MaxLogRepetition = 0
+	p.MinLogEntropy = 0
+	return p
+}
+
+// recordedDecision is decision as run --record stores it.
+func recordedDecision(d policy.Decision, pattern string) recording.Decision {
+	rd := recording.Decision{
+		Action:  d.Action.String(),
+		Reason:  d.Reason,
+		Breach:  d.Breach,
+		Pattern: pattern,
+	}
+	if d.IntendedAction != d.Action {
+		rd.IntendedAction = d.IntendedAction.String()
+	}
+	return rd
+}
+
+// counterRate turns a running count into a rate over the last few seconds,
+// so one burst between polls does not read as a flood.
+type counterRate struct {
+	points []countPoint
+}
+
+type countPoint struct {
+	at    time.Time
+	count int64
+}
+
+const counterRateWindow = 5 * time.Second
+
+// Observe records count at now and returns the per-second rate since the
+// oldest point still inside the window.
+func (r *counterRate) Observe(now time.Time, count int64) float64 {
+	r.points = append(r.points, countPoint{at: now, count: count})
+	for len(r.points) > 2 && now.Sub(r.points[1].at) >= counterRateWindow {
+		r.points = r.points[1:]
+	}
+	first := r.points[0]
+	secs := now.Sub(first.at).Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(count-first.count) / secs
+}
+
+// latestTime returns the most recent of ts.
+func latestTime(ts ...time.Time) time.Time {
+	var latest time.Time
+	for _, t := range ts {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// run blocks until ctx is cancelled, the target exits, or a policy action
+// stops it. cancel is called whenever the monitor stops the target.
+func (m *processMonitor) run(ctx context.Context, cancel context.CancelFunc) {
+	pid := m.target.PID()
+	var initialFDs int
+	var cpuOver cpuOverClock
+	// Silence is measured per attempt, and time spent frozen does not count.
+	attemptStart := time.Now()
+	var lastPaused time.Time
+
+	ticker := time.NewTicker(time.Duration(m.pollInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	// Sample the whole tree: launchers like `bash -c` or Python wrappers
+	// often sit idle while a descendant does the work.
+	sampler := sysmon.NewTreeSampler(pid)
+	var hang sysmon.HangTracker
+	var lines counterRate
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.target.Exited() {
+				return
+			}
+			if m.target.Paused() {
+				// A frozen group has no CPU or output to judge; restart
+				// the CPU window once it is resumed.
+				cpuOver.Reset()
+				lastPaused = time.Now()
+				if m.recorder != nil {
+					m.recorder.Sample(lastPaused, recording.Sample{Paused: true})
+				}
+				continue
+			}
+			tree, err := sampler.Sample()
+			if err != nil {
+				continue
+			}
+			cpuUsage := tree.CPU
+
+			if cpuUsage > m.maxObservedCPU {
+				m.maxObservedCPU = cpuUsage
+			}
+
+			// Broadcast Live Stats (with PID)
+			lastLines := m.observer.GetLastLines(1)
+			lastLine := ""
+			if len(lastLines) > 0 {
+				lastLine = lastLines[0]
+			}
+
+			// Deep Watch (Syscall Monitoring)
+			isProbing := false
+			sysStatsStr := ""
+			if deepWatch {
+				stats, err := m.sysMonitor.GetStats(pid)
+				if err == nil {
+					// Check for probing (sudden spike)
+					// Use monitor's internal logic
+					probing, details := m.sysMonitor.DetectProbing(pid, stats)
+					if probing {
+						isProbing = true
+						sysStatsStr = details
+					}
+
+					if initialFDs == 0 {
+						initialFDs = stats.OpenFDs
+					}
+
+					// Also keep local logic? No, monitor does it.
+					// But existing code check:
+					/*
+						if stats.OpenFDs > initialFDs*2 && stats.OpenFDs > 50 {
+							isProbing = true
+						}
+					*/
+					// Let's rely on Monitor's return
+					if !isProbing {
+						// Display stats if not probing for debug?
+						sysStatsStr = fmt.Sprintf("FDs: %d | Sockets: %d | Writes: %.1fMB/s", stats.OpenFDs, stats.SocketCount, stats.IO.WriteBytesPerSec/(1<<20))
+					}
+				}
+			}
+
+			status := "RUNNING"
+			if isProbing {
+				status = "PROBING_DETECTED"
+				fmt.Printf("\n[FlowForge] 🚨 PROBING DETECTED: %s\n", sysStatsStr)
+			}
+
+			m.reporter.UpdateState(
+				cpuUsage,
+				lastLine,
+				status,
+				pid,
+			)
+			m.reporter.UpdateTree(tree)
+			spentTokens := m.observer.TotalTokens()
+			spentUSD := tokens.EstimateCost(int(spentTokens), modelName)
+			m.reporter.UpdateSpend(spentTokens, spentUSD, m.policy.MaxTokens, m.policy.MaxCostUSD)
+
+			// Known pattern check (even before high CPU)
+			knownPatterns := m.patterns.Check(m.observer)
+
+			cpuOverFor := cpuOver.Observe(time.Now(), cpuUsage, maxCpu)
+			lastActivity := latestTime(attemptStart, lastPaused, m.observer.LastOutput())
+			stuckFor := hang.Observe(time.Now(), tree, lastActivity)
+			stuckState := ""
+			if stuckFor > 0 {
+				stuckState = tree.WaitState()
+			}
+			treeIO := m.sysMonitor.TreeIO(pid, time.Now(), tree.IOByPID)
+			fired := m.rules.Take()
+			logRuleFirings(m.command, pid, fired)
+			sampledAt := time.Now()
+			progressAt := m.observer.ProgressAdvancedAt()
+			if m.recorder != nil {
+				m.recorder.Sample(sampledAt, recording.Sample{
+					CPUPercent:         cpuUsage,
+					MemoryMB:           tree.RSSMB,
+					StuckForSeconds:    stuckFor.Seconds(),
+					StuckState:         stuckState,
+					WriteBytesPerSec:   treeIO.WriteBytesPerSec,
+					WriteBytes:         treeIO.Total.WriteBytes,
+					ProgressAgeSeconds: progressAge(sampledAt, progressAt),
+				})
+			}
+			detection := m.engine.Evaluate(detect.Sample{
+				Time:               sampledAt,
+				CPUPercent:         cpuUsage,
+				CPUThreshold:       maxCpu,
+				CPUOverFor:         cpuOverFor,
+				MemoryMB:           tree.RSSMB,
+				Runtime:            time.Since(m.startTime),
+				SilentFor:          time.Since(lastActivity),
+				StuckFor:           stuckFor,
+				StuckState:         stuckState,
+				WriteBytesPerSec:   treeIO.WriteBytesPerSec,
+				WriteBytes:         treeIO.Total.WriteBytes,
+				LinesPerSec:        lines.Observe(time.Now(), m.observer.TotalLines()),
+				ProgressAdvancedAt: progressAt,
+				Rules:              ruleMatches(fired),
+				KnownPatterns:      knownPatterns,
+				Tokens:             spentTokens,
+				CostUSD:            spentUSD,
+			})
+			m.observeRunStats(detection.Telemetry, detection.WindowFull)
+			windowFull := detection.WindowFull
+			if telemetry, decision, judged := judge(m.decider, m.policy, detection, m.agentID); judged {
+				if m.recorder != nil {
+					m.recorder.Decision(sampledAt, recordedDecision(decision, detection.Pattern))
+				}
+				firstNormalized := detection.Pattern
+				cpuScore, entropyScore, confidenceScore := detection.CPUScore, detection.EntropyScore, detection.Confidence
+				reason := decision.Reason
+
+				// Before the log window fills, only limit decisions are worth tracing.
+				if windowFull || decision.Action != policy.ActionContinue {
+					if time.Since(m.lastDecisionTrace) > 5*time.Second || decision.Action != policy.ActionContinue {
+						_ = database.LogDecisionTraceWithProcesses(m.command, pid, cpuScore, entropyScore, confidenceScore, decision.Action.String(), reason, "", tree.Processes)
+						m.lastDecisionTrace = time.Now()
+					}
+					m.reporter.UpdateDecision(decision.Action.String(), reason, cpuScore, entropyScore, confidenceScore)
+				}
+
+				// Incidents for wall-clock limits are typed by the limit and
+				// carry no output pattern; a known pattern breach carries the
+				// pattern it matched.
+				incidentPattern := firstNormalized
+				if decision.Breach == policy.BreachKnownPattern {
+					incidentPattern = telemetry.KnownPatterns[0].Pattern
+				} else if decision.Breach != "" || incidentPattern == "" {
+					incidentPattern = "N/A"
+				}
+
+				switch decision.Action {
+				case policy.ActionContinue:
+					// no-op
+				case policy.ActionAlert:
+					// WATCHDOG MODE: Escalation Logic
+					cooldown := 30 * time.Second
+					if m.watchdogEscalationLevel == 1 {
+						cooldown = 15 * time.Second
+					} else if m.watchdogEscalationLevel >= 2 {
+						cooldown = 5 * time.Second
+					}
+
+					if time.Since(m.lastWatchdogAlert) > cooldown {
+						m.lastWatchdogAlert = time.Now()
+						m.watchdogEscalationLevel++
+						incidentID := uuid.NewString()
+
+						alertType := "WATCHDOG_ALERT"
+						if m.watchdogEscalationLevel == 2 {
+							alertType = "WATCHDOG_WARN"
+						} else if m.watchdogEscalationLevel > 2 {
+							alertType = "WATCHDOG_CRITICAL"
+						}
+
+						if telemetry.LogRepetition >= m.policy.MaxLogRepetition {
+							m.patterns.Learn(firstNormalized, m.command, telemetry)
+						}
+
+						fmt.Printf("\n🔍 WATCHDOG [%s]: Policy alert. Escalation Level %d.\n", alertType, m.watchdogEscalationLevel)
+						if firstNormalized != "" {
+							fmt.Println("Pattern (Normalized):", firstNormalized)
+						}
+						fmt.Printf("[FlowForge] Decision: CPU=%.1f Entropy=%.1f Confidence=%.1f\n", cpuScore, entropyScore, confidenceScore)
+
+						finalTokens := int(m.observer.TotalTokens())
+						finalCost := tokens.EstimateCost(finalTokens, modelName)
+
+						_ = database.LogDecisionTraceWithProcesses(m.command, pid, cpuScore, entropyScore, confidenceScore, decision.Action.String(), reason, incidentID, tree.Processes)
+						_ = database.LogIncidentWithDecisionForIncident(
+							m.command,
+							modelName,
+							alertType,
+							cpuUsage,
+							incidentPattern,
+							time.Since(m.startTime).Seconds(),
+							finalTokens,
+							finalCost,
+							m.agentID,
+							m.agentVersion,
+							reason,
+							cpuScore,
+							entropyScore,
+							confidenceScore,
+							"watchdog",
+							0,
+							incidentID,
+						)
+						recordCrashSignature(m.command, detection, decision.Breach, incidentID)
+						_ = database.LogAuditEventWithIncident("flowforge", "WATCHDOG_ALERT", reason, "monitor", pid, m.command, incidentID)
+
+						m.reporter.UpdateState(
+							cpuUsage,
+							fmt.Sprintf("WATCHDOG: Policy alert (%s)", alertType),
+							alertType,
+							pid,
+						)
+					}
+				case policy.ActionLogOnly:
+					fmt.Printf("\n[FlowForge] 🧪 %s\n", reason)
+					incidentID := uuid.NewString()
+					_ = database.LogDecisionTraceWithProcesses(m.command, pid, cpuScore, entropyScore, confidenceScore, decision.Action.String(), reason, incidentID, tree.Processes)
+					_ = database.LogPolicyDryRunWithIncident(m.command, pid, reason, confidenceScore, incidentID)
+				case policy.ActionPause:
+					if noKill {
+						fmt.Printf("\n[FlowForge] WATCHDOG MODE: %s\n", reason)
+						incidentID := uuid.NewString()
+						blockedReason := "watchdog mode blocked pause: " + reason
+						_ = database.LogDecisionTraceWithProcesses(m.command, pid, cpuScore, entropyScore, confidenceScore, "ACTION_BLOCKED", blockedReason, incidentID, tree.Processes)
+						_ = database.LogPolicyDryRunWithIncident(m.command, pid, blockedReason, confidenceScore, incidentID)
+						continue
+					}
+					if err := m.target.Pause(); err != nil {
+						fmt.Printf("\n[FlowForge] Warning: could not pause process group: %v\n", err)
+						continue
+					}
+					incidentID := uuid.NewString()
+					incidentType := "LOOP_PAUSED"
+					if decision.Breach != "" {
+						incidentType = decision.Breach
+					} else {
+						m.patterns.Learn(firstNormalized, m.command, telemetry)
+					}
+
+					fmt.Printf("\n⏸️  AUTO_PAUSE: %s\n", reason)
+					fmt.Println("[FlowForge] Process group frozen. Resume with POST /v1/process/resume or kill with POST /v1/process/kill.")
+					finalTokens := int(m.observer.TotalTokens())
+					finalCost := tokens.EstimateCost(finalTokens, modelName)
+
+					_ = database.LogDecisionTraceWithProcesses(m.command, pid, cpuScore, entropyScore, confidenceScore, decision.Action.String(), reason, incidentID, tree.Processes)
+					_ = database.LogIncidentWithDecisionForIncident(
+						m.command,
+						modelName,
+						incidentType,
+						cpuUsage,
+						incidentPattern,
+						time.Since(m.startTime).Seconds(),
+						finalTokens,
+						finalCost,
+						m.agentID,
+						m.agentVersion,
+						reason,
+						cpuScore,
+						entropyScore,
+						confidenceScore,
+						"paused",
+						0,
+						incidentID,
+					)
+					recordCrashSignature(m.command, detection, decision.Breach, incidentID)
+					_ = database.LogAuditEventWithIncident("flowforge", "AUTO_PAUSE", reason, "monitor", pid, m.command, incidentID)
+
+					if m.reporter.Attached() {
+						m.reporter.UpdateLifecycle("PAUSED", "PAUSED", pid)
+					} else {
+						api.SetWorkerPaused(state.DefaultWorkerID, true, reason)
+					}
+					cpuOver.Reset()
+				case policy.ActionKill, policy.ActionRestart:
+					if noKill {
+						// Legacy watchdog mode always suppresses destructive actions.
+						fmt.Printf("\n[FlowForge] WATCHDOG MODE: %s\n", reason)
+						incidentID := uuid.NewString()
+						blockedReason := "watchdog mode blocked destructive action: " + reason
+						_ = database.LogDecisionTraceWithProcesses(m.command, pid, cpuScore, entropyScore, confidenceScore, "ACTION_BLOCKED", blockedReason, incidentID, tree.Processes)
+						_ = database.LogPolicyDryRunWithIncident(m.command, pid, blockedReason, confidenceScore, incidentID)
+						continue
+					}
+					incidentID := uuid.NewString()
+
+					// Loop feedback and pattern learning only apply to
+					// output-driven breaches, not to wall-clock limits.
+					if decision.Breach == "" {
+						m.patterns.Learn(firstNormalized, m.command, telemetry)
+						feedback.GenerateFeedback(feedback.FeedbackData{
+							Command:    m.command,
+							Pattern:    firstNormalized,
+							ExitReason: "LOOP_DETECTED",
+							MaxCPU:     cpuUsage,
+							ModelName:  modelName,
+							Savings:    0,
+						})
+					}
+
+					actionName := "AUTO_KILL"
+					exitReason := "LOOP_DETECTED"
+					if decision.Action == policy.ActionRestart {
+						actionName = "AUTO_RESTART"
+						exitReason = "RESTART_TRIGGERED"
+					}
+					if decision.Breach != "" {
+						exitReason = decision.Breach
+					}
+					if decision.Breach == policy.BreachDeadline || decision.Breach == policy.BreachBudget {
+						m.runLimitReached.Store(true)
+					}
+
+					fmt.Printf("\n🚨 %s: %s\n", actionName, reason)
+					finalTokens := int(m.observer.TotalTokens())
+					finalCost := tokens.EstimateCost(finalTokens, modelName)
+
+					_ = database.LogDecisionTraceWithProcesses(m.command, pid, cpuScore, entropyScore, confidenceScore, decision.Action.String(), reason, incidentID, tree.Processes)
+					_ = database.LogIncidentWithDecisionForIncident(
+						m.command,
+						modelName,
+						exitReason,
+						cpuUsage,
+						incidentPattern,
+						time.Since(m.startTime).Seconds(),
+						finalTokens,
+						finalCost,
+						m.agentID,
+						m.agentVersion,
+						reason,
+						cpuScore,
+						entropyScore,
+						confidenceScore,
+						"terminated",
+						0,
+						incidentID,
+					)
+					recordCrashSignature(m.command, detection, decision.Breach, incidentID)
+					_ = database.LogAuditEventWithIncident("flowforge", actionName, reason, "monitor", pid, m.command, incidentID)
+
+					m.reporter.UpdateState(
+						cpuUsage,
+						"POLICY ACTION - Terminating process group...",
+						exitReason,
+						pid,
+					)
+
+					m.stopTarget(incidentID)
+					cancel()
+					fmt.Println("[FlowForge] Process group terminated after policy decision.")
+					return
+				}
+			}
+
+			if cpuUsage > maxCpu {
+				topStr := ""
+				if top, ok := tree.Top(); ok && len(tree.Processes) > 1 {
+					topStr = fmt.Sprintf("Top: %s pid=%d (%.1f%%)", top.Name, top.PID, top.CPU)
+				}
+				fmt.Printf("[FlowForge] WARNING: High CPU (%.2f%%) detected. %s %s\n", cpuUsage, topStr, sysStatsStr)
+			}
+
+			// --- SAFETY CHOKE POINT ---
+			// 0. cgroup accounting covers every process in the run, including forks.
+			if m.cgroup != nil {
+				if cgStats, err := m.cgroup.Stats(); err == nil {
+					if cgStats.CPUThrottledCount > m.lastCgroupStats.CPUThrottledCount && time.Since(m.lastThrottleNotice) > 30*time.Second {
+						m.lastThrottleNotice = time.Now()
+						fmt.Printf("[FlowForge] cgroup cpu.max throttling: %d periods (%.1fs total)\n",
+							cgStats.CPUThrottledCount, float64(cgStats.CPUThrottledMicros)/1e6)
+					}
+					if cgStats.OOMKills > m.lastCgroupStats.OOMKills {
+						memMB := float64(cgStats.MemoryCurrentBytes) / 1024.0 / 1024.0
+						fmt.Printf("\n[FlowForge] 🛑 SAFETY CHOKE: cgroup memory.max reached (oom_kill=%d). TERMINATING.\n", cgStats.OOMKills)
+						finalTokens := int(m.observer.TotalTokens())
+						finalCost := tokens.EstimateCost(finalTokens, modelName)
+						incidentID := uuid.NewString()
+						_ = database.LogIncidentWithDecisionForIncident(m.command, modelName, "SAFETY_LIMIT_EXCEEDED", cpuUsage, fmt.Sprintf("Memory Limit: cgroup oom_kill=%d (%.2fMB)", cgStats.OOMKills, memMB), time.Since(m.startTime).Seconds(), finalTokens, finalCost, m.agentID, m.agentVersion, "", 0, 0, 0, "", 0, incidentID)
+
+						m.stopTarget(incidentID)
+						cancel()
+						return
+					}
+					m.lastCgroupStats = cgStats
+				}
+			}
+
+			// 1. Memory Limit (tree RSS; the cgroup enforces it in-kernel when active)
+			maxMemMB := viper.GetFloat64("max-memory-mb")
+			if maxMemMB > 0 && m.cgroup == nil && tree.RSSMB > maxMemMB {
+				memMB := tree.RSSMB
+				fmt.Printf("\n[FlowForge] 🛑 SAFETY CHOKE: Memory usage (%.2f MB) exceeded limit (%.2f MB). TERMINATING.\n", memMB, maxMemMB)
+				// ... (rest of logic same)
+				finalTokens := int(m.observer.TotalTokens())
+				finalCost := tokens.EstimateCost(finalTokens, modelName)
+				incidentID := uuid.NewString()
+				_ = database.LogIncidentWithDecisionForIncident(m.command, modelName, "SAFETY_LIMIT_EXCEEDED", cpuUsage, fmt.Sprintf("Memory Limit: %.2fMB", memMB), time.Since(m.startTime).Seconds(), finalTokens, finalCost, m.agentID, m.agentVersion, "", 0, 0, 0, "", 0, incidentID)
+
+				m.stopTarget(incidentID)
+				cancel()
+				return
+			}
+
+			// 2. Token Rate Limit (Choke)
+			maxTokensRate := viper.GetFloat64("max-tokens-per-min")
+			if maxTokensRate > 0 {
+				currentTokens := m.observer.TotalTokens()
+				elapsedMin := time.Since(m.startTime).Minutes()
+				if elapsedMin > 0.1 { // Warmup 6s
+					rate := float64(currentTokens) / elapsedMin
+					if rate > maxTokensRate {
+						fmt.Printf("\n[FlowForge] 🛑 SAFETY CHOKE: Token generation rate (%.0f/min) exceeded limit (%.0f/min). TERMINATING.\n", rate, maxTokensRate)
+
+						finalTokens := int(currentTokens)
+						finalCost := tokens.EstimateCost(finalTokens, modelName)
+						incidentID := uuid.NewString()
+						_ = database.LogIncidentWithDecisionForIncident(m.command, modelName, "SAFETY_LIMIT_EXCEEDED", cpuUsage, fmt.Sprintf("Token Rate: %.0f/min", rate), time.Since(m.startTime).Seconds(), finalTokens, finalCost, m.agentID, m.agentVersion, "", 0, 0, 0, "", 0, incidentID)
+
+						m.stopTarget(incidentID)
+						cancel()
+						return
+					}
+				}
+			}
+		}
+	}
+}
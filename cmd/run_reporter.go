@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"errors"
+	"flowforge/internal/daemon"
+	"flowforge/internal/state"
+	"flowforge/internal/sysmon"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runReporter publishes a run's telemetry to local state and, when a daemon
+// is attached, mirrors every update over the daemon socket.
+type runReporter struct {
+	runID   string
+	command string
+	args    []string
+	dir     string
+	local   *state.Tracker
+
+	mu     sync.Mutex
+	client *daemon.Client
+	// controls binds the daemon's stop/pause/resume requests to a client,
+	// so a reconnected one is wired up like the first.
+	controls func(*daemon.Client)
+	// redial reconnects after the daemon connection drops. When it fails,
+	// fallback runs once so the run serves its own control surface.
+	redial   func() (*daemon.Client, error)
+	fallback func()
+}
+
+func newRunReporter(runID, command string, args []string, dir string) *runReporter {
+	return &runReporter{
+		runID:   runID,
+		command: command,
+		args:    append([]string(nil), args...),
+		dir:     dir,
+		local:   state.Worker(state.DefaultWorkerID),
+	}
+}
+
+// Attached reports whether updates are being forwarded to a daemon.
+func (r *runReporter) Attached() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.client != nil
+}
+
+func (r *runReporter) attach(client *daemon.Client) {
+	r.mu.Lock()
+	r.client = client
+	r.mu.Unlock()
+}
+
+// controlledProcess is a monitored process the daemon can also resume.
+type controlledProcess interface {
+	monitoredProcess
+	Resume() error
+}
+
+// attachWithControls starts reporting to client and routes the daemon's
+// stop/pause/resume requests to whatever process current returns, so a
+// restarted child is still reachable. onStop runs before the stop is applied.
+func (r *runReporter) attachWithControls(client *daemon.Client, current func() controlledProcess, onStop func()) {
+	controls := func(client *daemon.Client) {
+		client.OnStop(func(grace time.Duration) {
+			onStop()
+			_ = current().Stop(grace)
+		})
+		client.OnPause(func() {
+			p := current()
+			if err := p.Pause(); err != nil {
+				fmt.Printf("[FlowForge] Warning: pause requested by daemon failed: %v\n", err)
+				return
+			}
+			fmt.Println("\n[FlowForge] ⏸️  Process group paused by daemon.")
+			r.UpdateLifecycle("PAUSED", "PAUSED", p.PID())
+		})
+		client.OnResume(func() {
+			p := current()
+			if err := p.Resume(); err != nil {
+				fmt.Printf("[FlowForge] Warning: resume requested by daemon failed: %v\n", err)
+				return
+			}
+			fmt.Println("[FlowForge] ▶️  Process group resumed.")
+			r.UpdateLifecycle("RUNNING", "RUNNING", p.PID())
+		})
+	}
+	controls(client)
+	r.mu.Lock()
+	r.controls = controls
+	r.mu.Unlock()
+	r.attach(client)
+}
+
+// recoverWith sets how the run regains a control surface when the daemon
+// connection drops: redial the daemon, or else run fallback.
+func (r *runReporter) recoverWith(redial func() (*daemon.Client, error), fallback func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redial = redial
+	r.fallback = fallback
+}
+
+func (r *runReporter) UpdateState(cpu float64, lastLine, status string, pid int) {
+	r.local.UpdateState(cpu, lastLine, status, r.command, r.args, r.dir, pid)
+	r.send(daemon.Message{
+		Type:     daemon.MsgTelemetry,
+		PID:      pid,
+		CPU:      cpu,
+		LastLine: lastLine,
+		Status:   status,
+	})
+}
+
+func (r *runReporter) UpdateTree(tree sysmon.TreeSample) {
+	r.local.UpdateTree(tree.RSSMB, tree.Threads, tree.FDs, tree.Processes)
+	r.send(daemon.Message{
+		Type:      daemon.MsgTree,
+		RSSMB:     tree.RSSMB,
+		Threads:   tree.Threads,
+		FDs:       tree.FDs,
+		Processes: tree.Processes,
+	})
+}
+
+func (r *runReporter) UpdateSpend(tokens int64, costUSD float64, maxTokens int64, maxCostUSD float64) {
+	r.local.UpdateSpend(tokens, costUSD, maxTokens, maxCostUSD)
+	r.send(daemon.Message{
+		Type:       daemon.MsgSpend,
+		Tokens:     tokens,
+		CostUSD:    costUSD,
+		MaxTokens:  maxTokens,
+		MaxCostUSD: maxCostUSD,
+	})
+}
+
+func (r *runReporter) UpdateDecision(action, reason string, cpuScore, entropy, confidence float64) {
+	r.local.UpdateDecision(reason, cpuScore, entropy, confidence)
+	r.send(daemon.Message{
+		Type:            daemon.MsgDecision,
+		Action:          action,
+		Reason:          reason,
+		CPUScore:        cpuScore,
+		EntropyScore:    entropy,
+		ConfidenceScore: confidence,
+	})
+}
+
+func (r *runReporter) UpdateLifecycle(lifecycle, status string, pid int) {
+	r.local.UpdateLifecycle(lifecycle, status, pid)
+	r.send(daemon.Message{
+		Type:      daemon.MsgLifecycle,
+		Lifecycle: lifecycle,
+		Status:    status,
+		PID:       pid,
+	})
+}
+
+// ReportExit sends the final exit frame for the run's Wait result.
+func (r *runReporter) ReportExit(waitErr error) {
+	msg := daemon.Message{Type: daemon.MsgExit}
+	if waitErr != nil {
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) && exitErr.ExitCode() > 0 {
+			msg.ExitCode = exitErr.ExitCode()
+		} else {
+			msg.ExitCode = 1
+			msg.Error = waitErr.Error()
+		}
+	}
+	r.send(msg)
+}
+
+func (r *runReporter) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != nil {
+		_ = r.client.Close()
+		r.client = nil
+	}
+}
+
+func (r *runReporter) send(msg daemon.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client == nil {
+		return
+	}
+	err := r.client.Send(msg)
+	if err == nil {
+		return
+	}
+	fmt.Printf("[FlowForge] Warning: lost daemon connection: %v\n", err)
+	_ = r.client.Close()
+	r.client = nil
+	if msg.Type == daemon.MsgExit {
+		// The run is over; there is nothing left to control.
+		return
+	}
+
+	if r.redial != nil {
+		if client, err := r.redial(); err == nil {
+			if r.controls != nil {
+				r.controls(client)
+			}
+			if err := client.Send(msg); err == nil {
+				fmt.Println("[FlowForge] Reconnected to daemon.")
+				r.client = client
+				return
+			}
+			_ = client.Close()
+		}
+	}
+	if fallback := r.fallback; fallback != nil {
+		r.fallback = nil
+		fallback()
+	}
+}
+
+// connectToDaemon registers the run with a healthy local daemon. It returns
+// an error when no daemon is running so callers can fall back to an embedded API.
+func connectToDaemon(runID, command string, args []string, dir string, pid int) (*daemon.Client, error) {
+	paths, err := daemon.Paths()
+	if err != nil {
+		return nil, err
+	}
+	st, err := daemon.ReadState(paths)
+	if err != nil {
+		return nil, fmt.Errorf("read daemon state: %w", err)
+	}
+	port := strings.TrimSpace(st.Port)
+	if st.Status != "running" || port == "" {
+		return nil, errors.New("daemon is not running")
+	}
+	if !probeDaemonHealth(port, 500*time.Millisecond) {
+		return nil, fmt.Errorf("daemon on port %s is not healthy", port)
+	}
+	return daemon.DialIPC(paths.SocketFile, daemon.Message{
+		RunID:   runID,
+		Command: command,
+		Args:    args,
+		Dir:     dir,
+		PID:     pid,
+	}, time.Second)
+}
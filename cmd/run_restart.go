@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"flowforge/internal/api"
+	"flowforge/internal/state"
+	"flowforge/internal/supervisor"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultRestartMaxAttempts = 5
+	defaultRestartBackoffMS   = 1000
+	defaultRestartBackoffMax  = 30000
+	defaultRestartJitter      = 0.2
+)
+
+// restartConfig is the resolved supervisor restart policy for one run.
+type restartConfig struct {
+	Policy      supervisor.RestartPolicy
+	MaxAttempts int
+	Backoff     supervisor.Backoff
+}
+
+// resolveRestartConfig reads the restart policy from flags, then config/profile.
+func resolveRestartConfig() (restartConfig, error) {
+	raw := restartPolicyFlag
+	if raw == "" {
+		raw = viper.GetString("restart")
+	}
+	p, err := supervisor.ParseRestartPolicy(raw)
+	if err != nil {
+		return restartConfig{}, err
+	}
+
+	maxAttempts := restartMaxAttempts
+	if maxAttempts < 0 {
+		maxAttempts = defaultRestartMaxAttempts
+		if viper.IsSet("restart-max-attempts") {
+			maxAttempts = viper.GetInt("restart-max-attempts")
+		}
+	}
+
+	initial := defaultRestartBackoffMS
+	if viper.IsSet("restart-backoff-ms") {
+		initial = viper.GetInt("restart-backoff-ms")
+	}
+	maxBackoff := defaultRestartBackoffMax
+	if viper.IsSet("restart-backoff-max-ms") {
+		maxBackoff = viper.GetInt("restart-backoff-max-ms")
+	}
+	jitter := defaultRestartJitter
+	if viper.IsSet("restart-jitter") {
+		jitter = viper.GetFloat64("restart-jitter")
+	}
+
+	return restartConfig{
+		Policy:      p,
+		MaxAttempts: maxAttempts,
+		Backoff: supervisor.Backoff{
+			Initial:    time.Duration(initial) * time.Millisecond,
+			Max:        time.Duration(maxBackoff) * time.Millisecond,
+			Multiplier: 2,
+			Jitter:     jitter,
+		},
+	}, nil
+}
+
+// nextRestart decides whether the attempt that just ended with cause is
+// restarted. It returns the backoff before the restart, or the CRASH_LOOP
+// reason once the attempts, or a restart budget the user set, are used up.
+func nextRestart(cfg restartConfig, attempt int, cause supervisor.ExitCause) (time.Duration, string) {
+	restart := attempt + 1
+	if restart > cfg.MaxAttempts {
+		return 0, fmt.Sprintf("gave up after %d restarts (last exit: %s)", attempt, cause)
+	}
+	delay := cfg.Backoff.Delay(restart, rand.Float64)
+	if ok, retryAfter := api.ReserveSupervisorRestart(state.DefaultWorkerID, restart, delay, cause.String()); !ok {
+		return 0, fmt.Sprintf("restart budget exhausted after %s (retry in %ds)", cause, retryAfter)
+	}
+	return delay, ""
+}
+
+// classifyAttemptExit maps how one child run ended onto a restart cause.
+func classifyAttemptExit(waitErr error, userTerminated, flowforgeTerminated bool) supervisor.ExitCause {
+	switch {
+	case userTerminated:
+		return supervisor.ExitOperator
+	case flowforgeTerminated:
+		return supervisor.ExitPolicyBreach
+	case waitErr != nil:
+		return supervisor.ExitFailure
+	default:
+		return supervisor.ExitClean
+	}
+}
+
+// exitIncidentReason names the incident raised when an attempt ends on its
+// own with class; "" means the exit is not an incident.
+func exitIncidentReason(class supervisor.ExitClass) string {
+	switch class {
+	case supervisor.ExitClassError:
+		return "COMMAND_FAILURE"
+	case supervisor.ExitClassCrash:
+		return "PROCESS_CRASHED"
+	case supervisor.ExitClassOOM:
+		return "OOM_KILLED"
+	case supervisor.ExitClassSignal:
+		return "KILLED_BY_SIGNAL"
+	default:
+		return ""
+	}
+}
+
+// sleepUnlessSignalled waits out a restart backoff. It returns false if the
+// user interrupts FlowForge while no child is running.
+func sleepUnlessSignalled(d time.Duration) bool {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case sig := <-sigCh:
+		fmt.Printf("\n[FlowForge] Received signal: %v. Cancelling restart.\n", sig)
+		return false
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"flowforge/internal/api"
+	"flowforge/internal/supervisor"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestFailingCommandGetsMaxAttemptsRestartsByDefault(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("FLOWFORGE_RESTART_BUDGET_MAX", "")
+	api.ResetWorkerControlForTests()
+	restartPolicyFlag, restartMaxAttempts = "on-failure", -1
+	t.Cleanup(func() { restartPolicyFlag, restartMaxAttempts = "", -1 })
+
+	cfg, err := resolveRestartConfig()
+	if err != nil {
+		t.Fatalf("resolveRestartConfig: %v", err)
+	}
+	restarts := 0
+	for attempt := 0; ; attempt++ {
+		sup := supervisor.New(exec.Command("sh", "-c", "exit 3"))
+		if err := sup.Start(); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		cause := classifyAttemptExit(sup.Wait(), false, false)
+		if !cfg.Policy.ShouldRestart(cause) {
+			t.Fatalf("expected on-failure to restart after %s", cause)
+		}
+		_, crashReason := nextRestart(cfg, attempt, cause)
+		if crashReason != "" {
+			if !strings.HasPrefix(crashReason, "gave up after") {
+				t.Fatalf("expected the attempt limit to end the run, got %q", crashReason)
+			}
+			break
+		}
+		restarts++
+	}
+	if restarts != defaultRestartMaxAttempts {
+		t.Fatalf("expected %d restarts, got %d", defaultRestartMaxAttempts, restarts)
+	}
+}
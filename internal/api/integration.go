@@ -383,16 +383,17 @@ func handleIntegrationWorkspaceActions(w http.ResponseWriter, r *http.Request, w
 		return
 	}
 
-	workerControl.registerSpecFromStateIfMissing()
+	worker := workerControl.get(state.DefaultWorkerID)
+	worker.registerSpecFromStateIfMissing()
 	var (
 		decision lifecycleAction
 		reqErr   error
 	)
 	switch action {
 	case "restart":
-		decision, reqErr = requestLifecycleRestart()
+		decision, reqErr = worker.requestRestart()
 	case "kill":
-		decision, reqErr = requestLifecycleKill()
+		decision, reqErr = worker.requestKill()
 	}
 	if reqErr != nil {
 		statusCode := lifecycleHTTPCode(reqErr, http.StatusInternalServerError)
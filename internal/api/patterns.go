@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"flowforge/internal/database"
+	"flowforge/internal/state"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HandlePatterns lists the known bad pattern registry. Disabled and
+// expired patterns are included with ?all=true.
+func HandlePatterns(w http.ResponseWriter, r *http.Request) {
+	corsMiddleware(w, r)
+	r = ensureRequestContext(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSONErrorForRequest(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if err := ensureAPIDBReady(); err != nil {
+		writeJSONErrorForRequest(w, r, http.StatusInternalServerError, fmt.Sprintf("database init failed: %v", err))
+		return
+	}
+
+	all := false
+	if raw := strings.TrimSpace(r.URL.Query().Get("all")); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeJSONErrorForRequest(w, r, http.StatusBadRequest, "all must be true or false")
+			return
+		}
+		all = v
+	}
+	ps, err := database.ListPatterns()
+	if err != nil {
+		writeJSONErrorForRequest(w, r, http.StatusInternalServerError, fmt.Sprintf("Database error: %v", err))
+		return
+	}
+	out := make([]database.Pattern, 0, len(ps))
+	for _, p := range ps {
+		if all || (p.Enabled && !p.Expired) {
+			out = append(out, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// HandlePatternScoped serves POST /v1/patterns/{id}/disable and
+// POST /v1/patterns/{id}/enable.
+func HandlePatternScoped(w http.ResponseWriter, r *http.Request) {
+	corsMiddleware(w, r)
+	r = ensureRequestContext(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	id, op, err := parsePatternPath(r.URL.Path)
+	if err != nil {
+		writeJSONErrorForRequest(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSONErrorForRequest(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !requireAuth(w, r) {
+		return
+	}
+	if err := ensureAPIDBReady(); err != nil {
+		writeJSONErrorForRequest(w, r, http.StatusInternalServerError, fmt.Sprintf("database init failed: %v", err))
+		return
+	}
+	idemCtx, handled := beginIdempotentMutation(w, r, fmt.Sprintf("POST /v1/patterns/%d/%s", id, op))
+	if handled {
+		return
+	}
+
+	enabled := op == "enable"
+	if err := database.SetPatternEnabled(id, enabled); err != nil {
+		status, msg := http.StatusInternalServerError, fmt.Sprintf("pattern update failed: %v", err)
+		if errors.Is(err, database.ErrPatternNotFound) {
+			status, msg = http.StatusNotFound, "pattern not found"
+		}
+		payload := problemPayload(r, status, msg, nil)
+		persistIdempotentMutation(idemCtx, status, payload)
+		writeProblem(w, status, payload)
+		return
+	}
+
+	reason := mutationReason(r)
+	if reason == "" {
+		reason = fmt.Sprintf("pattern %d %sd", id, op)
+	}
+	reason = annotateReasonWithRequestID(reason, r)
+	_, _ = database.LogAuditEventWithIncidentAndIDAndRequestID(actorFromRequest(r), "PATTERN_"+strings.ToUpper(op), reason, "patterns", state.GetState().PID, strconv.FormatInt(id, 10), "", requestIDFromRequest(r))
+
+	payload := map[string]interface{}{
+		"ok":      true,
+		"id":      id,
+		"enabled": enabled,
+	}
+	persistIdempotentMutation(idemCtx, http.StatusOK, payload)
+	writeJSON(w, http.StatusOK, payload)
+}
+
+func parsePatternPath(path string) (int64, string, error) {
+	const prefix = "/v1/patterns/"
+	rest := strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	parts := strings.Split(rest, "/")
+	if !strings.HasPrefix(path, prefix) || len(parts) != 2 {
+		return 0, "", errors.New("pattern endpoint not found")
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", errors.New("pattern endpoint not found")
+	}
+	switch parts[1] {
+	case "disable", "enable":
+		return id, parts[1], nil
+	}
+	return 0, "", errors.New("pattern endpoint not found")
+}
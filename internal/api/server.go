@@ -288,6 +288,12 @@ func NewHandler() http.Handler {
 	registerRoute(mux, "/process/restart", HandleProcessRestart)
 	registerRoute(mux, "/v1/process/restart", HandleProcessRestart)
 
+	registerRoute(mux, "/process/pause", HandleProcessPause)
+	registerRoute(mux, "/v1/process/pause", HandleProcessPause)
+
+	registerRoute(mux, "/process/resume", HandleProcessResume)
+	registerRoute(mux, "/v1/process/resume", HandleProcessResume)
+
 	registerRoute(mux, "/healthz", HandleHealth)
 	registerRoute(mux, "/v1/healthz", HandleHealth)
 
@@ -299,6 +305,7 @@ func NewHandler() http.Handler {
 
 	registerRoute(mux, "/worker/lifecycle", HandleWorkerLifecycle)
 	registerRoute(mux, "/v1/worker/lifecycle", HandleWorkerLifecycle)
+	registerRoute(mux, "/v1/workers", HandleWorkers)
 
 	registerRoute(mux, "/timeline", HandleTimeline)
 	registerRoute(mux, "/v1/timeline", HandleTimeline)
@@ -308,6 +315,8 @@ func NewHandler() http.Handler {
 	registerRoute(mux, "/v1/ops/requests/", HandleRequestTrace)
 	registerRoute(mux, "/v1/integrations/workspaces/register", HandleIntegrationWorkspaceRegister)
 	registerRoute(mux, "/v1/integrations/workspaces/", HandleIntegrationWorkspaceScoped)
+	registerRoute(mux, "/v1/patterns", HandlePatterns)
+	registerRoute(mux, "/v1/patterns/", HandlePatternScoped)
 	return mux
 }
 
@@ -329,6 +338,11 @@ func resolveBindAddr(port string) string {
 }
 
 func handleStream(w http.ResponseWriter, r *http.Request) {
+	worker, ok := resolveRequestWorker(w, r)
+	if !ok {
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -347,7 +361,7 @@ func handleStream(w http.ResponseWriter, r *http.Request) {
 		case <-r.Context().Done():
 			return
 		case <-ticker.C:
-			jsonData, err := state.JSON()
+			jsonData, err := worker.state.JSON()
 			if err == nil {
 				fmt.Fprintf(w, "data: %s\n\n", jsonData)
 				flusher.Flush()
@@ -438,8 +452,13 @@ func HandleMetrics(w http.ResponseWriter, r *http.Request) {
 		writeJSONErrorForRequest(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	st := state.GetState()
-	active := st.Status != "STOPPED" && st.PID > 0
+	active := false
+	for _, st := range state.Workers() {
+		if st.Status != "STOPPED" && st.PID > 0 {
+			active = true
+			break
+		}
+	}
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
 	_, _ = fmt.Fprint(w, apiMetrics.Prometheus(active))
 	_, _ = fmt.Fprint(w, controlPlaneReplayPrometheus())
@@ -616,9 +635,14 @@ func HandleWorkerLifecycle(w http.ResponseWriter, r *http.Request) {
 		writeJSONErrorForRequest(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	snap := WorkerLifecycleSnapshot()
-	st := state.GetState()
+	worker, ok := resolveRequestWorker(w, r)
+	if !ok {
+		return
+	}
+	snap := worker.snapshot()
+	st := worker.state.Get()
 	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"worker_id":  worker.id,
 		"phase":      snap["phase"],
 		"operation":  snap["operation"],
 		"pid":        snap["pid"],
@@ -631,6 +655,53 @@ func HandleWorkerLifecycle(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleWorkers lists every registered worker with its lifecycle and telemetry.
+func HandleWorkers(w http.ResponseWriter, r *http.Request) {
+	corsMiddleware(w, r)
+	r = ensureRequestContext(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSONErrorForRequest(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	workers := make([]map[string]interface{}, 0)
+	for _, worker := range workerControl.list() {
+		snap := worker.snapshot()
+		st := worker.state.Get()
+		// The default worker always exists; only list it once something runs there.
+		if worker.id == state.DefaultWorkerID && st.Command == "" && st.PID == 0 {
+			continue
+		}
+		workers = append(workers, map[string]interface{}{
+			"worker_id":  worker.id,
+			"phase":      snap["phase"],
+			"operation":  snap["operation"],
+			"pid":        snap["pid"],
+			"managed":    snap["managed"],
+			"last_error": snap["last_err"],
+			"status":     st.Status,
+			"lifecycle":  st.Lifecycle,
+			"command":    st.Command,
+			"cpu":        st.CPU,
+			"rss_mb":     st.RSSMB,
+			"threads":    st.Threads,
+			"fds":        st.FDs,
+			"processes":  st.Processes,
+			"tokens":     st.Tokens,
+			"cost_usd":   st.CostUSD,
+			"timestamp":  st.Timestamp,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"count":   len(workers),
+		"workers": workers,
+	})
+}
+
 func HandleTimeline(w http.ResponseWriter, r *http.Request) {
 	corsMiddleware(w, r)
 	r = ensureRequestContext(w, r)
@@ -723,7 +794,11 @@ func HandleProcessKill(w http.ResponseWriter, r *http.Request) {
 	if !requireAuth(w, r) {
 		return
 	}
-	idemCtx, handled := beginIdempotentMutation(w, r, "POST /process/kill")
+	worker, ok := resolveRequestWorker(w, r)
+	if !ok {
+		return
+	}
+	idemCtx, handled := beginIdempotentMutation(w, r, lifecycleEndpointKey("POST /process/kill", worker.id))
 	if handled {
 		return
 	}
@@ -732,8 +807,8 @@ func HandleProcessKill(w http.ResponseWriter, r *http.Request) {
 		reason = "manual API kill request"
 	}
 
-	workerControl.registerSpecFromStateIfMissing()
-	decision, err := requestLifecycleKill()
+	worker.registerSpecFromStateIfMissing()
+	decision, err := worker.requestKill()
 	if err != nil {
 		statusCode := lifecycleHTTPCode(err, http.StatusInternalServerError)
 		msg := lifecycleErrorMessage(err, "failed to request kill")
@@ -743,13 +818,14 @@ func HandleProcessKill(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats := state.GetState()
+	stats := worker.state.Get()
 	if decision.AcceptedNew {
 		apiMetrics.IncProcessKill()
 		incidentID := uuid.NewString()
 		_ = database.LogAuditEventWithIncidentAndRequestID(actorFromRequest(r), "KILL", annotateReasonWithRequestID(reason, r), "api", decision.PID, stats.Command, incidentID, requestIDFromRequest(r))
 	}
 	payload := map[string]interface{}{
+		"worker_id": worker.id,
 		"status":    decision.Status,
 		"pid":       decision.PID,
 		"lifecycle": decision.Lifecycle,
@@ -774,7 +850,11 @@ func HandleProcessRestart(w http.ResponseWriter, r *http.Request) {
 	if !requireAuth(w, r) {
 		return
 	}
-	idemCtx, handled := beginIdempotentMutation(w, r, "POST /process/restart")
+	worker, ok := resolveRequestWorker(w, r)
+	if !ok {
+		return
+	}
+	idemCtx, handled := beginIdempotentMutation(w, r, lifecycleEndpointKey("POST /process/restart", worker.id))
 	if handled {
 		return
 	}
@@ -783,13 +863,13 @@ func HandleProcessRestart(w http.ResponseWriter, r *http.Request) {
 		reason = "manual API restart request"
 	}
 
-	workerControl.registerSpecFromStateIfMissing()
-	decision, err := requestLifecycleRestart()
+	worker.registerSpecFromStateIfMissing()
+	decision, err := worker.requestRestart()
 	if err != nil {
 		statusCode := lifecycleHTTPCode(err, http.StatusInternalServerError)
 		msg := lifecycleErrorMessage(err, "failed to request restart")
 		if statusCode == http.StatusTooManyRequests {
-			stats := state.GetState()
+			stats := worker.state.Get()
 			incidentID := uuid.NewString()
 			_ = database.LogAuditEventWithIncidentAndRequestID(actorFromRequest(r), "RESTART_BLOCKED", annotateReasonWithRequestID(msg, r), "api", stats.PID, stats.Command, incidentID, requestIDFromRequest(r))
 		}
@@ -806,13 +886,14 @@ func HandleProcessRestart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats := state.GetState()
+	stats := worker.state.Get()
 	if decision.AcceptedNew {
 		apiMetrics.IncProcessRestart()
 		incidentID := uuid.NewString()
 		_ = database.LogAuditEventWithIncidentAndRequestID(actorFromRequest(r), "RESTART", annotateReasonWithRequestID(reason, r), "api", decision.PID, stats.Command, incidentID, requestIDFromRequest(r))
 	}
 	payload := map[string]interface{}{
+		"worker_id": worker.id,
 		"status":    decision.Status,
 		"pid":       decision.PID,
 		"lifecycle": decision.Lifecycle,
@@ -822,6 +903,85 @@ func HandleProcessRestart(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusAccepted, payload)
 }
 
+// HandleProcessPause is exported for testing.
+func HandleProcessPause(w http.ResponseWriter, r *http.Request) {
+	handleProcessPauseResume(w, r, true)
+}
+
+// HandleProcessResume is exported for testing.
+func HandleProcessResume(w http.ResponseWriter, r *http.Request) {
+	handleProcessPauseResume(w, r, false)
+}
+
+func handleProcessPauseResume(w http.ResponseWriter, r *http.Request, pause bool) {
+	corsMiddleware(w, r)
+	r = ensureRequestContext(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSONErrorForRequest(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !requireAuth(w, r) {
+		return
+	}
+	worker, ok := resolveRequestWorker(w, r)
+	if !ok {
+		return
+	}
+	endpoint, auditAction, fallbackErr := "POST /process/resume", "RESUME", "failed to request resume"
+	if pause {
+		endpoint, auditAction, fallbackErr = "POST /process/pause", "PAUSE", "failed to request pause"
+	}
+	idemCtx, handled := beginIdempotentMutation(w, r, lifecycleEndpointKey(endpoint, worker.id))
+	if handled {
+		return
+	}
+	reason := mutationReason(r)
+	if reason == "" {
+		reason = "manual API " + strings.ToLower(auditAction) + " request"
+	}
+
+	worker.registerSpecFromStateIfMissing()
+	var decision lifecycleAction
+	var err error
+	if pause {
+		decision, err = worker.requestPause()
+	} else {
+		decision, err = worker.requestResume()
+	}
+	if err != nil {
+		statusCode := lifecycleHTTPCode(err, http.StatusInternalServerError)
+		msg := lifecycleErrorMessage(err, fallbackErr)
+		payload := problemPayload(r, statusCode, msg, nil)
+		persistIdempotentMutation(idemCtx, statusCode, payload)
+		writeProblem(w, statusCode, payload)
+		return
+	}
+
+	stats := worker.state.Get()
+	if decision.AcceptedNew {
+		if pause {
+			apiMetrics.IncProcessPause()
+		} else {
+			apiMetrics.IncProcessResume()
+		}
+		incidentID := uuid.NewString()
+		_ = database.LogAuditEventWithIncidentAndRequestID(actorFromRequest(r), auditAction, annotateReasonWithRequestID(reason, r), "api", decision.PID, stats.Command, incidentID, requestIDFromRequest(r))
+	}
+	payload := map[string]interface{}{
+		"worker_id": worker.id,
+		"status":    decision.Status,
+		"pid":       decision.PID,
+		"lifecycle": decision.Lifecycle,
+	}
+	persistIdempotentMutation(idemCtx, http.StatusOK, payload)
+	writeJSON(w, http.StatusOK, payload)
+}
+
 func killProcessTree(pid int) error {
 	if pid <= 0 {
 		return fmt.Errorf("invalid pid %d", pid)
@@ -838,6 +998,20 @@ func killProcessTree(pid int) error {
 	return fmt.Errorf("group kill failed: %v; pid kill failed: %w", groupErr, pidErr)
 }
 
+func signalProcessTree(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return fmt.Errorf("invalid pid %d", pid)
+	}
+	groupErr := syscall.Kill(-pid, sig)
+	if groupErr == nil {
+		return nil
+	}
+	if pidErr := syscall.Kill(pid, sig); pidErr != nil {
+		return fmt.Errorf("group signal failed: %v; pid signal failed: %w", groupErr, pidErr)
+	}
+	return nil
+}
+
 func actorFromRequest(r *http.Request) string {
 	authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
 	if strings.HasPrefix(authHeader, "Bearer ") {
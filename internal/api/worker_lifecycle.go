@@ -1,13 +1,17 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"flowforge/internal/database"
 	"flowforge/internal/state"
 	"flowforge/internal/supervisor"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,12 +25,20 @@ const (
 	lifecycleStopping = "STOPPING"
 	lifecycleStopped  = "STOPPED"
 	lifecycleFailed   = "FAILED"
+	// lifecyclePaused means the process group is frozen with SIGSTOP and
+	// waits for an operator to resume or kill it.
+	lifecyclePaused = "PAUSED"
+	// lifecycleCrashLoop means automatic restarts were given up after the
+	// attempt limit or restart budget was exhausted.
+	lifecycleCrashLoop = "CRASH_LOOP"
 )
 
 const (
 	opNone    = ""
 	opKill    = "kill"
 	opRestart = "restart"
+	opPause   = "pause"
+	opResume  = "resume"
 )
 
 const (
@@ -36,6 +48,8 @@ const (
 	envRestartBudgetWindowSeconds     = "FLOWFORGE_RESTART_BUDGET_WINDOW_SECONDS"
 )
 
+var workerIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
 type restartBudgetConfig struct {
 	Max    int
 	Window time.Duration
@@ -48,6 +62,19 @@ type WorkerController interface {
 	Wait() error
 }
 
+// exitReporter is implemented by controllers that can classify how their
+// process ended.
+type exitReporter interface {
+	ExitInfo() supervisor.ExitInfo
+}
+
+// PausableController is implemented by controllers that can freeze and
+// continue their process group themselves. Others are signalled by PID.
+type PausableController interface {
+	Pause() error
+	Resume() error
+}
+
 type workerSpec struct {
 	Command string
 	Args    []string
@@ -91,6 +118,9 @@ func newLifecycleErrorWithRetry(code int, msg string, retryAfterSeconds int) err
 type workerLifecycle struct {
 	mu sync.Mutex
 
+	id    string
+	state *state.Tracker
+
 	phase     string
 	operation string
 
@@ -102,6 +132,9 @@ type workerLifecycle struct {
 
 	lastErr string
 	watchID uint64
+	// retireID changes on every external registration and retirement; a
+	// pending unregister only fires while it is unchanged.
+	retireID uint64
 
 	lastAction    string
 	lastActionAt  time.Time
@@ -110,13 +143,23 @@ type workerLifecycle struct {
 	stopRequestedAt    time.Time
 	restartRequestedAt time.Time
 	restartHistory     []time.Time
+
+	// tasks counts the worker's lifecycle goroutines, which write to the
+	// database as they finish.
+	tasks sync.WaitGroup
 }
 
-func newWorkerLifecycle() *workerLifecycle {
-	return &workerLifecycle{phase: lifecycleStopped}
+func newWorkerLifecycle(workerID string) *workerLifecycle {
+	workerID = state.NormalizeWorkerID(workerID)
+	return &workerLifecycle{
+		id:    workerID,
+		state: state.Worker(workerID),
+		phase: lifecycleStopped,
+	}
 }
 
 type lifecycleEvidence struct {
+	WorkerID  string `json:"worker_id"`
 	Phase     string `json:"phase"`
 	Operation string `json:"operation"`
 	PID       int    `json:"pid"`
@@ -125,7 +168,7 @@ type lifecycleEvidence struct {
 	Trigger   string `json:"trigger"`
 }
 
-func emitLifecycleTransition(phase, operation string, pid int, managed bool, lastErr, trigger string) {
+func emitLifecycleTransition(workerID, phase, operation string, pid int, managed bool, lastErr, trigger string) {
 	phase = strings.ToUpper(strings.TrimSpace(phase))
 	if phase == "" {
 		phase = "UNKNOWN"
@@ -144,7 +187,15 @@ func emitLifecycleTransition(phase, operation string, pid int, managed bool, las
 		reason = fmt.Sprintf("%s: %s", trigger, lastErr)
 	}
 	summary := fmt.Sprintf("phase=%s operation=%s pid=%d managed=%t", phase, operation, pid, managed)
+	workerID = state.NormalizeWorkerID(workerID)
+	runID := ""
+	if workerID != state.DefaultWorkerID {
+		// Non-default workers are keyed by their run ID so their lifecycle
+		// evidence groups with the rest of that run's events.
+		runID = workerID
+	}
 	payload := lifecycleEvidence{
+		WorkerID:  workerID,
 		Phase:     phase,
 		Operation: operation,
 		PID:       pid,
@@ -156,7 +207,7 @@ func emitLifecycleTransition(phase, operation string, pid int, managed bool, las
 		"lifecycle",
 		"control-plane",
 		reason,
-		"",
+		runID,
 		"",
 		"LIFECYCLE_"+phase,
 		summary,
@@ -184,11 +235,12 @@ func (w *workerLifecycle) registerExternal(command string, args []string, dir st
 	w.phase = lifecycleRunning
 	w.operation = opNone
 	w.lastErr = ""
+	w.retireID++
 	w.startWatcherLocked(controller, false)
 	w.mu.Unlock()
 
-	state.UpdateLifecycle(lifecycleRunning, "RUNNING", w.pid)
-	emitLifecycleTransition(lifecycleRunning, opNone, w.pid, false, "", "external_worker_registered")
+	w.state.UpdateLifecycle(lifecycleRunning, "RUNNING", w.pid)
+	emitLifecycleTransition(w.id, lifecycleRunning, opNone, w.pid, false, "", "external_worker_registered")
 }
 
 func (w *workerLifecycle) requestKill() (lifecycleAction, error) {
@@ -228,10 +280,10 @@ func (w *workerLifecycle) requestKill() (lifecycleAction, error) {
 	w.lastActionAt = time.Now()
 	w.lastActionPID = pid
 	w.stopRequestedAt = w.lastActionAt
-	state.UpdateLifecycle(lifecycleStopping, "STOPPING", pid)
-	emitLifecycleTransition(lifecycleStopping, opKill, pid, w.managed, "", "kill_requested")
+	w.state.UpdateLifecycle(lifecycleStopping, "STOPPING", pid)
+	emitLifecycleTransition(w.id, lifecycleStopping, opKill, pid, w.managed, "", "kill_requested")
 
-	go w.stopAsync(controller, pid, false)
+	w.tasks.Go(func() { w.stopAsync(controller, pid, false) })
 
 	return lifecycleAction{
 		Status:      "stop_requested",
@@ -263,7 +315,7 @@ func (w *workerLifecycle) requestRestart() (lifecycleAction, error) {
 
 	spec := w.spec
 	if !spec.valid() {
-		st := state.GetState()
+		st := w.state.Get()
 		if st.Command != "" && len(st.Args) > 0 {
 			spec = workerSpec{
 				Command: st.Command,
@@ -288,7 +340,7 @@ func (w *workerLifecycle) requestRestart() (lifecycleAction, error) {
 		retryAfter := w.restartBudgetRetryAfterLocked(budget, now)
 		w.lastErr = msg
 		apiMetrics.IncRestartBudgetBlocked()
-		emitLifecycleTransition(w.phase, w.operation, w.pid, w.managed, msg, "restart_budget_blocked")
+		emitLifecycleTransition(w.id, w.phase, w.operation, w.pid, w.managed, msg, "restart_budget_blocked")
 		return lifecycleAction{}, newLifecycleErrorWithRetry(429, msg, retryAfter)
 	}
 	w.recordRestartAttemptLocked(now)
@@ -301,10 +353,10 @@ func (w *workerLifecycle) requestRestart() (lifecycleAction, error) {
 	w.lastActionAt = now
 	w.lastActionPID = 0
 	w.restartRequestedAt = w.lastActionAt
-	state.UpdateLifecycle(lifecycleStarting, "STARTING", 0)
-	emitLifecycleTransition(lifecycleStarting, opRestart, 0, w.managed, "", "restart_requested")
+	w.state.UpdateLifecycle(lifecycleStarting, "STARTING", 0)
+	emitLifecycleTransition(w.id, lifecycleStarting, opRestart, 0, w.managed, "", "restart_requested")
 
-	go w.startAsync(spec)
+	w.tasks.Go(func() { w.startAsync(spec) })
 
 	return lifecycleAction{
 		Status:      "restart_requested",
@@ -314,6 +366,102 @@ func (w *workerLifecycle) requestRestart() (lifecycleAction, error) {
 	}, nil
 }
 
+func (w *workerLifecycle) requestPause() (lifecycleAction, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.syncFromStateLocked()
+
+	if w.operation == opRestart || w.phase == lifecycleStarting {
+		return lifecycleAction{}, newLifecycleError(409, "restart already in progress")
+	}
+	if w.operation == opKill || w.phase == lifecycleStopping {
+		return lifecycleAction{}, newLifecycleError(409, "worker is stopping")
+	}
+	if w.phase == lifecyclePaused {
+		return lifecycleAction{
+			Status:      "paused",
+			Lifecycle:   lifecyclePaused,
+			PID:         w.pid,
+			AcceptedNew: false,
+		}, nil
+	}
+	if !w.hasActiveWorkerLocked() {
+		return lifecycleAction{}, newLifecycleError(400, "no active process to pause")
+	}
+
+	pid := w.activePIDLocked()
+	if err := setControllerPaused(w.controller, pid, true); err != nil {
+		return lifecycleAction{}, newLifecycleError(500, fmt.Sprintf("pause failed: %v", err))
+	}
+	w.phase = lifecyclePaused
+	w.lastErr = ""
+	w.lastAction = opPause
+	w.lastActionAt = time.Now()
+	w.lastActionPID = pid
+	w.state.UpdateLifecycle(lifecyclePaused, "PAUSED", pid)
+	emitLifecycleTransition(w.id, lifecyclePaused, opNone, pid, w.managed, "", "pause_requested")
+
+	return lifecycleAction{
+		Status:      "paused",
+		Lifecycle:   lifecyclePaused,
+		PID:         pid,
+		AcceptedNew: true,
+	}, nil
+}
+
+func (w *workerLifecycle) requestResume() (lifecycleAction, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.syncFromStateLocked()
+
+	if w.phase != lifecyclePaused {
+		if w.phase == lifecycleRunning && w.isRecentActionLocked(opResume, 2*time.Second) {
+			return lifecycleAction{
+				Status:      "resumed",
+				Lifecycle:   lifecycleRunning,
+				PID:         w.pid,
+				AcceptedNew: false,
+			}, nil
+		}
+		return lifecycleAction{}, newLifecycleError(409, "worker is not paused")
+	}
+	if !w.hasActiveWorkerLocked() {
+		return lifecycleAction{}, newLifecycleError(400, "no active process to resume")
+	}
+
+	pid := w.activePIDLocked()
+	if err := setControllerPaused(w.controller, pid, false); err != nil {
+		return lifecycleAction{}, newLifecycleError(500, fmt.Sprintf("resume failed: %v", err))
+	}
+	w.phase = lifecycleRunning
+	w.lastErr = ""
+	w.lastAction = opResume
+	w.lastActionAt = time.Now()
+	w.lastActionPID = pid
+	w.state.UpdateLifecycle(lifecycleRunning, "RUNNING", pid)
+	emitLifecycleTransition(w.id, lifecycleRunning, opNone, pid, w.managed, "", "resume_requested")
+
+	return lifecycleAction{
+		Status:      "resumed",
+		Lifecycle:   lifecycleRunning,
+		PID:         pid,
+		AcceptedNew: true,
+	}, nil
+}
+
+func setControllerPaused(controller WorkerController, pid int, paused bool) error {
+	if p, ok := controller.(PausableController); ok {
+		if paused {
+			return p.Pause()
+		}
+		return p.Resume()
+	}
+	if paused {
+		return signalProcessTree(pid, syscall.SIGSTOP)
+	}
+	return signalProcessTree(pid, syscall.SIGCONT)
+}
+
 func (w *workerLifecycle) stopAsync(controller WorkerController, pid int, fromWatcher bool) {
 	startedAt := time.Now()
 	w.mu.Lock()
@@ -344,8 +492,8 @@ func (w *workerLifecycle) stopAsync(controller WorkerController, pid int, fromWa
 		w.phase = lifecycleFailed
 		w.operation = opNone
 		w.lastErr = err.Error()
-		state.UpdateLifecycle(lifecycleFailed, "FAILED", w.pid)
-		emitLifecycleTransition(lifecycleFailed, opNone, w.pid, w.managed, w.lastErr, "stop_failed")
+		w.state.UpdateLifecycle(lifecycleFailed, "FAILED", w.pid)
+		emitLifecycleTransition(w.id, lifecycleFailed, opNone, w.pid, w.managed, w.lastErr, "stop_failed")
 		apiMetrics.ObserveStopLatency(time.Since(startedAt).Seconds(), false)
 		return
 	}
@@ -358,8 +506,8 @@ func (w *workerLifecycle) stopAsync(controller WorkerController, pid int, fromWa
 	w.operation = opNone
 	w.lastErr = ""
 	w.stopRequestedAt = time.Time{}
-	state.UpdateLifecycle(lifecycleStopped, "STOPPED", 0)
-	emitLifecycleTransition(lifecycleStopped, opNone, 0, false, "", "stop_completed")
+	w.state.UpdateLifecycle(lifecycleStopped, "STOPPED", 0)
+	emitLifecycleTransition(w.id, lifecycleStopped, opNone, 0, false, "", "stop_completed")
 	apiMetrics.ObserveStopLatency(time.Since(startedAt).Seconds(), true)
 }
 
@@ -377,6 +525,10 @@ func (w *workerLifecycle) startAsync(spec workerSpec) {
 	}
 
 	sup := supervisor.New(cmd)
+	sup.OnEscape(func(e supervisor.Escape) {
+		reason := fmt.Sprintf("pid %d (%s) %s", e.PID, e.Name, e.Reason)
+		_ = database.LogAuditEvent("flowforge", "PROCESS_ESCAPED", reason, "supervisor", e.PID, spec.Command)
+	})
 	if err := sup.Start(); err != nil {
 		w.mu.Lock()
 		w.phase = lifecycleFailed
@@ -386,8 +538,8 @@ func (w *workerLifecycle) startAsync(spec workerSpec) {
 		w.controller = nil
 		w.managed = false
 		w.mu.Unlock()
-		state.UpdateLifecycle(lifecycleFailed, "FAILED", 0)
-		emitLifecycleTransition(lifecycleFailed, opNone, 0, false, err.Error(), "restart_failed")
+		w.state.UpdateLifecycle(lifecycleFailed, "FAILED", 0)
+		emitLifecycleTransition(w.id, lifecycleFailed, opNone, 0, false, err.Error(), "restart_failed")
 		apiMetrics.ObserveRestartLatency(time.Since(startedAt).Seconds(), false)
 		return
 	}
@@ -405,21 +557,30 @@ func (w *workerLifecycle) startAsync(spec workerSpec) {
 	w.startWatcherLocked(sup, true)
 	w.mu.Unlock()
 
-	state.UpdateState(0, "", "RUNNING", spec.Command, spec.Args, spec.Dir, pid)
-	state.UpdateLifecycle(lifecycleRunning, "RUNNING", pid)
-	emitLifecycleTransition(lifecycleRunning, opNone, pid, true, "", "restart_completed")
+	w.state.UpdateState(0, "", "RUNNING", spec.Command, spec.Args, spec.Dir, pid)
+	w.state.UpdateLifecycle(lifecycleRunning, "RUNNING", pid)
+	emitLifecycleTransition(w.id, lifecycleRunning, opNone, pid, true, "", "restart_completed")
 	apiMetrics.ObserveRestartLatency(time.Since(startedAt).Seconds(), true)
 }
 
 func (w *workerLifecycle) startWatcherLocked(controller WorkerController, managed bool) uint64 {
 	w.watchID++
 	id := w.watchID
-	go w.waitForController(id, controller, managed)
+	w.tasks.Go(func() { w.waitForController(id, controller, managed) })
 	return id
 }
 
 func (w *workerLifecycle) waitForController(id uint64, controller WorkerController, managed bool) {
 	err := controller.Wait()
+	if r, ok := controller.(exitReporter); ok && managed {
+		// External controllers belong to a `flowforge run`, which records
+		// its own exits. Only API stops reach a managed worker.
+		w.mu.Lock()
+		command := w.spec.Command
+		w.mu.Unlock()
+		exit := database.NewRunExit(r.ExitInfo(), controller.PID(), 0, database.ExitClassOperatorStop)
+		_ = database.LogRunExit(command, exit, "")
+	}
 
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -439,15 +600,14 @@ func (w *workerLifecycle) waitForController(id uint64, controller WorkerControll
 	if err != nil {
 		w.phase = lifecycleFailed
 		w.lastErr = err.Error()
-		state.UpdateLifecycle(lifecycleFailed, "FAILED", 0)
-		emitLifecycleTransition(lifecycleFailed, opNone, 0, false, w.lastErr, "worker_exit_error")
+		w.state.UpdateLifecycle(lifecycleFailed, "FAILED", 0)
+		emitLifecycleTransition(w.id, lifecycleFailed, opNone, 0, false, w.lastErr, "worker_exit_error")
 		return
 	}
 	w.phase = lifecycleStopped
 	w.lastErr = ""
-	state.UpdateLifecycle(lifecycleStopped, "STOPPED", 0)
-	emitLifecycleTransition(lifecycleStopped, opNone, 0, false, "", "worker_exited")
-	_ = managed
+	w.state.UpdateLifecycle(lifecycleStopped, "STOPPED", 0)
+	emitLifecycleTransition(w.id, lifecycleStopped, opNone, 0, false, "", "worker_exited")
 }
 
 func (w *workerLifecycle) hasActiveWorkerLocked() bool {
@@ -482,7 +642,7 @@ func (w *workerLifecycle) registerSpecFromStateIfMissing() {
 }
 
 func (w *workerLifecycle) syncFromStateLocked() {
-	st := state.GetState()
+	st := w.state.Get()
 	if !w.spec.valid() && st.Command != "" && len(st.Args) > 0 {
 		w.spec = workerSpec{
 			Command: st.Command,
@@ -499,7 +659,7 @@ func (w *workerLifecycle) syncFromStateLocked() {
 	}
 	if w.controller == nil && (st.PID <= 0 || !processLikelyAlive(st.PID)) {
 		w.pid = 0
-		if w.operation == opNone && w.phase != lifecycleStarting && w.phase != lifecycleStopping && w.phase != lifecycleFailed {
+		if w.operation == opNone && w.phase != lifecycleStarting && w.phase != lifecycleStopping && w.phase != lifecycleFailed && w.phase != lifecycleCrashLoop {
 			w.phase = lifecycleStopped
 		}
 	}
@@ -510,6 +670,7 @@ func (w *workerLifecycle) snapshot() map[string]interface{} {
 	defer w.mu.Unlock()
 	w.syncFromStateLocked()
 	return map[string]interface{}{
+		"worker_id": w.id,
 		"phase":     w.phase,
 		"operation": w.operation,
 		"pid":       w.pid,
@@ -618,33 +779,304 @@ func processLikelyAlive(pid int) bool {
 	return !errors.Is(err, syscall.ESRCH)
 }
 
-var workerControl = newWorkerLifecycle()
+// workerRegistry tracks one lifecycle state machine per supervised worker.
+type workerRegistry struct {
+	mu      sync.Mutex
+	workers map[string]*workerLifecycle
+}
+
+func newWorkerRegistry() *workerRegistry {
+	return &workerRegistry{
+		workers: map[string]*workerLifecycle{
+			state.DefaultWorkerID: newWorkerLifecycle(state.DefaultWorkerID),
+		},
+	}
+}
+
+// get returns the lifecycle for workerID, creating it on first use.
+func (r *workerRegistry) get(workerID string) *workerLifecycle {
+	workerID = state.NormalizeWorkerID(workerID)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, ok := r.workers[workerID]
+	if !ok {
+		w = newWorkerLifecycle(workerID)
+		r.workers[workerID] = w
+	}
+	return w
+}
+
+// lookup returns the lifecycle for workerID. The default worker always
+// resolves so legacy single-worker routes keep working.
+func (r *workerRegistry) lookup(workerID string) (*workerLifecycle, bool) {
+	workerID = state.NormalizeWorkerID(workerID)
+	if workerID == state.DefaultWorkerID {
+		return r.get(workerID), true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, ok := r.workers[workerID]
+	return w, ok
+}
 
+func (r *workerRegistry) remove(workerID string) {
+	workerID = state.NormalizeWorkerID(workerID)
+	if workerID == state.DefaultWorkerID {
+		return
+	}
+	r.mu.Lock()
+	delete(r.workers, workerID)
+	r.mu.Unlock()
+	state.RemoveWorker(workerID)
+}
+
+func (r *workerRegistry) list() []*workerLifecycle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*workerLifecycle, 0, len(r.workers))
+	for _, w := range r.workers {
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].id < out[j].id })
+	return out
+}
+
+var workerControl = newWorkerRegistry()
+
+// RegisterExternalWorker registers an externally started process as the default worker.
 func RegisterExternalWorker(command string, args []string, dir string, controller WorkerController) {
-	workerControl.registerExternal(command, args, dir, controller)
+	RegisterExternalWorkerWithID(state.DefaultWorkerID, command, args, dir, controller)
+}
+
+// RegisterExternalWorkerWithID registers an externally started process under workerID.
+func RegisterExternalWorkerWithID(workerID, command string, args []string, dir string, controller WorkerController) {
+	workerControl.get(workerID).registerExternal(command, args, dir, controller)
+}
+
+// ReserveSupervisorRestart records an automatic restart of workerID as a
+// lifecycle transition and counts it in the restart history POST
+// /v1/process/restart is limited by. The supervisor's own attempt limit
+// governs these restarts; the API restart budget only applies to them when
+// FLOWFORGE_RESTART_BUDGET_MAX is set. When that budget is exhausted it
+// returns false and the seconds until a restart would be allowed again.
+func ReserveSupervisorRestart(workerID string, attempt int, delay time.Duration, cause string) (bool, int) {
+	w := workerControl.get(workerID)
+	budget := loadRestartBudgetConfig()
+	if strings.TrimSpace(os.Getenv(envRestartBudgetMax)) == "" {
+		budget.Max = 0
+	}
+	now := time.Now()
+
+	w.mu.Lock()
+	if w.restartBudgetExceededLocked(budget, now) {
+		retryAfter := w.restartBudgetRetryAfterLocked(budget, now)
+		msg := fmt.Sprintf("restart budget exceeded: allowed %d restarts per %ds", budget.Max, int(budget.Window.Seconds()))
+		w.lastErr = msg
+		phase, operation, pid, managed := w.phase, w.operation, w.pid, w.managed
+		w.mu.Unlock()
+		apiMetrics.IncRestartBudgetBlocked()
+		emitLifecycleTransition(w.id, phase, operation, pid, managed, msg, "restart_budget_blocked")
+		return false, retryAfter
+	}
+	w.recordRestartAttemptLocked(now)
+	// Detach the exited controller's watcher; the next registration owns state.
+	w.watchID++
+	w.controller = nil
+	w.pid = 0
+	w.phase = lifecycleStarting
+	w.operation = opRestart
+	w.lastErr = ""
+	managed := w.managed
+	w.mu.Unlock()
+
+	w.state.UpdateLifecycle(lifecycleStarting, "STARTING", 0)
+	detail := fmt.Sprintf("attempt %d after %s, backoff %s", attempt, cause, delay.Round(time.Millisecond))
+	emitLifecycleTransition(w.id, lifecycleStarting, opRestart, 0, managed, detail, "supervisor_restart")
+	return true, 0
+}
+
+// SetWorkerPaused records a pause or resume that happened outside the API,
+// such as a policy pause in the run itself. It does not signal the process.
+func SetWorkerPaused(workerID string, paused bool, reason string) {
+	w := workerControl.get(workerID)
+	w.mu.Lock()
+	from, to, trigger := lifecycleRunning, lifecyclePaused, "pause_reported"
+	if !paused {
+		from, to, trigger = lifecyclePaused, lifecycleRunning, "resume_reported"
+	}
+	if w.phase != from {
+		w.mu.Unlock()
+		return
+	}
+	w.phase = to
+	pid, managed := w.activePIDLocked(), w.managed
+	w.mu.Unlock()
+
+	w.state.UpdateLifecycle(to, to, pid)
+	emitLifecycleTransition(w.id, to, opNone, pid, managed, reason, trigger)
+}
+
+// MarkWorkerCrashLoop moves workerID into the CRASH_LOOP phase after the
+// supervisor gives up restarting it.
+func MarkWorkerCrashLoop(workerID, reason string) {
+	w := workerControl.get(workerID)
+	w.mu.Lock()
+	w.watchID++
+	w.controller = nil
+	w.pid = 0
+	w.phase = lifecycleCrashLoop
+	w.operation = opNone
+	w.lastErr = reason
+	managed := w.managed
+	w.mu.Unlock()
+
+	w.state.UpdateLifecycle(lifecycleCrashLoop, lifecycleCrashLoop, 0)
+	emitLifecycleTransition(w.id, lifecycleCrashLoop, opNone, 0, managed, reason, "crash_loop_detected")
+}
+
+// RetireExternalWorker marks workerID stopped once controller, an external
+// run, has exited or disconnected, and unregisters it after retention so
+// finished runs do not pile up on /v1/workers. It does nothing if another
+// run has since registered under workerID, and that run cancels the
+// pending unregister.
+func RetireExternalWorker(workerID string, controller WorkerController, retention time.Duration) {
+	w, ok := workerControl.lookup(workerID)
+	if !ok || controller == nil {
+		return
+	}
+	lastErr := ""
+	if err := controller.Wait(); err != nil {
+		lastErr = err.Error()
+	}
+
+	w.mu.Lock()
+	if w.controller != nil && w.controller != controller {
+		w.mu.Unlock()
+		return
+	}
+	w.retireID++
+	id := w.retireID
+	// An API stop in progress finishes the transition itself.
+	transition := w.operation != opKill && w.phase != lifecycleStopping
+	if transition {
+		w.watchID++
+		w.controller = nil
+		w.managed = false
+		w.pid = 0
+		w.phase = lifecycleStopped
+		w.operation = opNone
+		w.lastErr = lastErr
+	}
+	w.mu.Unlock()
+
+	if transition {
+		w.state.UpdateLifecycle(lifecycleStopped, "STOPPED", 0)
+		emitLifecycleTransition(w.id, lifecycleStopped, opNone, 0, false, lastErr, "external_worker_ended")
+	}
+	if w.id == state.DefaultWorkerID {
+		return
+	}
+	time.AfterFunc(retention, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.retireID != id {
+			return
+		}
+		workerControl.remove(w.id)
+	})
+}
+
+// UnregisterWorker forgets a non-default worker and its runtime state.
+func UnregisterWorker(workerID string) {
+	workerControl.remove(workerID)
 }
 
 func SetWorkerSpec(command string, args []string, dir string) {
-	workerControl.setSpec(command, args, dir)
+	SetWorkerSpecWithID(state.DefaultWorkerID, command, args, dir)
+}
+
+func SetWorkerSpecWithID(workerID, command string, args []string, dir string) {
+	workerControl.get(workerID).setSpec(command, args, dir)
 }
 
 func WorkerLifecycleSnapshot() map[string]interface{} {
-	return workerControl.snapshot()
+	return workerControl.get(state.DefaultWorkerID).snapshot()
+}
+
+// StopWorkersForTests stops every worker's process and waits for its
+// lifecycle goroutines, so none of them writes to a database a test is
+// about to close or replace.
+func StopWorkersForTests() {
+	for _, w := range workerControl.list() {
+		w.mu.Lock()
+		controller := w.controller
+		w.mu.Unlock()
+		if controller != nil && !controller.Exited() {
+			_ = controller.Stop(time.Second)
+		}
+		w.tasks.Wait()
+	}
 }
 
 // ResetWorkerControlForTests resets global lifecycle state.
 // Intended only for test isolation.
 func ResetWorkerControlForTests() {
-	workerControl = newWorkerLifecycle()
+	StopWorkersForTests()
+	for _, w := range workerControl.list() {
+		state.RemoveWorker(w.id)
+	}
+	workerControl = newWorkerRegistry()
 	state.UpdateLifecycle(lifecycleStopped, "STOPPED", 0)
 }
 
-func requestLifecycleKill() (lifecycleAction, error) {
-	return workerControl.requestKill()
+// workerIDFromRequest reads the target worker from the worker_id query
+// parameter or, for POST requests, the JSON body. Empty selects the default worker.
+func workerIDFromRequest(r *http.Request) (string, error) {
+	workerID := strings.TrimSpace(r.URL.Query().Get("worker_id"))
+	if workerID == "" && r.Method == http.MethodPost {
+		body, err := readAndRestoreRequestBody(r, idempotencyMaxBodyBytes)
+		if err == nil && len(body) > 0 {
+			var payload struct {
+				WorkerID string `json:"worker_id"`
+			}
+			if json.Unmarshal(body, &payload) == nil {
+				workerID = strings.TrimSpace(payload.WorkerID)
+			}
+		}
+	}
+	if workerID == "" {
+		return state.DefaultWorkerID, nil
+	}
+	if !workerIDPattern.MatchString(workerID) {
+		return "", errors.New("worker_id must match [A-Za-z0-9._:-] and be <= 128 chars")
+	}
+	return workerID, nil
+}
+
+// resolveRequestWorker looks up the worker addressed by r and writes a problem
+// response when it is invalid or unknown.
+func resolveRequestWorker(w http.ResponseWriter, r *http.Request) (*workerLifecycle, bool) {
+	workerID, err := workerIDFromRequest(r)
+	if err != nil {
+		writeJSONErrorForRequest(w, r, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	worker, ok := workerControl.lookup(workerID)
+	if !ok {
+		writeJSONErrorForRequest(w, r, http.StatusNotFound, fmt.Sprintf("worker %q not found", workerID))
+		return nil, false
+	}
+	return worker, true
 }
 
-func requestLifecycleRestart() (lifecycleAction, error) {
-	return workerControl.requestRestart()
+// lifecycleEndpointKey scopes idempotency records to a worker so the same key
+// can be reused safely across workers.
+func lifecycleEndpointKey(endpoint, workerID string) string {
+	workerID = state.NormalizeWorkerID(workerID)
+	if workerID == state.DefaultWorkerID {
+		return endpoint
+	}
+	return endpoint + " worker=" + workerID
 }
 
 func lifecycleHTTPCode(err error, fallback int) int {
@@ -0,0 +1,367 @@
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultRoot is where the unified (v2) hierarchy is normally mounted.
+const DefaultRoot = "/sys/fs/cgroup"
+
+const cpuPeriodMicros = 100000
+
+// SupervisorLeaf is the child cgroup FlowForge moves itself into when run
+// groups are created next to it, under the cgroup it was started in.
+const SupervisorLeaf = "flowforge-supervisor"
+
+// ErrUnavailable reports that cgroup v2 is not mounted or not delegated to
+// this user. Callers should fall back to polling-based limits.
+var ErrUnavailable = errors.New("cgroup v2 not available")
+
+// Limits are the hard limits applied to a run's cgroup. Zero means unlimited.
+type Limits struct {
+	MemoryMaxBytes int64
+	// CPUPercent is relative to one core: 150 allows one and a half CPUs.
+	CPUPercent float64
+	PidsMax    int64
+}
+
+// Config describes the cgroup to create for one run.
+type Config struct {
+	// Root is the cgroup2 mount point. Empty means DefaultRoot.
+	Root string
+	// Parent is the delegated cgroup, relative to Root, under which the run's
+	// group is created. Empty means the cgroup FlowForge was started in; it
+	// first moves itself into a SupervisorLeaf child there, because a
+	// cgroup with member processes cannot enable controllers for children.
+	Parent string
+	Name   string
+	Limits Limits
+}
+
+// Stats is a snapshot of the accounting files for a run's cgroup.
+type Stats struct {
+	MemoryCurrentBytes int64
+	MemoryMaxEvents    int64
+	OOMEvents          int64
+	OOMKills           int64
+	CPUUsageMicros     int64
+	CPUThrottledCount  int64
+	CPUThrottledMicros int64
+	PidsCurrent        int64
+}
+
+// Group is a cgroup v2 directory owned by a single run.
+type Group struct {
+	path string
+}
+
+// Create makes the run's cgroup and applies limits. It returns an error
+// wrapping ErrUnavailable when cgroups cannot be used on this host.
+func Create(cfg Config) (*Group, error) {
+	root := cfg.Root
+	if root == "" {
+		root = DefaultRoot
+	}
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%w: %s is not a cgroup2 mount", ErrUnavailable, root)
+	}
+	name := strings.TrimSpace(cfg.Name)
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("cgroup: invalid group name %q", cfg.Name)
+	}
+
+	parentRel := cfg.Parent
+	if parentRel == "" {
+		self, err := selfCgroupPath()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		// After the first run FlowForge already sits in its leaf.
+		if filepath.Base(self) == SupervisorLeaf {
+			self = filepath.Dir(self)
+		}
+		parentRel = self
+	}
+	parent := filepath.Join(root, filepath.Clean("/"+parentRel))
+	if cfg.Parent == "" && parent != filepath.Clean(root) {
+		if err := vacate(parent); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enableControllers(parent, cfg.Limits); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(parent, name)
+	if err := os.Mkdir(path, 0o755); err != nil {
+		if errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EROFS) {
+			return nil, fmt.Errorf("%w: cannot create %s: %v", ErrUnavailable, path, err)
+		}
+		return nil, fmt.Errorf("cgroup: create %s: %w", path, err)
+	}
+
+	g := &Group{path: path}
+	if err := g.apply(cfg.Limits); err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return g, nil
+}
+
+// Path returns the absolute directory of the group.
+func (g *Group) Path() string {
+	return g.path
+}
+
+// Open returns the group's directory, for starting a child directly inside
+// it through SysProcAttr.CgroupFD. The caller closes it.
+func (g *Group) Open() (*os.File, error) {
+	return os.Open(g.path)
+}
+
+// AddProcess moves pid into the group. Children it forks afterwards inherit
+// the group, so limits cover the whole tree.
+func (g *Group) AddProcess(pid int) error {
+	if pid <= 0 {
+		return fmt.Errorf("cgroup: invalid pid %d", pid)
+	}
+	return g.write("cgroup.procs", strconv.Itoa(pid))
+}
+
+// Procs lists the PIDs currently in the group.
+func (g *Group) Procs() ([]int, error) {
+	blob, err := os.ReadFile(filepath.Join(g.path, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(blob)) {
+		pid, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// Kill sends SIGKILL to every process in the group, including descendants
+// that left the supervisor's process group.
+func (g *Group) Kill() error {
+	if err := g.write("cgroup.kill", "1"); err == nil {
+		return nil
+	}
+	// cgroup.kill needs Linux 5.14; signal members one by one otherwise.
+	pids, err := g.Procs()
+	if err != nil {
+		return err
+	}
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove kills anything left in the group and deletes it.
+func (g *Group) Remove() error {
+	if pids, err := g.Procs(); err == nil && len(pids) > 0 {
+		_ = g.Kill()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if pids, err := g.Procs(); err != nil || len(pids) == 0 {
+				break
+			}
+			time.Sleep(25 * time.Millisecond)
+		}
+	}
+	if err := os.Remove(g.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cgroup: remove %s: %w", g.path, err)
+	}
+	return nil
+}
+
+// Stats reads memory.events, cpu.stat and the current usage counters.
+// Files belonging to controllers that are not enabled are skipped.
+func (g *Group) Stats() (Stats, error) {
+	var st Stats
+	cpu, err := readKeyedFile(filepath.Join(g.path, "cpu.stat"))
+	if err != nil {
+		return st, err
+	}
+	st.CPUUsageMicros = cpu["usage_usec"]
+	st.CPUThrottledCount = cpu["nr_throttled"]
+	st.CPUThrottledMicros = cpu["throttled_usec"]
+
+	if mem, err := readKeyedFile(filepath.Join(g.path, "memory.events")); err == nil {
+		st.MemoryMaxEvents = mem["max"]
+		st.OOMEvents = mem["oom"]
+		st.OOMKills = mem["oom_kill"]
+	}
+	if v, err := readIntFile(filepath.Join(g.path, "memory.current")); err == nil {
+		st.MemoryCurrentBytes = v
+	}
+	if v, err := readIntFile(filepath.Join(g.path, "pids.current")); err == nil {
+		st.PidsCurrent = v
+	}
+	return st, nil
+}
+
+func (g *Group) apply(l Limits) error {
+	if l.MemoryMaxBytes > 0 {
+		if err := g.write("memory.max", strconv.FormatInt(l.MemoryMaxBytes, 10)); err != nil {
+			return fmt.Errorf("cgroup: set memory.max: %w", err)
+		}
+	}
+	if l.CPUPercent > 0 {
+		quota := int64(l.CPUPercent / 100.0 * cpuPeriodMicros)
+		if quota < 1000 {
+			quota = 1000
+		}
+		if err := g.write("cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriodMicros)); err != nil {
+			return fmt.Errorf("cgroup: set cpu.max: %w", err)
+		}
+	}
+	if l.PidsMax > 0 {
+		if err := g.write("pids.max", strconv.FormatInt(l.PidsMax, 10)); err != nil {
+			return fmt.Errorf("cgroup: set pids.max: %w", err)
+		}
+	}
+	return nil
+}
+
+func (g *Group) write(file, value string) error {
+	return os.WriteFile(filepath.Join(g.path, file), []byte(value), 0o644)
+}
+
+// vacate moves this process out of parent into its SupervisorLeaf child, so
+// controllers can be enabled for parent's children (the root cgroup is
+// exempt from that rule). Other processes still in parent make
+// enableControllers fail, and the run falls back to polling.
+func vacate(parent string) error {
+	if !hasProcs(parent) {
+		return nil
+	}
+	leaf := filepath.Join(parent, SupervisorLeaf)
+	if err := os.Mkdir(leaf, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: cannot create %s: %v", ErrUnavailable, leaf, err)
+	}
+	// Writing a PID moves every thread of the process.
+	if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		return fmt.Errorf("%w: move FlowForge into %s: %v", ErrUnavailable, leaf, err)
+	}
+	if hasProcs(parent) {
+		return fmt.Errorf("%w: %s has other processes; set cgroup-parent to a delegated cgroup without members", ErrUnavailable, parent)
+	}
+	return nil
+}
+
+func hasProcs(dir string) bool {
+	blob, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	return err == nil && strings.TrimSpace(string(blob)) != ""
+}
+
+// enableControllers turns on the controllers the limits need in the parent's
+// subtree_control. Memory is always requested so memory.events is available.
+func enableControllers(parent string, l Limits) error {
+	blob, err := os.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("%w: read %s: %v", ErrUnavailable, parent, err)
+	}
+	available := make(map[string]bool)
+	for _, c := range strings.Fields(string(blob)) {
+		available[c] = true
+	}
+
+	want := []string{"memory"}
+	if l.CPUPercent > 0 {
+		want = append(want, "cpu")
+	}
+	if l.PidsMax > 0 {
+		want = append(want, "pids")
+	}
+
+	enabled := make(map[string]bool)
+	if blob, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control")); err == nil {
+		for _, c := range strings.Fields(string(blob)) {
+			enabled[c] = true
+		}
+	}
+
+	var missing []string
+	for _, c := range want {
+		if !available[c] {
+			return fmt.Errorf("%w: %s controller not delegated to %s", ErrUnavailable, c, parent)
+		}
+		if !enabled[c] {
+			missing = append(missing, "+"+c)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	control := filepath.Join(parent, "cgroup.subtree_control")
+	if err := os.WriteFile(control, []byte(strings.Join(missing, " ")), 0o644); err != nil {
+		return fmt.Errorf("%w: enable %s in %s: %v", ErrUnavailable, strings.Join(missing, " "), parent, err)
+	}
+	return nil
+}
+
+// selfCgroupPath returns this process's path in the unified hierarchy.
+func selfCgroupPath() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "0::") {
+			return strings.TrimPrefix(line, "0::"), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("process is not in a cgroup2 hierarchy")
+}
+
+func readKeyedFile(path string) (map[string]int64, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]int64)
+	for _, line := range strings.Split(string(blob), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		out[fields[0]] = v
+	}
+	return out, nil
+}
+
+func readIntFile(path string) (int64, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(blob)), 10, 64)
+}
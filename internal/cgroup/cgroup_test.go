@@ -0,0 +1,132 @@
+package cgroup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func fakeHierarchy(t *testing.T, controllers string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{root, filepath.Join(root, "delegated")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte(controllers), 0o644); err != nil {
+			t.Fatalf("write controllers: %v", err)
+		}
+	}
+	return root
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return strings.TrimSpace(string(blob))
+}
+
+func TestCreateAppliesLimits(t *testing.T) {
+	root := fakeHierarchy(t, "cpuset cpu io memory pids")
+
+	g, err := Create(Config{
+		Root:   root,
+		Parent: "delegated",
+		Name:   "flowforge-run-1",
+		Limits: Limits{MemoryMaxBytes: 256 << 20, CPUPercent: 150, PidsMax: 64},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if want := filepath.Join(root, "delegated", "flowforge-run-1"); g.Path() != want {
+		t.Fatalf("Path() = %q, want %q", g.Path(), want)
+	}
+
+	if got := readFile(t, filepath.Join(root, "delegated", "cgroup.subtree_control")); got != "+memory +cpu +pids" {
+		t.Fatalf("subtree_control = %q", got)
+	}
+	if got := readFile(t, filepath.Join(g.Path(), "memory.max")); got != "268435456" {
+		t.Fatalf("memory.max = %q", got)
+	}
+	if got := readFile(t, filepath.Join(g.Path(), "cpu.max")); got != "150000 100000" {
+		t.Fatalf("cpu.max = %q", got)
+	}
+	if got := readFile(t, filepath.Join(g.Path(), "pids.max")); got != "64" {
+		t.Fatalf("pids.max = %q", got)
+	}
+}
+
+func TestCreateReportsUnavailableWithoutDelegation(t *testing.T) {
+	if _, err := Create(Config{Root: t.TempDir(), Parent: "x", Name: "run"}); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Create() on non-cgroup2 root error = %v, want ErrUnavailable", err)
+	}
+
+	root := fakeHierarchy(t, "cpu memory")
+	_, err := Create(Config{Root: root, Parent: "delegated", Name: "run", Limits: Limits{PidsMax: 10}})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Create() without pids controller error = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestVacateMovesSelfIntoSupervisorLeaf(t *testing.T) {
+	root := fakeHierarchy(t, "cpu memory pids")
+	parent := filepath.Join(root, "delegated")
+	if err := vacate(parent); err != nil {
+		t.Fatalf("vacate() of an empty cgroup error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, SupervisorLeaf)); !os.IsNotExist(err) {
+		t.Fatalf("expected no leaf for an empty cgroup, stat error = %v", err)
+	}
+
+	// A fake cgroup.procs does not drop members when one moves, so the
+	// parent still looks occupied afterwards.
+	self := strconv.Itoa(os.Getpid())
+	if err := os.WriteFile(filepath.Join(parent, "cgroup.procs"), []byte(self+"\n"), 0o644); err != nil {
+		t.Fatalf("write procs: %v", err)
+	}
+	if err := vacate(parent); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("vacate() with other members error = %v, want ErrUnavailable", err)
+	}
+	if got := readFile(t, filepath.Join(parent, SupervisorLeaf, "cgroup.procs")); got != self {
+		t.Fatalf("leaf cgroup.procs = %q, want own pid %s", got, self)
+	}
+}
+
+func TestStatsParsesAccountingFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"cpu.stat":       "usage_usec 123456\nuser_usec 100000\nsystem_usec 23456\nnr_periods 40\nnr_throttled 7\nthrottled_usec 98765\n",
+		"memory.events":  "low 0\nhigh 0\nmax 3\noom 2\noom_kill 1\n",
+		"memory.current": "1048576\n",
+		"pids.current":   "5\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	st, err := (&Group{path: dir}).Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	want := Stats{
+		MemoryCurrentBytes: 1048576,
+		MemoryMaxEvents:    3,
+		OOMEvents:          2,
+		OOMKills:           1,
+		CPUUsageMicros:     123456,
+		CPUThrottledCount:  7,
+		CPUThrottledMicros: 98765,
+		PidsCurrent:        5,
+	}
+	if st != want {
+		t.Fatalf("Stats() = %+v, want %+v", st, want)
+	}
+}
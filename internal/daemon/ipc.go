@@ -0,0 +1,476 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flowforge/internal/state"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// IPC message types. Runs send register/telemetry/tree/spend/decision/lifecycle/exit;
+// the daemon sends stop/pause/resume.
+const (
+	MsgRegister  = "register"
+	MsgTelemetry = "telemetry"
+	MsgTree      = "tree"
+	MsgSpend     = "spend"
+	MsgDecision  = "decision"
+	MsgLifecycle = "lifecycle"
+	MsgExit      = "exit"
+	MsgStop      = "stop"
+	MsgPause     = "pause"
+	MsgResume    = "resume"
+)
+
+const ipcMaxMessageBytes = 1024 * 1024
+
+// Message is one newline-delimited JSON frame on the daemon socket.
+type Message struct {
+	Type            string   `json:"type"`
+	RunID           string   `json:"run_id"`
+	Command         string   `json:"command,omitempty"`
+	Args            []string `json:"args,omitempty"`
+	Dir             string   `json:"dir,omitempty"`
+	PID             int      `json:"pid,omitempty"`
+	CPU             float64  `json:"cpu,omitempty"`
+	LastLine        string   `json:"last_line,omitempty"`
+	Status          string   `json:"status,omitempty"`
+	Lifecycle       string   `json:"lifecycle,omitempty"`
+	Action          string   `json:"action,omitempty"`
+	Reason          string   `json:"reason,omitempty"`
+	CPUScore        float64  `json:"cpu_score,omitempty"`
+	EntropyScore    float64  `json:"entropy_score,omitempty"`
+	ConfidenceScore float64  `json:"confidence_score,omitempty"`
+	ExitCode        int      `json:"exit_code,omitempty"`
+	Error           string   `json:"error,omitempty"`
+	GraceMillis     int64    `json:"grace_ms,omitempty"`
+
+	RSSMB     float64               `json:"rss_mb,omitempty"`
+	Threads   int                   `json:"threads,omitempty"`
+	FDs       int                   `json:"fds,omitempty"`
+	Processes []state.ProcessSample `json:"processes,omitempty"`
+
+	Tokens     int64   `json:"tokens,omitempty"`
+	CostUSD    float64 `json:"cost_usd,omitempty"`
+	MaxTokens  int64   `json:"max_tokens,omitempty"`
+	MaxCostUSD float64 `json:"max_cost_usd,omitempty"`
+}
+
+// IPCHandler receives run traffic on the daemon side.
+type IPCHandler interface {
+	// RegisterRun is called once per connection with the run's register frame.
+	RegisterRun(session *Session, msg Message)
+	// HandleRunMessage is called for every subsequent frame from that run.
+	HandleRunMessage(session *Session, msg Message)
+	// EndRun is called once the run has exited or disconnected, after
+	// session.Wait has returned.
+	EndRun(session *Session)
+}
+
+// Session is the daemon-side view of one connected run. It satisfies the
+// api.WorkerController contract so the daemon can stop runs it did not start.
+type Session struct {
+	conn  net.Conn
+	runID string
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	pid      int // Follows the child across supervisor restarts
+	exitCode int
+	exitErr  string
+	exited   bool
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func newSession(conn net.Conn, reg Message) *Session {
+	return &Session{
+		conn:  conn,
+		runID: reg.RunID,
+		pid:   reg.PID,
+		done:  make(chan struct{}),
+	}
+}
+
+func (s *Session) RunID() string { return s.runID }
+
+func (s *Session) PID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pid
+}
+
+func (s *Session) setPID(pid int) {
+	if pid <= 0 {
+		return
+	}
+	s.mu.Lock()
+	s.pid = pid
+	s.mu.Unlock()
+}
+
+func (s *Session) Exited() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until the run reports its exit or disconnects.
+func (s *Session) Wait() error {
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.exited {
+		return errors.New("run disconnected without reporting exit")
+	}
+	if s.exitErr != "" {
+		return errors.New(s.exitErr)
+	}
+	if s.exitCode != 0 {
+		return fmt.Errorf("exit status %d", s.exitCode)
+	}
+	return nil
+}
+
+// Stop asks the run to terminate its process group and waits for it to
+// disconnect. If the run does not respond, the group is killed directly.
+func (s *Session) Stop(grace time.Duration) error {
+	if s.Exited() {
+		return nil
+	}
+	if grace <= 0 {
+		grace = 2 * time.Second
+	}
+	sendErr := s.send(Message{Type: MsgStop, RunID: s.runID, GraceMillis: grace.Milliseconds()})
+
+	select {
+	case <-s.done:
+		return nil
+	case <-time.After(grace + 3*time.Second):
+	}
+
+	if pid := s.PID(); pid > 0 {
+		if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+	select {
+	case <-s.done:
+		return nil
+	case <-time.After(2 * time.Second):
+	}
+	if sendErr != nil {
+		return fmt.Errorf("daemon: stop run %s: %w", s.runID, sendErr)
+	}
+	return fmt.Errorf("daemon: run %s did not exit after stop request", s.runID)
+}
+
+// Pause asks the run to freeze its process group. The run reports the
+// PAUSED lifecycle once it has done so.
+func (s *Session) Pause() error {
+	if s.Exited() {
+		return fmt.Errorf("daemon: run %s has exited", s.runID)
+	}
+	return s.send(Message{Type: MsgPause, RunID: s.runID})
+}
+
+// Resume asks the run to continue a paused process group.
+func (s *Session) Resume() error {
+	if s.Exited() {
+		return fmt.Errorf("daemon: run %s has exited", s.runID)
+	}
+	return s.send(Message{Type: MsgResume, RunID: s.runID})
+}
+
+func (s *Session) send(msg Message) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return writeMessage(s.conn, msg)
+}
+
+func (s *Session) recordExit(msg Message) {
+	s.mu.Lock()
+	s.exited = true
+	s.exitCode = msg.ExitCode
+	s.exitErr = msg.Error
+	s.mu.Unlock()
+}
+
+func (s *Session) close() {
+	s.doneOnce.Do(func() {
+		_ = s.conn.Close()
+		close(s.done)
+	})
+}
+
+// IPCServer accepts run connections on the daemon's Unix socket.
+type IPCServer struct {
+	ln      net.Listener
+	path    string
+	handler IPCHandler
+
+	mu       sync.Mutex
+	sessions map[*Session]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// ListenIPC binds the daemon socket at path, replacing any stale socket file.
+func ListenIPC(path string, handler IPCHandler) (*IPCServer, error) {
+	if handler == nil {
+		return nil, errors.New("ipc handler is required")
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("remove stale daemon socket: %w", err)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on daemon socket: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("restrict daemon socket permissions: %w", err)
+	}
+
+	srv := &IPCServer{
+		ln:       ln,
+		path:     path,
+		handler:  handler,
+		sessions: make(map[*Session]struct{}),
+	}
+	srv.wg.Add(1)
+	go srv.acceptLoop()
+	return srv, nil
+}
+
+// Close stops accepting runs, drops open sessions and removes the socket file.
+func (s *IPCServer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	sessions := make([]*Session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	err := s.ln.Close()
+	for _, sess := range sessions {
+		sess.close()
+	}
+	s.wg.Wait()
+	_ = os.Remove(s.path)
+	return err
+}
+
+func (s *IPCServer) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+func (s *IPCServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+
+	scanner := newMessageScanner(conn)
+	reg, err := readMessage(scanner)
+	if err != nil || reg.Type != MsgRegister || strings.TrimSpace(reg.RunID) == "" {
+		_ = conn.Close()
+		return
+	}
+
+	sess := newSession(conn, reg)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		sess.close()
+		return
+	}
+	s.sessions[sess] = struct{}{}
+	s.mu.Unlock()
+
+	s.handler.RegisterRun(sess, reg)
+	for {
+		msg, err := readMessage(scanner)
+		if err != nil {
+			break
+		}
+		msg.RunID = sess.runID
+		if msg.Type == MsgTelemetry || msg.Type == MsgLifecycle {
+			sess.setPID(msg.PID)
+		}
+		if msg.Type == MsgExit {
+			sess.recordExit(msg)
+		}
+		s.handler.HandleRunMessage(sess, msg)
+		if msg.Type == MsgExit {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.sessions, sess)
+	s.mu.Unlock()
+	sess.close()
+	s.handler.EndRun(sess)
+}
+
+// Client is the run-side connection to the daemon socket.
+type Client struct {
+	conn  net.Conn
+	runID string
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	onStop   func(grace time.Duration)
+	onPause  func()
+	onResume func()
+	closed   bool
+}
+
+// DialIPC connects to the daemon socket and registers the run described by reg.
+func DialIPC(path string, reg Message, timeout time.Duration) (*Client, error) {
+	if strings.TrimSpace(reg.RunID) == "" {
+		return nil, errors.New("run_id is required to register with daemon")
+	}
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return nil, err
+	}
+	reg.Type = MsgRegister
+	if err := writeMessage(conn, reg); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("register with daemon: %w", err)
+	}
+
+	c := &Client{conn: conn, runID: reg.RunID}
+	go c.readLoop()
+	return c, nil
+}
+
+// OnStop sets the callback invoked when the daemon asks the run to stop.
+func (c *Client) OnStop(fn func(grace time.Duration)) {
+	c.mu.Lock()
+	c.onStop = fn
+	c.mu.Unlock()
+}
+
+// OnPause sets the callback invoked when the daemon asks the run to pause.
+func (c *Client) OnPause(fn func()) {
+	c.mu.Lock()
+	c.onPause = fn
+	c.mu.Unlock()
+}
+
+// OnResume sets the callback invoked when the daemon asks the run to resume.
+func (c *Client) OnResume(fn func()) {
+	c.mu.Lock()
+	c.onResume = fn
+	c.mu.Unlock()
+}
+
+// Send forwards one frame to the daemon. The run ID is filled in automatically.
+func (c *Client) Send(msg Message) error {
+	msg.RunID = c.runID
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return writeMessage(c.conn, msg)
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+	return c.conn.Close()
+}
+
+func (c *Client) readLoop() {
+	scanner := newMessageScanner(c.conn)
+	for {
+		msg, err := readMessage(scanner)
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		onStop, onPause, onResume := c.onStop, c.onPause, c.onResume
+		c.mu.Unlock()
+		switch msg.Type {
+		case MsgStop:
+			if onStop != nil {
+				onStop(time.Duration(msg.GraceMillis) * time.Millisecond)
+			}
+		case MsgPause:
+			if onPause != nil {
+				onPause()
+			}
+		case MsgResume:
+			if onResume != nil {
+				onResume()
+			}
+		}
+	}
+}
+
+func newMessageScanner(conn net.Conn) *bufio.Scanner {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), ipcMaxMessageBytes)
+	return scanner
+}
+
+func readMessage(scanner *bufio.Scanner) (Message, error) {
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			return Message{}, fmt.Errorf("decode ipc message: %w", err)
+		}
+		return msg, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return Message{}, err
+	}
+	return Message{}, errors.New("ipc connection closed")
+}
+
+func writeMessage(conn net.Conn, msg Message) error {
+	blob, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	blob = append(blob, '\n')
+	_ = conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn.Write(blob)
+	return err
+}
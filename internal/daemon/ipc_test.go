@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingHandler struct {
+	mu         sync.Mutex
+	registered chan *Session
+	ended      chan *Session
+	messages   []Message
+}
+
+func (h *recordingHandler) RegisterRun(session *Session, msg Message) {
+	h.registered <- session
+}
+
+func (h *recordingHandler) HandleRunMessage(session *Session, msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, msg)
+}
+
+func (h *recordingHandler) EndRun(session *Session) {
+	if h.ended != nil {
+		h.ended <- session
+	}
+}
+
+func (h *recordingHandler) snapshot() []Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Message(nil), h.messages...)
+}
+
+func TestIPCRegisterForwardAndExit(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "ff.sock")
+	handler := &recordingHandler{registered: make(chan *Session, 1)}
+	srv, err := ListenIPC(socket, handler)
+	if err != nil {
+		t.Fatalf("ListenIPC() error = %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+
+	client, err := DialIPC(socket, Message{RunID: "run-1", PID: 4242, Command: "sleep 1"}, time.Second)
+	if err != nil {
+		t.Fatalf("DialIPC() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	var session *Session
+	select {
+	case session = <-handler.registered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("run was not registered")
+	}
+	if session.RunID() != "run-1" || session.PID() != 4242 {
+		t.Fatalf("session = (%q, %d), want (run-1, 4242)", session.RunID(), session.PID())
+	}
+
+	if err := client.Send(Message{Type: MsgTelemetry, CPU: 42.5, Status: "RUNNING"}); err != nil {
+		t.Fatalf("Send(telemetry) error = %v", err)
+	}
+	if err := client.Send(Message{Type: MsgExit, ExitCode: 3}); err != nil {
+		t.Fatalf("Send(exit) error = %v", err)
+	}
+
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- session.Wait() }()
+	select {
+	case err := <-waitErr:
+		if err == nil || err.Error() != "exit status 3" {
+			t.Fatalf("Wait() error = %v, want exit status 3", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("session did not finish after exit frame")
+	}
+
+	msgs := handler.snapshot()
+	if len(msgs) != 2 || msgs[0].Type != MsgTelemetry || msgs[0].RunID != "run-1" || msgs[0].CPU != 42.5 {
+		t.Fatalf("unexpected forwarded messages: %+v", msgs)
+	}
+}
+
+func TestIPCEndRunFollowsDisconnect(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "ff.sock")
+	handler := &recordingHandler{registered: make(chan *Session, 1), ended: make(chan *Session, 1)}
+	srv, err := ListenIPC(socket, handler)
+	if err != nil {
+		t.Fatalf("ListenIPC() error = %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+
+	client, err := DialIPC(socket, Message{RunID: "run-4"}, time.Second)
+	if err != nil {
+		t.Fatalf("DialIPC() error = %v", err)
+	}
+	session := <-handler.registered
+	_ = client.Close()
+
+	select {
+	case ended := <-handler.ended:
+		if ended != session {
+			t.Fatal("EndRun was called with a different session")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("EndRun was not called after the run disconnected")
+	}
+	if err := session.Wait(); err == nil {
+		t.Fatal("Wait() should report a run that disconnected without an exit")
+	}
+}
+
+func TestIPCStopIsDeliveredToRun(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "ff.sock")
+	handler := &recordingHandler{registered: make(chan *Session, 1)}
+	srv, err := ListenIPC(socket, handler)
+	if err != nil {
+		t.Fatalf("ListenIPC() error = %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+
+	client, err := DialIPC(socket, Message{RunID: "run-2"}, time.Second)
+	if err != nil {
+		t.Fatalf("DialIPC() error = %v", err)
+	}
+	stopped := make(chan time.Duration, 1)
+	client.OnStop(func(grace time.Duration) {
+		stopped <- grace
+		_ = client.Send(Message{Type: MsgExit})
+		_ = client.Close()
+	})
+
+	session := <-handler.registered
+	if err := session.Stop(1500 * time.Millisecond); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	select {
+	case grace := <-stopped:
+		if grace != 1500*time.Millisecond {
+			t.Fatalf("stop grace = %s, want 1.5s", grace)
+		}
+	default:
+		t.Fatal("client did not receive stop request")
+	}
+	if !session.Exited() {
+		t.Fatal("session should be exited after stop")
+	}
+}
+
+func TestIPCPauseAndResumeAreDeliveredToRun(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "ff.sock")
+	handler := &recordingHandler{registered: make(chan *Session, 1)}
+	srv, err := ListenIPC(socket, handler)
+	if err != nil {
+		t.Fatalf("ListenIPC() error = %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+
+	client, err := DialIPC(socket, Message{RunID: "run-3", PID: 77}, time.Second)
+	if err != nil {
+		t.Fatalf("DialIPC() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	controls := make(chan string, 2)
+	client.OnPause(func() { controls <- MsgPause })
+	client.OnResume(func() { controls <- MsgResume })
+
+	session := <-handler.registered
+	if err := session.Pause(); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if err := session.Resume(); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	for _, want := range []string{MsgPause, MsgResume} {
+		select {
+		case got := <-controls:
+			if got != want {
+				t.Fatalf("control = %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("client did not receive %s request", want)
+		}
+	}
+
+	// Restarted children report a new PID; the session must follow it.
+	if err := client.Send(Message{Type: MsgLifecycle, Lifecycle: "RUNNING", PID: 78}); err != nil {
+		t.Fatalf("Send(lifecycle) error = %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for session.PID() != 78 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if session.PID() != 78 {
+		t.Fatalf("session PID = %d, want 78", session.PID())
+	}
+}
@@ -18,10 +18,11 @@ const (
 )
 
 type RuntimePaths struct {
-	Dir       string
-	PIDFile   string
-	LogFile   string
-	StateFile string
+	Dir        string
+	PIDFile    string
+	LogFile    string
+	StateFile  string
+	SocketFile string
 }
 
 type State struct {
@@ -50,10 +51,11 @@ func Paths() (RuntimePaths, error) {
 		return RuntimePaths{}, err
 	}
 	return RuntimePaths{
-		Dir:       dir,
-		PIDFile:   filepath.Join(dir, "flowforge-daemon.pid"),
-		LogFile:   filepath.Join(dir, "flowforge-daemon.log"),
-		StateFile: filepath.Join(dir, "flowforge-daemon.state.json"),
+		Dir:        dir,
+		PIDFile:    filepath.Join(dir, "flowforge-daemon.pid"),
+		LogFile:    filepath.Join(dir, "flowforge-daemon.log"),
+		StateFile:  filepath.Join(dir, "flowforge-daemon.state.json"),
+		SocketFile: filepath.Join(dir, "flowforge-daemon.sock"),
 	}, nil
 }
 
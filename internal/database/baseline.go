@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BaselineStat is the learned distribution of one metric for a command
+// fingerprint, merged across runs by the adaptive decider.
+type BaselineStat struct {
+	Metric    string  `json:"metric"`
+	Runs      int     `json:"runs"`
+	Mean      float64 `json:"mean"`
+	Variance  float64 `json:"variance"`
+	UpdatedAt string  `json:"updated_at,omitempty"`
+}
+
+// LoadCommandBaseline returns the stored metrics for fingerprint; none
+// means the command has no history yet.
+func LoadCommandBaseline(fingerprint string) ([]BaselineStat, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+	rows, err := db.Query(`
+SELECT metric, runs, mean, variance, COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM command_baselines
+WHERE fingerprint = ?
+ORDER BY metric
+`, strings.TrimSpace(fingerprint))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []BaselineStat
+	for rows.Next() {
+		var s BaselineStat
+		if err := rows.Scan(&s.Metric, &s.Runs, &s.Mean, &s.Variance, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// SaveCommandBaseline replaces the stored metrics for fingerprint. command
+// is kept for display; the fingerprint is the key.
+func SaveCommandBaseline(fingerprint, command string, stats []BaselineStat) error {
+	if db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	fingerprint = strings.TrimSpace(fingerprint)
+	if fingerprint == "" {
+		return fmt.Errorf("fingerprint is required")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, s := range stats {
+		if _, err := tx.Exec(`
+INSERT INTO command_baselines(fingerprint, metric, command, runs, mean, variance, updated_at)
+VALUES(?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(fingerprint, metric) DO UPDATE SET
+	command = excluded.command,
+	runs = excluded.runs,
+	mean = excluded.mean,
+	variance = excluded.variance,
+	updated_at = CURRENT_TIMESTAMP
+`, fingerprint, s.Metric, command, s.Runs, s.Mean, s.Variance); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestCommandBaselineRoundTrip(t *testing.T) {
+	_ = withTempDBPath(t)
+	CloseDB()
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	if stats, err := LoadCommandBaseline("abc"); err != nil || len(stats) != 0 {
+		t.Fatalf("expected no history, got %+v, %v", stats, err)
+	}
+	if err := SaveCommandBaseline(" ", "python3 job.py", nil); err == nil {
+		t.Fatal("expected an empty fingerprint to be rejected")
+	}
+
+	first := []BaselineStat{
+		{Metric: "cpu_percent", Runs: 1, Mean: 45, Variance: 20},
+		{Metric: "memory_mb", Runs: 1, Mean: 300, Variance: 100},
+	}
+	if err := SaveCommandBaseline("abc", "python3 job.py --seed 1", first); err != nil {
+		t.Fatalf("SaveCommandBaseline: %v", err)
+	}
+	if err := SaveCommandBaseline("abc", "python3 job.py --seed 2", []BaselineStat{{Metric: "cpu_percent", Runs: 2, Mean: 55, Variance: 120}}); err != nil {
+		t.Fatalf("SaveCommandBaseline: %v", err)
+	}
+	if err := SaveCommandBaseline("other", "sleep 1", []BaselineStat{{Metric: "cpu_percent", Runs: 9, Mean: 1}}); err != nil {
+		t.Fatalf("SaveCommandBaseline: %v", err)
+	}
+
+	stats, err := LoadCommandBaseline("abc")
+	if err != nil {
+		t.Fatalf("LoadCommandBaseline: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected two metrics, got %+v", stats)
+	}
+	cpu, mem := stats[0], stats[1]
+	if cpu.Metric != "cpu_percent" || cpu.Runs != 2 || cpu.Mean != 55 || cpu.Variance != 120 || cpu.UpdatedAt == "" {
+		t.Fatalf("expected the updated cpu row, got %+v", cpu)
+	}
+	if mem.Metric != "memory_mb" || mem.Runs != 1 || mem.Mean != 300 {
+		t.Fatalf("expected the memory row to be kept, got %+v", mem)
+	}
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"encoding/json"
+	"flowforge/internal/encryption"
+	"fmt"
+	"strings"
+)
+
+// CrashSignature is the payload of a crash_signature event: the stack
+// trace that kept recurring when an incident was raised.
+type CrashSignature struct {
+	Fingerprint string   `json:"fingerprint"`
+	Language    string   `json:"language"`
+	Exception   string   `json:"exception"`
+	Frames      []string `json:"frames"`
+	Count       int      `json:"count"`
+	Sample      string   `json:"sample"` // One occurrence as printed
+}
+
+// LogCrashSignature records the repeating stack trace behind incidentID.
+// The sample is encrypted like incident patterns.
+func LogCrashSignature(command string, sig CrashSignature, incidentID string) error {
+	if enc, _ := encryption.Encrypt(sig.Sample); enc != "" {
+		sig.Sample = enc
+	}
+	summary := fmt.Sprintf("%s: %s seen %d times", command, sig.Fingerprint, sig.Count)
+	return logUnifiedEventWithPayload("crash_signature", "CRASH_SIGNATURE", summary, sig.Fingerprint, "system", incidentID, 0, 0, 0, 0, sig)
+}
+
+// crashSignaturesByIncident maps incident IDs to their crash signature.
+func crashSignaturesByIncident() (map[string]*CrashSignature, error) {
+	rows, err := db.Query(`
+SELECT incident_id, COALESCE(payload_json, '{}')
+FROM events
+WHERE event_type = 'crash_signature' AND incident_id IS NOT NULL
+ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]*CrashSignature)
+	for rows.Next() {
+		var incidentID, payloadRaw string
+		if err := rows.Scan(&incidentID, &payloadRaw); err != nil {
+			return nil, err
+		}
+		var sig CrashSignature
+		if err := json.Unmarshal([]byte(payloadRaw), &sig); err != nil || sig.Fingerprint == "" {
+			continue
+		}
+		sig.Sample = decryptIfPossible(sig.Sample)
+		out[strings.TrimSpace(incidentID)] = &sig
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCrashSignatureIsSurfacedOnItsIncident(t *testing.T) {
+	_ = withTempDBPath(t)
+	CloseDB()
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	sig := CrashSignature{
+		Fingerprint: "python:KeyError @ tools.py:fetch_page < agent.py:main",
+		Language:    "python",
+		Exception:   "KeyError",
+		Frames:      []string{"tools.py:fetch_page", "agent.py:main"},
+		Count:       3,
+		Sample:      "Traceback (most recent call last):\n  File \"/app/tools.py\", line 41, in fetch_page\nKeyError: 'x'",
+	}
+	if err := LogIncidentWithDecisionForIncident("python3 agent.py", "gpt-4", "LOOP_DETECTED", 70, sig.Fingerprint, 3, 0, 0, "agent", "1.0.0", "same stack trace 3 times", 0, 0, 0, "terminated", 0, "inc-trace"); err != nil {
+		t.Fatalf("LogIncidentWithDecisionForIncident: %v", err)
+	}
+	if err := LogCrashSignature("python3 agent.py", sig, "inc-trace"); err != nil {
+		t.Fatalf("LogCrashSignature: %v", err)
+	}
+	if err := LogIncident("python3 other.py", "gpt-4", "LOOP_DETECTED", 1, "retry", 1, 0, 0, "agent", "1.0.0"); err != nil {
+		t.Fatalf("LogIncident: %v", err)
+	}
+
+	incidents, err := GetAllIncidents()
+	if err != nil {
+		t.Fatalf("GetAllIncidents: %v", err)
+	}
+	var found *CrashSignature
+	for _, inc := range incidents {
+		if inc.Command == "python3 other.py" && inc.Trace != nil {
+			t.Fatalf("unlinked incident should have no trace, got %+v", inc.Trace)
+		}
+		if inc.Command == "python3 agent.py" {
+			found = inc.Trace
+		}
+	}
+	if found == nil {
+		t.Fatal("expected the traceback incident to carry its crash signature")
+	}
+	if !reflect.DeepEqual(*found, sig) {
+		t.Fatalf("trace = %+v, want %+v", *found, sig)
+	}
+}
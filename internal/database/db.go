@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"flowforge/internal/encryption"
+	"flowforge/internal/state"
 	"fmt"
 	"os"
 	"sort"
@@ -40,6 +41,10 @@ type Incident struct {
 	ConfidenceScore      float64 `json:"confidence_score"`
 	RecoveryStatus       string  `json:"recovery_status"`
 	RestartCount         int     `json:"restart_count"`
+	// Exit is how the attempt that raised the incident ended, when known.
+	Exit *RunExit `json:"exit,omitempty"`
+	// Trace is the stack trace that kept recurring, for traceback loops.
+	Trace *CrashSignature `json:"trace,omitempty"`
 }
 
 type AuditEvent struct {
@@ -55,15 +60,16 @@ type AuditEvent struct {
 }
 
 type DecisionTrace struct {
-	ID              int     `json:"id"`
-	Timestamp       string  `json:"timestamp"`
-	Command         string  `json:"command"`
-	PID             int     `json:"pid"`
-	CPUScore        float64 `json:"cpu_score"`
-	EntropyScore    float64 `json:"entropy_score"`
-	ConfidenceScore float64 `json:"confidence_score"`
-	Decision        string  `json:"decision"`
-	Reason          string  `json:"reason"`
+	ID              int                   `json:"id"`
+	Timestamp       string                `json:"timestamp"`
+	Command         string                `json:"command"`
+	PID             int                   `json:"pid"`
+	CPUScore        float64               `json:"cpu_score"`
+	EntropyScore    float64               `json:"entropy_score"`
+	ConfidenceScore float64               `json:"confidence_score"`
+	Decision        string                `json:"decision"`
+	Reason          string                `json:"reason"`
+	Processes       []state.ProcessSample `json:"processes,omitempty"`
 }
 
 type TimelineEvent struct {
@@ -134,8 +140,9 @@ type auditEventPayload struct {
 }
 
 type decisionEventPayload struct {
-	ID      int    `json:"id"`
-	Command string `json:"command"`
+	ID        int                   `json:"id"`
+	Command   string                `json:"command"`
+	Processes []state.ProcessSample `json:"processes,omitempty"`
 }
 
 func InitDB() error {
@@ -216,6 +223,7 @@ func InitDB() error {
 	if _, err := db.Exec(createDecisionTableSQL); err != nil {
 		return err
 	}
+	db.Exec("ALTER TABLE decision_traces ADD COLUMN processes_json TEXT DEFAULT '';")
 
 	createEventsTableSQL := `CREATE TABLE IF NOT EXISTS events (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -374,6 +382,35 @@ func InitDB() error {
 		return err
 	}
 
+	createCommandBaselinesTableSQL := `CREATE TABLE IF NOT EXISTS command_baselines (
+		fingerprint TEXT NOT NULL,
+		metric TEXT NOT NULL,
+		command TEXT NOT NULL DEFAULT '',
+		runs INTEGER NOT NULL DEFAULT 0,
+		mean REAL NOT NULL DEFAULT 0,
+		variance REAL NOT NULL DEFAULT 0,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY(fingerprint, metric)
+	);`
+	if _, err := db.Exec(createCommandBaselinesTableSQL); err != nil {
+		return err
+	}
+
+	createPatternsTableSQL := `CREATE TABLE IF NOT EXISTS patterns (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pattern TEXT NOT NULL UNIQUE,
+		source_command TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL DEFAULT 'warn',
+		enabled INTEGER NOT NULL DEFAULT 1,
+		hit_count INTEGER NOT NULL DEFAULT 0,
+		ttl_seconds INTEGER NOT NULL DEFAULT 0,
+		first_seen DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_seen DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+	if _, err := db.Exec(createPatternsTableSQL); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -640,23 +677,38 @@ func LogDecisionTrace(command string, pid int, cpuScore, entropyScore, confidenc
 }
 
 func LogDecisionTraceWithIncident(command string, pid int, cpuScore, entropyScore, confidenceScore float64, decision, reason, incidentID string) error {
+	return LogDecisionTraceWithProcesses(command, pid, cpuScore, entropyScore, confidenceScore, decision, reason, incidentID, nil)
+}
+
+// LogDecisionTraceWithProcesses records a decision together with the
+// per-process breakdown of the supervised tree at decision time.
+func LogDecisionTraceWithProcesses(command string, pid int, cpuScore, entropyScore, confidenceScore float64, decision, reason, incidentID string, processes []state.ProcessSample) error {
 	if db == nil {
 		return fmt.Errorf("db not initialized")
 	}
-	stmt, err := db.Prepare("INSERT INTO decision_traces(command, pid, cpu_score, entropy_score, confidence_score, decision, reason) VALUES(?, ?, ?, ?, ?, ?, ?)")
+	processesJSON := ""
+	if len(processes) > 0 {
+		blob, err := json.Marshal(processes)
+		if err != nil {
+			return err
+		}
+		processesJSON = string(blob)
+	}
+	stmt, err := db.Prepare("INSERT INTO decision_traces(command, pid, cpu_score, entropy_score, confidence_score, decision, reason, processes_json) VALUES(?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	result, err := stmt.Exec(command, pid, cpuScore, entropyScore, confidenceScore, decision, reason)
+	result, err := stmt.Exec(command, pid, cpuScore, entropyScore, confidenceScore, decision, reason, processesJSON)
 	if err != nil {
 		return err
 	}
 	insertedID, _ := result.LastInsertId()
 	summary := fmt.Sprintf("CPU %.1f / Entropy %.1f / Confidence %.1f", cpuScore, entropyScore, confidenceScore)
 	payload := decisionEventPayload{
-		ID:      int(insertedID),
-		Command: command,
+		ID:        int(insertedID),
+		Command:   command,
+		Processes: processes,
 	}
 	return logUnifiedEventWithPayload("decision", decision, summary, reason, "system", incidentID, pid, cpuScore, entropyScore, confidenceScore, payload)
 }
@@ -668,7 +720,7 @@ func GetDecisionTraces(limit int) ([]DecisionTrace, error) {
 	if limit <= 0 {
 		limit = 100
 	}
-	rows, err := db.Query("SELECT id, timestamp, COALESCE(command, ''), COALESCE(pid, 0), COALESCE(cpu_score, 0.0), COALESCE(entropy_score, 0.0), COALESCE(confidence_score, 0.0), COALESCE(decision, ''), COALESCE(reason, '') FROM decision_traces ORDER BY id DESC LIMIT ?", limit)
+	rows, err := db.Query("SELECT id, timestamp, COALESCE(command, ''), COALESCE(pid, 0), COALESCE(cpu_score, 0.0), COALESCE(entropy_score, 0.0), COALESCE(confidence_score, 0.0), COALESCE(decision, ''), COALESCE(reason, ''), COALESCE(processes_json, '') FROM decision_traces ORDER BY id DESC LIMIT ?", limit)
 	if err != nil {
 		return nil, err
 	}
@@ -676,9 +728,13 @@ func GetDecisionTraces(limit int) ([]DecisionTrace, error) {
 	var traces []DecisionTrace
 	for rows.Next() {
 		var t DecisionTrace
-		if err := rows.Scan(&t.ID, &t.Timestamp, &t.Command, &t.PID, &t.CPUScore, &t.EntropyScore, &t.ConfidenceScore, &t.Decision, &t.Reason); err != nil {
+		var processesJSON string
+		if err := rows.Scan(&t.ID, &t.Timestamp, &t.Command, &t.PID, &t.CPUScore, &t.EntropyScore, &t.ConfidenceScore, &t.Decision, &t.Reason, &processesJSON); err != nil {
 			return nil, err
 		}
+		if processesJSON != "" {
+			_ = json.Unmarshal([]byte(processesJSON), &t.Processes)
+		}
 		traces = append(traces, t)
 	}
 	return traces, nil
@@ -1160,10 +1216,19 @@ func decryptIfPossible(value string) string {
 }
 
 func getIncidentsFromUnifiedEvents() ([]Incident, error) {
+	exits, err := runExitsByIncident()
+	if err != nil {
+		return nil, err
+	}
+	traces, err := crashSignaturesByIncident()
+	if err != nil {
+		return nil, err
+	}
 	rows, err := db.Query(`
 SELECT
 	COALESCE(payload_json, '{}'),
-	COALESCE(created_at, timestamp, CURRENT_TIMESTAMP)
+	COALESCE(created_at, timestamp, CURRENT_TIMESTAMP),
+	COALESCE(incident_id, '')
 FROM events
 WHERE event_type = 'incident'
 ORDER BY created_at DESC, id DESC`)
@@ -1174,8 +1239,8 @@ ORDER BY created_at DESC, id DESC`)
 
 	incidents := make([]Incident, 0)
 	for rows.Next() {
-		var payloadRaw, ts string
-		if err := rows.Scan(&payloadRaw, &ts); err != nil {
+		var payloadRaw, ts, incidentID string
+		if err := rows.Scan(&payloadRaw, &ts, &incidentID); err != nil {
 			return nil, err
 		}
 		payloadRaw = strings.TrimSpace(payloadRaw)
@@ -1210,6 +1275,8 @@ ORDER BY created_at DESC, id DESC`)
 			ConfidenceScore:      payload.ConfidenceScore,
 			RecoveryStatus:       payload.RecoveryStatus,
 			RestartCount:         payload.RestartCount,
+			Exit:                 exits[strings.TrimSpace(incidentID)],
+			Trace:                traces[strings.TrimSpace(incidentID)],
 		})
 	}
 	return incidents, nil
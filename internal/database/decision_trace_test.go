@@ -0,0 +1,40 @@
+package database
+
+import (
+	"flowforge/internal/state"
+	"testing"
+)
+
+func TestDecisionTraceStoresProcessBreakdown(t *testing.T) {
+	_ = withTempDBPath(t)
+	CloseDB()
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	processes := []state.ProcessSample{
+		{PID: 202, PPID: 201, Name: "python3", CPU: 97.5, RSSMB: 48.2, Threads: 4, FDs: 12},
+		{PID: 201, PPID: 1, Name: "bash", CPU: 0.1, RSSMB: 3.1, Threads: 1, FDs: 4},
+	}
+	if err := LogDecisionTraceWithProcesses("bash -c ./agent.py", 201, 97.6, 10, 90, "KILL", "cpu", "", processes); err != nil {
+		t.Fatalf("LogDecisionTraceWithProcesses: %v", err)
+	}
+	if err := LogDecisionTrace("bash -c ./agent.py", 201, 1, 1, 1, "CONTINUE", "healthy"); err != nil {
+		t.Fatalf("LogDecisionTrace: %v", err)
+	}
+
+	traces, err := GetDecisionTraces(10)
+	if err != nil {
+		t.Fatalf("GetDecisionTraces: %v", err)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("expected 2 traces, got %d", len(traces))
+	}
+	if len(traces[0].Processes) != 0 {
+		t.Fatalf("trace without breakdown should have no processes, got %+v", traces[0].Processes)
+	}
+	got := traces[1].Processes
+	if len(got) != 2 || got[0] != processes[0] || got[1] != processes[1] {
+		t.Fatalf("processes round-trip mismatch: %+v", got)
+	}
+}
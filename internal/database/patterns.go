@@ -0,0 +1,225 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Pattern actions: what a run does when its output matches the pattern.
+const (
+	PatternActionWarn  = "warn"  // Print an early warning only
+	PatternActionAlert = "alert" // Raise a policy alert
+	PatternActionKill  = "kill"  // Treat the match as a breach
+)
+
+// ErrPatternNotFound is returned for an unknown pattern ID.
+var ErrPatternNotFound = errors.New("pattern not found")
+
+// Pattern is a known bad output pattern in the registry. Patterns are
+// normalized log lines, stored as is so they can be matched and exported.
+type Pattern struct {
+	ID            int64  `json:"id,omitempty"`
+	Pattern       string `json:"pattern"`
+	SourceCommand string `json:"source_command,omitempty"`
+	Action        string `json:"action"`
+	Enabled       bool   `json:"enabled"`
+	HitCount      int    `json:"hit_count"`
+	TTLSeconds    int64  `json:"ttl_seconds,omitempty"` // Expires this long after LastSeen; 0 never expires
+	FirstSeen     string `json:"first_seen,omitempty"`
+	LastSeen      string `json:"last_seen,omitempty"`
+	Expired       bool   `json:"expired,omitempty"`
+}
+
+// ValidPatternAction reports whether action is warn, alert or kill.
+func ValidPatternAction(action string) bool {
+	switch action {
+	case PatternActionWarn, PatternActionAlert, PatternActionKill:
+		return true
+	}
+	return false
+}
+
+const patternColumns = `id, pattern, source_command, action, enabled, hit_count, ttl_seconds, first_seen, last_seen,
+	ttl_seconds > 0 AND datetime(last_seen, '+' || ttl_seconds || ' seconds') <= CURRENT_TIMESTAMP`
+
+// ListPatterns returns every pattern in the registry, most recently seen
+// first.
+func ListPatterns() ([]Pattern, error) {
+	return queryPatterns(`SELECT ` + patternColumns + ` FROM patterns ORDER BY last_seen DESC, id DESC`)
+}
+
+// ActivePatterns returns the enabled, unexpired patterns runs match against.
+func ActivePatterns() ([]Pattern, error) {
+	return queryPatterns(`SELECT ` + patternColumns + ` FROM patterns
+WHERE enabled = 1 AND (ttl_seconds = 0 OR datetime(last_seen, '+' || ttl_seconds || ' seconds') > CURRENT_TIMESTAMP)
+ORDER BY id`)
+}
+
+// GetPattern returns one pattern by ID.
+func GetPattern(id int64) (Pattern, error) {
+	ps, err := queryPatterns(`SELECT `+patternColumns+` FROM patterns WHERE id = ?`, id)
+	if err != nil {
+		return Pattern{}, err
+	}
+	if len(ps) == 0 {
+		return Pattern{}, ErrPatternNotFound
+	}
+	return ps[0], nil
+}
+
+func queryPatterns(query string, args ...interface{}) ([]Pattern, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Pattern
+	for rows.Next() {
+		var p Pattern
+		if err := rows.Scan(&p.ID, &p.Pattern, &p.SourceCommand, &p.Action, &p.Enabled, &p.HitCount, &p.TTLSeconds, &p.FirstSeen, &p.LastSeen, &p.Expired); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// AddPattern stores a newly learned pattern with one hit and returns it
+// with its ID. Adding a pattern that is already stored counts a hit on it.
+func AddPattern(p Pattern) (Pattern, error) {
+	if db == nil {
+		return Pattern{}, fmt.Errorf("db not initialized")
+	}
+	p.Pattern = strings.TrimSpace(p.Pattern)
+	if p.Pattern == "" {
+		return Pattern{}, fmt.Errorf("pattern is required")
+	}
+	if p.Action == "" {
+		p.Action = PatternActionWarn
+	}
+	if !ValidPatternAction(p.Action) {
+		return Pattern{}, fmt.Errorf("action must be warn, alert or kill, got %q", p.Action)
+	}
+	var id int64
+	err := db.QueryRow(`
+INSERT INTO patterns(pattern, source_command, action, enabled, hit_count, ttl_seconds, first_seen, last_seen)
+VALUES(?, ?, ?, 1, 1, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+ON CONFLICT(pattern) DO UPDATE SET
+	hit_count = hit_count + 1,
+	last_seen = CURRENT_TIMESTAMP
+RETURNING id
+`, p.Pattern, p.SourceCommand, p.Action, p.TTLSeconds).Scan(&id)
+	if err != nil {
+		return Pattern{}, err
+	}
+	return GetPattern(id)
+}
+
+// RecordPatternHit counts a match of pattern id and renews its TTL.
+func RecordPatternHit(id int64) error {
+	if db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	return execPattern(`UPDATE patterns SET hit_count = hit_count + 1, last_seen = CURRENT_TIMESTAMP WHERE id = ?`, id)
+}
+
+// SetPatternEnabled turns matching of pattern id on or off.
+func SetPatternEnabled(id int64, enabled bool) error {
+	if db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	return execPattern(`UPDATE patterns SET enabled = ? WHERE id = ?`, enabled, id)
+}
+
+func execPattern(query string, args ...interface{}) error {
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrPatternNotFound
+	}
+	return nil
+}
+
+// ImportPatterns merges patterns, typically from another registry's
+// export, keyed by pattern text. Action, enabled flag and TTL come from the
+// import; hit counts and first/last seen keep the larger history. It
+// returns how many patterns were new.
+func ImportPatterns(ps []Pattern) (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("db not initialized")
+	}
+	for i, p := range ps {
+		if strings.TrimSpace(p.Pattern) == "" {
+			return 0, fmt.Errorf("pattern %d: pattern is required", i+1)
+		}
+		if p.Action != "" && !ValidPatternAction(p.Action) {
+			return 0, fmt.Errorf("pattern %d: action must be warn, alert or kill, got %q", i+1, p.Action)
+		}
+		if p.TTLSeconds < 0 {
+			return 0, fmt.Errorf("pattern %d: ttl_seconds must be >= 0", i+1)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	added := 0
+	for _, p := range ps {
+		p.Pattern = strings.TrimSpace(p.Pattern)
+		if p.Action == "" {
+			p.Action = PatternActionWarn
+		}
+		var exists int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM patterns WHERE pattern = ?`, p.Pattern).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if exists == 0 {
+			added++
+		}
+		firstSeen, lastSeen := sqlTimestamp(p.FirstSeen), sqlTimestamp(p.LastSeen)
+		if _, err := tx.Exec(`
+INSERT INTO patterns(pattern, source_command, action, enabled, hit_count, ttl_seconds, first_seen, last_seen)
+VALUES(?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP), COALESCE(?, CURRENT_TIMESTAMP))
+ON CONFLICT(pattern) DO UPDATE SET
+	source_command = CASE WHEN excluded.source_command != '' THEN excluded.source_command ELSE source_command END,
+	action = excluded.action,
+	enabled = excluded.enabled,
+	ttl_seconds = excluded.ttl_seconds,
+	hit_count = MAX(hit_count, excluded.hit_count),
+	first_seen = MIN(first_seen, excluded.first_seen),
+	last_seen = MAX(last_seen, excluded.last_seen)
+`, p.Pattern, p.SourceCommand, p.Action, p.Enabled, p.HitCount, p.TTLSeconds, firstSeen, lastSeen); err != nil {
+			return 0, err
+		}
+	}
+	return added, tx.Commit()
+}
+
+// sqlTimestamp converts an exported time to the registry's UTC
+// "YYYY-MM-DD HH:MM:SS" form, or nil when it is empty or unreadable.
+func sqlTimestamp(raw string) interface{} {
+	t := parseTimestamp(strings.TrimSpace(raw))
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// CountPatterns returns how many patterns the registry holds.
+func CountPatterns() (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("db not initialized")
+	}
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM patterns`).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPatternRegistryLifecycle(t *testing.T) {
+	_ = withTempDBPath(t)
+	CloseDB()
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	if _, err := AddPattern(Pattern{Pattern: "retrying request <NUM>", Action: "explode"}); err == nil {
+		t.Fatal("expected an unknown action to be rejected")
+	}
+	p, err := AddPattern(Pattern{Pattern: " retrying request <NUM> ", SourceCommand: "python3 agent.py", Action: PatternActionKill})
+	if err != nil {
+		t.Fatalf("AddPattern: %v", err)
+	}
+	if p.ID == 0 || p.Pattern != "retrying request <NUM>" || p.HitCount != 1 || !p.Enabled || p.FirstSeen == "" || p.Expired {
+		t.Fatalf("unexpected stored pattern: %+v", p)
+	}
+	if again, err := AddPattern(Pattern{Pattern: "retrying request <NUM>"}); err != nil || again.ID != p.ID || again.HitCount != 2 || again.Action != PatternActionKill {
+		t.Fatalf("expected a re-add to count a hit on #%d, got %+v, %v", p.ID, again, err)
+	}
+	if err := RecordPatternHit(p.ID); err != nil {
+		t.Fatalf("RecordPatternHit: %v", err)
+	}
+
+	if err := SetPatternEnabled(p.ID, false); err != nil {
+		t.Fatalf("SetPatternEnabled: %v", err)
+	}
+	if active, err := ActivePatterns(); err != nil || len(active) != 0 {
+		t.Fatalf("expected no active patterns after disable, got %+v, %v", active, err)
+	}
+	if err := SetPatternEnabled(999, true); !errors.Is(err, ErrPatternNotFound) {
+		t.Fatalf("expected ErrPatternNotFound, got %v", err)
+	}
+	if err := SetPatternEnabled(p.ID, true); err != nil {
+		t.Fatalf("SetPatternEnabled: %v", err)
+	}
+	got, err := GetPattern(p.ID)
+	if err != nil || got.HitCount != 3 || !got.Enabled {
+		t.Fatalf("expected 3 hits and enabled, got %+v, %v", got, err)
+	}
+
+	// A pattern past its TTL stays listed but stops matching.
+	stale, err := AddPattern(Pattern{Pattern: "connection refused", TTLSeconds: 3600})
+	if err != nil {
+		t.Fatalf("AddPattern: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE patterns SET last_seen = datetime('now', '-2 hours') WHERE id = ?`, stale.ID); err != nil {
+		t.Fatalf("age pattern: %v", err)
+	}
+	active, err := ActivePatterns()
+	if err != nil || len(active) != 1 || active[0].ID != p.ID {
+		t.Fatalf("expected only #%d active, got %+v, %v", p.ID, active, err)
+	}
+	all, err := ListPatterns()
+	if err != nil || len(all) != 2 {
+		t.Fatalf("expected both patterns listed, got %+v, %v", all, err)
+	}
+	for _, listed := range all {
+		if listed.Expired != (listed.ID == stale.ID) {
+			t.Fatalf("unexpected expired flag on %+v", listed)
+		}
+	}
+}
+
+func TestImportPatternsMergesHistory(t *testing.T) {
+	_ = withTempDBPath(t)
+	CloseDB()
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	local, err := AddPattern(Pattern{Pattern: "rate limited, sleeping <NUM>s", SourceCommand: "node agent.js"})
+	if err != nil {
+		t.Fatalf("AddPattern: %v", err)
+	}
+	if _, err := ImportPatterns([]Pattern{{Pattern: "ok", Action: "explode"}}); err == nil {
+		t.Fatal("expected an unknown action to fail the whole import")
+	}
+
+	added, err := ImportPatterns([]Pattern{
+		{Pattern: "rate limited, sleeping <NUM>s", Action: PatternActionAlert, Enabled: true, HitCount: 40, FirstSeen: "2026-01-02T03:04:05Z", LastSeen: "2026-01-03T00:00:00Z"},
+		{Pattern: "tool call failed: timeout", Action: PatternActionKill, Enabled: false, HitCount: 7, TTLSeconds: 86400},
+	})
+	if err != nil {
+		t.Fatalf("ImportPatterns: %v", err)
+	}
+	if added != 1 {
+		t.Fatalf("expected 1 new pattern, got %d", added)
+	}
+
+	merged, err := GetPattern(local.ID)
+	if err != nil {
+		t.Fatalf("GetPattern: %v", err)
+	}
+	if merged.Action != PatternActionAlert || merged.HitCount != 40 || merged.SourceCommand != "node agent.js" {
+		t.Fatalf("unexpected merged pattern: %+v", merged)
+	}
+	if ts := parseTimestamp(merged.FirstSeen); ts.Year() != 2026 || ts.Month() != 1 || ts.Day() != 2 {
+		t.Fatalf("expected the earlier first_seen to win, got %q", merged.FirstSeen)
+	}
+	if merged.LastSeen == "2026-01-03T00:00:00Z" {
+		t.Fatalf("expected the local, later last_seen to win, got %q", merged.LastSeen)
+	}
+
+	if n, err := CountPatterns(); err != nil || n != 2 {
+		t.Fatalf("expected 2 patterns, got %d, %v", n, err)
+	}
+	if active, err := ActivePatterns(); err != nil || len(active) != 1 {
+		t.Fatalf("expected the disabled import to stay inactive, got %+v, %v", active, err)
+	}
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"flowforge/internal/encryption"
+	"fmt"
+)
+
+// RuleMatch is the payload of a rule_match event: a user-defined detection
+// rule that reached its hit count.
+type RuleMatch struct {
+	Rule          string  `json:"rule"`
+	Severity      string  `json:"severity"`
+	Action        string  `json:"action"`
+	Hits          int     `json:"hits"`
+	WindowSeconds float64 `json:"window_seconds"`
+	Stream        string  `json:"stream,omitempty"`
+	Line          string  `json:"line"` // The line that completed the count
+}
+
+// LogRuleMatch records that a rule fired. The line is encrypted like
+// incident patterns.
+func LogRuleMatch(command string, m RuleMatch, pid int) error {
+	if enc, _ := encryption.Encrypt(m.Line); enc != "" {
+		m.Line = enc
+	}
+	summary := fmt.Sprintf("%s: rule %s matched %d times (%s, action %s)", command, m.Rule, m.Hits, m.Severity, m.Action)
+	return logUnifiedEventWithPayload("rule_match", "RULE_MATCHED", summary, m.Rule, "system", "", pid, 0, 0, 0, m)
+}
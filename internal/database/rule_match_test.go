@@ -0,0 +1,31 @@
+package database
+
+import "testing"
+
+func TestRuleMatchIsOnTheTimeline(t *testing.T) {
+	_ = withTempDBPath(t)
+	CloseDB()
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	m := RuleMatch{Rule: "rate-limited", Severity: "warning", Action: "pause", Hits: 5, WindowSeconds: 60, Stream: "stderr", Line: "HTTP 429 Too Many Requests"}
+	if err := LogRuleMatch("python3 agent.py", m, 4242); err != nil {
+		t.Fatalf("LogRuleMatch: %v", err)
+	}
+
+	events, err := GetUnifiedEvents(10)
+	if err != nil {
+		t.Fatalf("GetUnifiedEvents: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	e := events[0]
+	if e.EventType != "rule_match" || e.Title != "RULE_MATCHED" || e.Reason != "rate-limited" || e.PID != 4242 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if e.Evidence["rule"] != "rate-limited" || e.Evidence["hits"] != float64(5) || e.Evidence["action"] != "pause" {
+		t.Fatalf("unexpected payload: %+v", e.Evidence)
+	}
+}
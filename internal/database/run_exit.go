@@ -0,0 +1,89 @@
+package database
+
+import (
+	"encoding/json"
+	"flowforge/internal/supervisor"
+	"fmt"
+	"strings"
+)
+
+// Exit classes recorded for a stop, refining supervisor.ExitClassStopped by
+// who asked for it.
+const (
+	ExitClassFlowForgeKill = "flowforge_kill"
+	ExitClassOperatorStop  = "operator_stop"
+)
+
+// RunExit is the payload of a run_exit event: how one attempt of a
+// supervised command ended.
+type RunExit struct {
+	Class           string  `json:"class"`
+	Summary         string  `json:"summary"`
+	ExitCode        int     `json:"exit_code"`
+	Signal          string  `json:"signal,omitempty"`
+	CoreDumped      bool    `json:"core_dumped"`
+	OOMKilled       bool    `json:"oom_killed"`
+	OOMSource       string  `json:"oom_source,omitempty"`
+	CPUSeconds      float64 `json:"cpu_seconds"`
+	MaxRSSMB        float64 `json:"max_rss_mb"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	PID             int     `json:"pid"`
+	Attempt         int     `json:"attempt"`
+}
+
+// NewRunExit builds the payload for info. stopClass replaces the generic
+// "stopped" class with who requested the stop.
+func NewRunExit(info supervisor.ExitInfo, pid, attempt int, stopClass string) RunExit {
+	class := string(info.Class)
+	if info.Class == supervisor.ExitClassStopped && stopClass != "" {
+		class = stopClass
+	}
+	return RunExit{
+		Class:           class,
+		Summary:         info.String(),
+		ExitCode:        info.ExitCode,
+		Signal:          info.Signal,
+		CoreDumped:      info.CoreDumped,
+		OOMKilled:       info.OOMKilled,
+		OOMSource:       info.OOMSource,
+		CPUSeconds:      info.CPUSeconds,
+		MaxRSSMB:        info.MaxRSSMB,
+		DurationSeconds: info.Runtime.Seconds(),
+		PID:             pid,
+		Attempt:         attempt,
+	}
+}
+
+// LogRunExit records a run_exit event, linked to the incident that ended
+// the attempt when there was one.
+func LogRunExit(command string, exit RunExit, incidentID string) error {
+	summary := fmt.Sprintf("%s %s after %.1fs (cpu %.2fs, max rss %.1fMB)", command, exit.Summary, exit.DurationSeconds, exit.CPUSeconds, exit.MaxRSSMB)
+	return logUnifiedEventWithPayload("run_exit", "RUN_EXIT", summary, exit.Class, "system", incidentID, exit.PID, 0, 0, 0, exit)
+}
+
+// runExitsByIncident maps incident IDs to the run_exit recorded with them.
+func runExitsByIncident() (map[string]*RunExit, error) {
+	rows, err := db.Query(`
+SELECT incident_id, COALESCE(payload_json, '{}')
+FROM events
+WHERE event_type = 'run_exit' AND incident_id IS NOT NULL
+ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]*RunExit)
+	for rows.Next() {
+		var incidentID, payloadRaw string
+		if err := rows.Scan(&incidentID, &payloadRaw); err != nil {
+			return nil, err
+		}
+		var exit RunExit
+		if err := json.Unmarshal([]byte(payloadRaw), &exit); err != nil || exit.Class == "" {
+			continue
+		}
+		out[strings.TrimSpace(incidentID)] = &exit
+	}
+	return out, rows.Err()
+}
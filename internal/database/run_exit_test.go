@@ -0,0 +1,74 @@
+package database
+
+import (
+	"flowforge/internal/supervisor"
+	"testing"
+	"time"
+)
+
+func TestRunExitIsSurfacedOnItsIncident(t *testing.T) {
+	_ = withTempDBPath(t)
+	CloseDB()
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	info := supervisor.ExitInfo{
+		Class:      supervisor.ExitClassCrash,
+		ExitCode:   -1,
+		Signal:     "SIGSEGV",
+		CoreDumped: true,
+		CPUSeconds: 1.5,
+		MaxRSSMB:   64,
+		Runtime:    3 * time.Second,
+	}
+	if err := LogIncidentWithDecisionForIncident("python3 agent.py", "gpt-4", "PROCESS_CRASHED", 12, "N/A", 3, 0, 0, "agent", "1.0.0", info.String(), 0, 0, 0, "", 0, "inc-crash"); err != nil {
+		t.Fatalf("LogIncidentWithDecisionForIncident: %v", err)
+	}
+	if err := LogRunExit("python3 agent.py", NewRunExit(info, 4242, 0, ExitClassFlowForgeKill), "inc-crash"); err != nil {
+		t.Fatalf("LogRunExit: %v", err)
+	}
+	if err := LogIncident("python3 other.py", "gpt-4", "USER_TERMINATED", 1, "N/A", 1, 0, 0, "agent", "1.0.0"); err != nil {
+		t.Fatalf("LogIncident: %v", err)
+	}
+	stopped := NewRunExit(supervisor.ExitInfo{Class: supervisor.ExitClassStopped, ExitCode: -1, Signal: "SIGTERM"}, 4343, 1, ExitClassOperatorStop)
+	if err := LogRunExit("python3 other.py", stopped, ""); err != nil {
+		t.Fatalf("LogRunExit without incident: %v", err)
+	}
+
+	incidents, err := GetAllIncidents()
+	if err != nil {
+		t.Fatalf("GetAllIncidents: %v", err)
+	}
+	if len(incidents) != 2 {
+		t.Fatalf("expected 2 incidents, got %d", len(incidents))
+	}
+	byReason := map[string]Incident{}
+	for _, inc := range incidents {
+		byReason[inc.ExitReason] = inc
+	}
+	exit := byReason["PROCESS_CRASHED"].Exit
+	if exit == nil {
+		t.Fatal("expected the crash incident to carry its run_exit")
+	}
+	want := RunExit{
+		Class:           "crash",
+		Summary:         "killed by SIGSEGV (core dumped)",
+		ExitCode:        -1,
+		Signal:          "SIGSEGV",
+		CoreDumped:      true,
+		CPUSeconds:      1.5,
+		MaxRSSMB:        64,
+		DurationSeconds: 3,
+		PID:             4242,
+	}
+	if *exit != want {
+		t.Fatalf("exit = %+v, want %+v", *exit, want)
+	}
+	if byReason["USER_TERMINATED"].Exit != nil {
+		t.Fatalf("unlinked incident should have no exit, got %+v", byReason["USER_TERMINATED"].Exit)
+	}
+	if stopped.Class != ExitClassOperatorStop {
+		t.Fatalf("expected stopped class to be refined, got %q", stopped.Class)
+	}
+}
@@ -0,0 +1,143 @@
+package detect
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+)
+
+const (
+	defaultCycleMaxPeriod = 8
+	defaultCycleMinScore  = 0.8
+)
+
+// cycleDetector finds output that repeats as a block of several lines, such
+// as an agent cycling plan -> tool call -> error -> plan. Lines are compared
+// after normalization with the same 90% similarity as the repetition
+// detector.
+type cycleDetector struct {
+	maxPeriod int
+	minScore  float64
+}
+
+func newCycleDetector(settings map[string]interface{}) (Detector, error) {
+	d := cycleDetector{maxPeriod: defaultCycleMaxPeriod, minScore: defaultCycleMinScore}
+	if v, ok := settings["max-period"]; ok {
+		n, ok := toInt(v)
+		if !ok || n < 2 {
+			return nil, fmt.Errorf("max-period must be an integer >= 2")
+		}
+		d.maxPeriod = n
+	}
+	if v, ok := settings["min-score"]; ok {
+		f, ok := toFloat(v)
+		if !ok || f <= 0 || f > 1 {
+			return nil, fmt.Errorf("min-score must be in (0, 1]")
+		}
+		d.minScore = f
+	}
+	return d, nil
+}
+
+func (cycleDetector) Name() string { return "cycle" }
+
+func (d cycleDetector) Detect(in Input, out *Result) {
+	period, score, block := FindCycle(in.Lines, d.maxPeriod, d.minScore)
+	if period == 0 {
+		return
+	}
+	out.Telemetry.LoopPeriod = period
+	if score > out.Telemetry.LogRepetition {
+		out.Telemetry.LogRepetition = score
+	}
+	out.Pattern = strings.Join(block, " → ")
+}
+
+// FindCycle returns the smallest period k in [2, maxPeriod] such that at
+// least minScore of the lines match the line k before them, with the
+// normalized k-line block that repeats. The window must hold the block at
+// least twice. A window that already repeats with period 1 is not a cycle.
+// The block is rotated to a canonical start, so the same loop yields the
+// same pattern wherever the window cuts it.
+func FindCycle(lines []string, maxPeriod int, minScore float64) (period int, score float64, block []string) {
+	n := len(lines)
+	if maxPeriod > n/2 {
+		maxPeriod = n / 2
+	}
+	if maxPeriod < 2 {
+		return 0, 0, nil
+	}
+	normalized := make([]string, n)
+	for i, line := range lines {
+		normalized[i] = NormalizeLog(line)
+	}
+
+	lev := metrics.NewLevenshtein()
+	periodScore := func(k int) float64 {
+		matches := 0
+		for i := k; i < n; i++ {
+			a, b := normalized[i], normalized[i-k]
+			if a == b || strutil.Similarity(a, b, lev) >= 0.9 {
+				matches++
+			}
+		}
+		return float64(matches) / float64(n-k)
+	}
+
+	if periodScore(1) >= minScore {
+		return 0, 0, nil
+	}
+	for k := 2; k <= maxPeriod; k++ {
+		if s := periodScore(k); s >= minScore {
+			return k, s, canonicalRotation(normalized[n-k:])
+		}
+	}
+	return 0, 0, nil
+}
+
+// canonicalRotation returns the lexicographically smallest rotation of block.
+func canonicalRotation(block []string) []string {
+	best := 0
+	for start := 1; start < len(block); start++ {
+		for i := 0; i < len(block); i++ {
+			a, b := block[(start+i)%len(block)], block[(best+i)%len(block)]
+			if a != b {
+				if a < b {
+					best = start
+				}
+				break
+			}
+		}
+	}
+	out := make([]string, 0, len(block))
+	out = append(out, block[best:]...)
+	return append(out, block[:best]...)
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		if n == float64(int(n)) {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
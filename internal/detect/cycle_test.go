@@ -0,0 +1,91 @@
+package detect
+
+import (
+	"reflect"
+	"testing"
+)
+
+func agentCycle(n int) []string {
+	steps := []string{
+		"plan: search docs for retry policy (attempt 7)",
+		"tool_call search query=\"retry policy\" id=4411",
+		"error: tool search failed with status 503",
+	}
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = steps[i%len(steps)]
+	}
+	return lines
+}
+
+func TestFindCycleReportsPeriodAndBlock(t *testing.T) {
+	lines := agentCycle(10)
+	period, score, block := FindCycle(lines, 8, 0.8)
+	if period != 3 || score != 1 {
+		t.Fatalf("FindCycle() period=%d score=%.2f, want 3 and 1.00", period, score)
+	}
+	want := []string{
+		"error: tool search failed with status <NUM>",
+		"plan: search docs for retry policy (attempt <NUM>)",
+		"tool_call search query=\"retry policy\" id=<NUM>",
+	}
+	if !reflect.DeepEqual(block, want) {
+		t.Fatalf("block = %q, want %q", block, want)
+	}
+	// Wherever the window cuts the loop, the pattern is the same.
+	if _, _, shifted := FindCycle(agentCycle(11)[1:], 8, 0.8); !reflect.DeepEqual(shifted, want) {
+		t.Fatalf("shifted block = %q, want %q", shifted, want)
+	}
+
+	// The first-line check sees this window as diverse.
+	if _, repetition := RepetitionScore(lines); repetition > 0.5 {
+		t.Fatalf("expected low first-line repetition for a cycle, got %.2f", repetition)
+	}
+}
+
+func TestFindCycleIgnoresNonCycles(t *testing.T) {
+	same := []string{"retry 1", "retry 2", "retry 3", "retry 4", "retry 5", "retry 6"}
+	if period, _, _ := FindCycle(same, 8, 0.8); period != 0 {
+		t.Fatalf("plain repetition should be left to the repetition detector, got period %d", period)
+	}
+	diverse := []string{"loading model", "parsing input", "tokenizing", "running batch", "writing output", "done"}
+	if period, _, _ := FindCycle(diverse, 8, 0.8); period != 0 {
+		t.Fatalf("diverse output should not be a cycle, got period %d", period)
+	}
+	// A period-6 block cannot repeat twice in a 10-line window.
+	if period, _, _ := FindCycle(agentCycle(10)[:5], 8, 0.8); period != 0 {
+		t.Fatalf("a block seen less than twice should not be a cycle, got period %d", period)
+	}
+}
+
+func TestDefaultEngineFlagsCycles(t *testing.T) {
+	e := NewEngine(10)
+	for _, line := range agentCycle(12) {
+		e.ObserveLine(Line{Text: line})
+	}
+	r := e.Evaluate(Sample{CPUPercent: 90, CPUThreshold: 60})
+	if r.Telemetry.LoopPeriod != 3 || r.Telemetry.LogRepetition < 0.8 {
+		t.Fatalf("expected a period-3 loop signal, got %+v", r.Telemetry)
+	}
+	if r.Pattern != "error: tool search failed with status <NUM> → plan: search docs for retry policy (attempt <NUM>) → tool_call search query=\"retry policy\" id=<NUM>" {
+		t.Fatalf("unexpected pattern %q", r.Pattern)
+	}
+}
+
+func TestCycleDetectorSettings(t *testing.T) {
+	if _, err := newCycleDetector(map[string]interface{}{"max-period": 1}); err == nil {
+		t.Fatal("expected max-period below 2 to be rejected")
+	}
+	if _, err := newCycleDetector(map[string]interface{}{"min-score": 1.5}); err == nil {
+		t.Fatal("expected min-score above 1 to be rejected")
+	}
+	d, err := newCycleDetector(map[string]interface{}{"max-period": 2, "min-score": 0.9})
+	if err != nil {
+		t.Fatalf("newCycleDetector: %v", err)
+	}
+	var r Result
+	d.Detect(Input{Lines: agentCycle(10)}, &r)
+	if r.Telemetry.LoopPeriod != 0 {
+		t.Fatalf("expected max-period 2 to miss a period-3 cycle, got %d", r.Telemetry.LoopPeriod)
+	}
+}
@@ -0,0 +1,182 @@
+package detect
+
+import (
+	"flowforge/internal/policy"
+	"sync"
+	"time"
+)
+
+// Line is one complete line of process output.
+type Line struct {
+	Text   string
+	Stream string // "stdout" or "stderr"; empty when the source does not say
+	Time   time.Time
+	Fields map[string]interface{} // The decoded JSON object in structured-log mode; nil otherwise
+}
+
+// Sample is one telemetry reading of the supervised process tree.
+type Sample struct {
+	Time               time.Time
+	CPUPercent         float64
+	CPUThreshold       float64       // max-cpu; CPU scores are relative to it
+	CPUOverFor         time.Duration // How long CPU has been above CPUThreshold
+	MemoryMB           float64
+	Runtime            time.Duration
+	SilentFor          time.Duration
+	StuckFor           time.Duration         // How long the tree has shown no progress at all
+	StuckState         string                // Where the stuck tree waits in the kernel
+	WriteBytesPerSec   float64               // Storage write rate over the last few seconds
+	WriteBytes         uint64                // Bytes written to storage this attempt
+	LinesPerSec        float64               // Output rate over the last few seconds
+	ProgressAdvancedAt time.Time             // When a parsed progress bar or counter last moved forward; zero if never
+	Rules              []policy.RuleMatch    // User-defined rules that fired since the previous sample
+	KnownPatterns      []policy.PatternMatch // Registry patterns with an alert or kill action matched since the previous sample
+	Tokens             int64                 // Tokens counted in the run's output so far
+	CostUSD            float64               // Estimated cost of Tokens
+}
+
+// Input is what a detector sees on each evaluation.
+type Input struct {
+	Lines   []string // The log window, oldest first
+	Entries []Line   // The same window with stream, time and parsed fields
+	Sample  Sample
+}
+
+// Result is the combined output of all detectors for one sample.
+type Result struct {
+	Telemetry  policy.Telemetry
+	WindowFull bool // Detectors only run once the log window has filled
+
+	// Scores in 0..100, recorded on decision traces.
+	CPUScore     float64
+	EntropyScore float64
+	Confidence   float64
+
+	// Pattern is the normalized output that best describes a loop; it is
+	// stored as the incident pattern.
+	Pattern string
+	// Trace is the repeating stack trace when the traceback detector fired.
+	Trace *TraceMatch
+}
+
+// Detector scores one aspect of the log window and records its findings in
+// the result handed to the policy decider.
+type Detector interface {
+	Name() string
+	Detect(in Input, out *Result)
+}
+
+// LineObserver is implemented by detectors that need every line as it
+// arrives rather than only the window.
+type LineObserver interface {
+	ObserveLine(l Line)
+}
+
+// Engine buffers the last window of output lines and runs its detectors
+// over them for each telemetry sample. It is safe for concurrent use.
+type Engine struct {
+	mu        sync.Mutex
+	window    int
+	lines     []Line
+	next      int
+	full      bool
+	detectors []Detector
+}
+
+// NewEngine returns an engine over a window of the given number of lines.
+// With no detectors it runs Default().
+func NewEngine(window int, detectors ...Detector) *Engine {
+	if window <= 0 {
+		window = 10
+	}
+	if len(detectors) == 0 {
+		detectors = Default()
+	}
+	return &Engine{
+		window:    window,
+		lines:     make([]Line, window),
+		detectors: detectors,
+	}
+}
+
+// Detectors returns the detectors the engine runs, in order.
+func (e *Engine) Detectors() []Detector {
+	return append([]Detector(nil), e.detectors...)
+}
+
+// ObserveLine adds a complete output line to the window.
+func (e *Engine) ObserveLine(l Line) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lines[e.next] = l
+	e.next++
+	if e.next == e.window {
+		e.next = 0
+		e.full = true
+	}
+	for _, d := range e.detectors {
+		if o, ok := d.(LineObserver); ok {
+			o.ObserveLine(l)
+		}
+	}
+}
+
+// Lines returns the current window, oldest first.
+func (e *Engine) Lines() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return texts(e.entriesLocked())
+}
+
+func (e *Engine) entriesLocked() []Line {
+	if !e.full {
+		return append([]Line(nil), e.lines[:e.next]...)
+	}
+	out := make([]Line, 0, e.window)
+	out = append(out, e.lines[e.next:]...)
+	return append(out, e.lines[:e.next]...)
+}
+
+func texts(entries []Line) []string {
+	out := make([]string, len(entries))
+	for i, l := range entries {
+		out[i] = l.Text
+	}
+	return out
+}
+
+// Evaluate turns a telemetry sample into policy telemetry. Output scores
+// stay zero until the window has filled.
+func (e *Engine) Evaluate(s Sample) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r := Result{
+		Telemetry: policy.Telemetry{
+			CPUPercent:       s.CPUPercent,
+			CPUOverFor:       s.CPUOverFor,
+			MemoryMB:         s.MemoryMB,
+			Runtime:          s.Runtime,
+			SilentFor:        s.SilentFor,
+			StuckFor:         s.StuckFor,
+			StuckState:       s.StuckState,
+			WriteBytesPerSec: s.WriteBytesPerSec,
+			WriteBytes:       s.WriteBytes,
+			LinesPerSec:      s.LinesPerSec,
+			Rules:            s.Rules,
+			KnownPatterns:    s.KnownPatterns,
+			Tokens:           s.Tokens,
+			CostUSD:          s.CostUSD,
+		},
+		WindowFull: e.full,
+	}
+	if !e.full {
+		return r
+	}
+	entries := e.entriesLocked()
+	in := Input{Lines: texts(entries), Entries: entries, Sample: s}
+	for _, d := range e.detectors {
+		d.Detect(in, &r)
+	}
+	return r
+}
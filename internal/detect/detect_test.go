@@ -0,0 +1,94 @@
+package detect
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEngineWaitsForFullWindow(t *testing.T) {
+	e := NewEngine(4)
+	for i := 0; i < 3; i++ {
+		e.ObserveLine(Line{Text: "retrying request 42"})
+	}
+	r := e.Evaluate(Sample{CPUPercent: 95, CPUThreshold: 60, MemoryMB: 12})
+	if r.WindowFull || r.Telemetry.LogRepetition != 0 || r.Pattern != "" {
+		t.Fatalf("expected no output scores before the window fills, got %+v", r)
+	}
+	if r.Telemetry.CPUPercent != 95 || r.Telemetry.MemoryMB != 12 {
+		t.Fatalf("expected sample fields to pass through, got %+v", r.Telemetry)
+	}
+
+	e.ObserveLine(Line{Text: "retrying request 43"})
+	r = e.Evaluate(Sample{CPUPercent: 95, CPUThreshold: 60})
+	if !r.WindowFull {
+		t.Fatal("expected a full window")
+	}
+	if r.Telemetry.LogRepetition != 1 || r.Pattern != "retrying request <NUM>" {
+		t.Fatalf("expected full repetition of the normalized line, got %+v", r)
+	}
+	if r.Telemetry.RawDiversity != 0.5 || r.Telemetry.LogEntropy != 0.25 || r.CPUScore != 100 {
+		t.Fatalf("unexpected scores: %+v", r)
+	}
+}
+
+func TestEngineWindowKeepsLatestLinesInOrder(t *testing.T) {
+	e := NewEngine(3)
+	for i := 1; i <= 5; i++ {
+		e.ObserveLine(Line{Text: fmt.Sprintf("line %d", i)})
+	}
+	if got, want := e.Lines(), []string{"line 3", "line 4", "line 5"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Lines() = %v, want %v", got, want)
+	}
+}
+
+type countingDetector struct {
+	seen int
+}
+
+func (*countingDetector) Name() string { return "counting" }
+
+func (d *countingDetector) ObserveLine(Line) { d.seen++ }
+
+func (d *countingDetector) Detect(in Input, out *Result) {
+	out.Telemetry.ProgressLike = d.seen > len(in.Lines)
+}
+
+func TestRegisteredDetectorsAreBuiltFromConfig(t *testing.T) {
+	var settings map[string]interface{}
+	Register("counting", func(s map[string]interface{}) (Detector, error) {
+		settings = s
+		return &countingDetector{}, nil
+	})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, "counting")
+		registryMu.Unlock()
+	})
+
+	ds, err := Build([]string{"repetition", "counting"}, map[string]map[string]interface{}{"counting": {"min": 3}})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(ds) != 2 || ds[0].Name() != "repetition" || ds[1].Name() != "counting" {
+		t.Fatalf("unexpected detectors: %v", ds)
+	}
+	if settings["min"] != 3 {
+		t.Fatalf("expected settings to reach the factory, got %v", settings)
+	}
+
+	e := NewEngine(2, ds...)
+	for i := 0; i < 3; i++ {
+		e.ObserveLine(Line{Text: "x"})
+	}
+	if !e.Evaluate(Sample{}).Telemetry.ProgressLike {
+		t.Fatal("expected the line observer to see every line, not just the window")
+	}
+
+	if _, err := Build([]string{"telepathy"}, nil); err == nil {
+		t.Fatal("expected an error for an unknown detector")
+	}
+	if _, err := Build([]string{"entropy", "entropy"}, nil); err == nil {
+		t.Fatal("expected an error for a duplicate detector")
+	}
+}
@@ -0,0 +1,202 @@
+package detect
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+)
+
+var firstNumberRegex = regexp.MustCompile(`\d+`)
+
+// Default returns the built-in detectors: repetition against the first
+// line, normalized entropy and confidence scores, raw line diversity, the
+// progress guard, multi-line cycles and repeated stack traces.
+func Default() []Detector {
+	return []Detector{
+		repetitionDetector{},
+		scoreDetector{},
+		diversityDetector{},
+		progressDetector{},
+		cycleDetector{maxPeriod: defaultCycleMaxPeriod, minScore: defaultCycleMinScore},
+		&tracebackDetector{minRepeats: defaultTraceMinRepeats, within: defaultTraceWithin, frames: defaultTraceFrames},
+	}
+}
+
+// repetitionDetector measures how many lines are near-copies of the first
+// one after normalization.
+type repetitionDetector struct{}
+
+func (repetitionDetector) Name() string { return "repetition" }
+
+func (repetitionDetector) Detect(in Input, out *Result) {
+	pattern, score := RepetitionScore(in.Lines)
+	out.Telemetry.LogRepetition = score
+	if out.Pattern == "" {
+		out.Pattern = pattern
+	}
+}
+
+// scoreDetector computes the entropy of normalized lines and the CPU and
+// confidence scores shown on decision traces.
+type scoreDetector struct{}
+
+func (scoreDetector) Name() string { return "entropy" }
+
+func (scoreDetector) Detect(in Input, out *Result) {
+	out.CPUScore, out.EntropyScore, out.Confidence = DecisionScores(in.Sample.CPUPercent, in.Sample.CPUThreshold, in.Lines)
+	out.Telemetry.LogEntropy = out.EntropyScore / 100.0
+}
+
+type diversityDetector struct{}
+
+func (diversityDetector) Name() string { return "diversity" }
+
+func (diversityDetector) Detect(in Input, out *Result) {
+	out.Telemetry.RawDiversity = RawDiversity(in.Lines)
+}
+
+// progressDetector marks the window as forward progress when a parsed
+// progress bar or counter moved recently, or when the lines read like
+// progress.
+type progressDetector struct{}
+
+func (progressDetector) Name() string { return "progress" }
+
+func (progressDetector) Detect(in Input, out *Result) {
+	at, now := in.Sample.ProgressAdvancedAt, in.Sample.Time
+	advancing := !at.IsZero() && !now.IsZero() && now.Sub(at) <= progressFresh
+	out.Telemetry.ProgressLike = out.Telemetry.ProgressLike || advancing || ProgressLike(in.Lines)
+}
+
+// DecisionScores returns the CPU, normalized-entropy and confidence scores
+// (0..100) for a window.
+func DecisionScores(cpuUsage, threshold float64, lines []string) (cpuScore, entropyScore, confidence float64) {
+	if threshold <= 0 {
+		threshold = 100
+	}
+	cpuScore = (cpuUsage / threshold) * 100.0
+	if cpuScore > 100 {
+		cpuScore = 100
+	}
+	if cpuScore < 0 {
+		cpuScore = 0
+	}
+
+	if len(lines) == 0 {
+		entropyScore = 100
+	} else {
+		uniq := make(map[string]struct{}, len(lines))
+		for _, line := range lines {
+			uniq[NormalizeLog(line)] = struct{}{}
+		}
+		entropyScore = (float64(len(uniq)) / float64(len(lines))) * 100.0
+	}
+
+	// Confidence increases with CPU pressure and repetitive output (low entropy).
+	confidence = 0.65*cpuScore + 0.35*(100.0-entropyScore)
+	if confidence > 100 {
+		confidence = 100
+	}
+	if confidence < 0 {
+		confidence = 0
+	}
+
+	return cpuScore, entropyScore, confidence
+}
+
+// RepetitionScore returns the normalized first line and the fraction of
+// the remaining lines that are at least 90% similar to it.
+func RepetitionScore(lines []string) (firstNormalized string, repetitionScore float64) {
+	if len(lines) == 0 {
+		return "", 0
+	}
+	if len(lines) == 1 {
+		return NormalizeLog(lines[0]), 0
+	}
+
+	firstNormalized = NormalizeLog(lines[0])
+	stagnantMatches := 0
+	lev := metrics.NewLevenshtein()
+	for _, line := range lines[1:] {
+		currentNormalized := NormalizeLog(line)
+		if strutil.Similarity(firstNormalized, currentNormalized, lev) >= 0.9 {
+			stagnantMatches++
+		}
+	}
+
+	repetitionScore = float64(stagnantMatches) / float64(len(lines)-1)
+	return firstNormalized, repetitionScore
+}
+
+// RawDiversity is the fraction of distinct lines before normalization.
+func RawDiversity(lines []string) float64 {
+	if len(lines) == 0 {
+		return 1.0
+	}
+	uniq := make(map[string]struct{}, len(lines))
+	for _, line := range lines {
+		uniq[line] = struct{}{}
+	}
+	return float64(len(uniq)) / float64(len(lines))
+}
+
+// ProgressLike reports whether the window reads like forward progress:
+// progress keywords and mostly increasing leading numbers.
+func ProgressLike(lines []string) bool {
+	if len(lines) < 4 {
+		return false
+	}
+
+	progressHints := 0
+	numericLines := 0
+	increaseCount := 0
+	comparisons := 0
+
+	var prevNumber int
+	hasPrevNumber := false
+
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "progress") ||
+			strings.Contains(lower, "step=") ||
+			strings.Contains(lower, "tick=") ||
+			strings.Contains(lower, "phase=") ||
+			strings.Contains(lower, "heartbeat") {
+			progressHints++
+		}
+
+		match := firstNumberRegex.FindString(lower)
+		if match == "" {
+			continue
+		}
+
+		numericLines++
+		value, err := strconv.Atoi(match)
+		if err != nil {
+			continue
+		}
+
+		if hasPrevNumber {
+			comparisons++
+			if value > prevNumber {
+				increaseCount++
+			}
+		}
+
+		prevNumber = value
+		hasPrevNumber = true
+	}
+
+	if comparisons == 0 {
+		return false
+	}
+
+	progressHintRatio := float64(progressHints) / float64(len(lines))
+	numericCoverage := float64(numericLines) / float64(len(lines))
+	increaseRatio := float64(increaseCount) / float64(comparisons)
+
+	return progressHintRatio >= 0.40 && numericCoverage >= 0.70 && increaseRatio >= 0.70
+}
@@ -1,8 +1,8 @@
-package cmd
+package detect
 
 import "testing"
 
-func TestRawDiversityScore(t *testing.T) {
+func TestRawDiversity(t *testing.T) {
 	tests := []struct {
 		name  string
 		lines []string
@@ -15,15 +15,15 @@ func TestRawDiversityScore(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := rawDiversityScore(tt.lines)
+			got := RawDiversity(tt.lines)
 			if got != tt.want {
-				t.Fatalf("rawDiversityScore() = %v, want %v", got, tt.want)
+				t.Fatalf("RawDiversity() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestDetectProgressLikeOutput(t *testing.T) {
+func TestProgressLike(t *testing.T) {
 	progressLines := []string{
 		"progress step=1 phase=compute metric=0.901",
 		"progress step=2 phase=compute metric=0.903",
@@ -31,7 +31,7 @@ func TestDetectProgressLikeOutput(t *testing.T) {
 		"progress step=4 phase=compute metric=0.922",
 		"progress step=5 phase=compute metric=0.930",
 	}
-	if !detectProgressLikeOutput(progressLines) {
+	if !ProgressLike(progressLines) {
 		t.Fatal("expected progress-like output to be detected")
 	}
 
@@ -42,7 +42,7 @@ func TestDetectProgressLikeOutput(t *testing.T) {
 		"processing request 4242 failed, retrying endlessly",
 		"processing request 4242 failed, retrying endlessly",
 	}
-	if detectProgressLikeOutput(runawayLines) {
+	if ProgressLike(runawayLines) {
 		t.Fatal("expected runaway repetitive output not to be classified as progress")
 	}
 }
@@ -0,0 +1,152 @@
+package detect
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// NormalizeRule is a user-supplied normalization step, from the
+// `normalize-rules` config key. Replace may refer to capture groups as $1;
+// an empty Replace removes the match.
+type NormalizeRule struct {
+	Name    string
+	Pattern string
+	Replace string
+}
+
+// Normalizer rewrites the variable parts of a log line (ids, addresses,
+// paths, times and numbers) to placeholders, so lines that differ only in
+// them compare equal. User rules run first, then the built-in token
+// classes. A Normalizer is compiled once and is safe for concurrent use.
+type Normalizer struct {
+	steps []normalizeStep
+}
+
+type normalizeStep struct {
+	re      *regexp.Regexp
+	replace string
+	// anyOf lists bytes one of which every match contains; lines without
+	// any of them skip the regex. Empty means always run it.
+	anyOf string
+	// keep, when set, leaves matches it returns true for unchanged.
+	keep func(match string) bool
+}
+
+// builtinSteps are the token classes every Normalizer knows. Order matters:
+// URLs and emails go before the paths and numbers inside them, and times
+// before the colon groups of IPv6.
+var builtinSteps = []normalizeStep{
+	{re: regexp.MustCompile(`\b[A-Za-z][A-Za-z0-9+.-]*://[^\s"'<>]+`), replace: "<URL>", anyOf: ":"},
+	{re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`), replace: "<EMAIL>", anyOf: "@"},
+	{re: regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), replace: "<UUID>", anyOf: "-"},
+	{re: regexp.MustCompile(`0x[0-9a-fA-F]+`), replace: "<HEX>", anyOf: "x"},
+	{re: regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T\s]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`), replace: "<TIME>", anyOf: ":"},
+	{re: regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}\b`), replace: "<TIME>", anyOf: ":"},
+	{re: regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`), replace: "<IP>", anyOf: "."},
+	{re: regexp.MustCompile(`(?i)\b(?:[0-9a-f]{1,4}:){7}[0-9a-f]{1,4}\b`), replace: "<IP>", anyOf: ":", keep: noDigit},
+	// Compressed IPv6 needs a digit so that std::vector stays as it is.
+	{re: regexp.MustCompile(`(?i)(?:\b[0-9a-f]{1,4}(?::[0-9a-f]{1,4}){0,6})?::(?:[0-9a-f]{1,4}(?::[0-9a-f]{1,4}){0,6}\b)?`), replace: "<IP>", anyOf: ":", keep: noDigit},
+	// Absolute, home and relative paths; the leading delimiter is kept.
+	{re: regexp.MustCompile(`(^|[\s"'=(\[,])(?:~|\.{1,2})?/[^\s"'()\[\],:;]+`), replace: "${1}<PATH>", anyOf: "/"},
+	{re: regexp.MustCompile(`\b[A-Za-z]:\\[^\s"'<>|]+`), replace: "<PATH>", anyOf: `\`},
+	// Digests and ids: long hex runs mixing digits and letters, so plain
+	// numbers and words like "deadbeef" are left to the classes below.
+	{re: regexp.MustCompile(`\b[0-9a-fA-F]{8,}\b`), replace: "<HASH>", anyOf: "0123456789", keep: func(s string) bool {
+		return noDigit(s) || !strings.ContainsAny(s, "abcdefABCDEF")
+	}},
+	{re: regexp.MustCompile(`\b\d+(\.\d+)?\b`), replace: "<NUM>", anyOf: "0123456789"},
+}
+
+func noDigit(s string) bool { return !strings.ContainsAny(s, "0123456789") }
+
+// NewNormalizer compiles rules ahead of the built-in token classes.
+func NewNormalizer(rules []NormalizeRule) (*Normalizer, error) {
+	n := &Normalizer{steps: make([]normalizeStep, 0, len(rules)+len(builtinSteps))}
+	for i, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if strings.TrimSpace(r.Pattern) == "" {
+			return nil, fmt.Errorf("normalize rule %s: pattern is required", name)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("normalize rule %s: %w", name, err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf("normalize rule %s: pattern matches the empty string", name)
+		}
+		n.steps = append(n.steps, normalizeStep{re: re, replace: r.Replace})
+	}
+	n.steps = append(n.steps, builtinSteps...)
+	return n, nil
+}
+
+// Normalize returns line with every variable part replaced.
+func (n *Normalizer) Normalize(line string) string {
+	for _, s := range n.steps {
+		if s.anyOf != "" && !strings.ContainsAny(line, s.anyOf) {
+			continue
+		}
+		if s.keep == nil {
+			line = s.re.ReplaceAllString(line, s.replace)
+			continue
+		}
+		keep, replace := s.keep, s.replace
+		line = s.re.ReplaceAllStringFunc(line, func(m string) string {
+			if keep(m) {
+				return m
+			}
+			return replace
+		})
+	}
+	return line
+}
+
+var builtinNormalizer = &Normalizer{steps: builtinSteps}
+
+// CommandNormalizer is the fixed normalizer for command lines. It has the
+// built-in classes except paths, URLs and emails, which tell one command
+// from another, and never takes `normalize-rules`, so fingerprints keyed by
+// it stay stable across configs.
+var CommandNormalizer = &Normalizer{steps: builtinStepsExcept("<PATH>", "<URL>", "<EMAIL>")}
+
+// builtinStepsExcept returns the built-in steps whose placeholder is none
+// of tokens, in their usual order.
+func builtinStepsExcept(tokens ...string) []normalizeStep {
+	var steps []normalizeStep
+next:
+	for _, step := range builtinSteps {
+		for _, tok := range tokens {
+			if strings.Contains(step.replace, tok) {
+				continue next
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+var activeNormalizer atomic.Pointer[Normalizer]
+
+// SetNormalizer makes n the normalizer NormalizeLog uses, process-wide;
+// nil restores the built-in classes alone. Commands install the configured
+// one at startup so detection, rules, the pattern registry and incident
+// patterns all see lines the same way.
+func SetNormalizer(n *Normalizer) {
+	if n == nil {
+		n = builtinNormalizer
+	}
+	activeNormalizer.Store(n)
+}
+
+// NormalizeLog normalizes line with the normalizer set by SetNormalizer.
+func NormalizeLog(line string) string {
+	if n := activeNormalizer.Load(); n != nil {
+		return n.Normalize(line)
+	}
+	return builtinNormalizer.Normalize(line)
+}
@@ -0,0 +1,88 @@
+package detect
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizerTokenClasses(t *testing.T) {
+	n, err := NewNormalizer(nil)
+	if err != nil {
+		t.Fatalf("NewNormalizer: %v", err)
+	}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Processing item 301 at 0x10297a350 with value 0.41184", "Processing item <NUM> at <HEX> with value <NUM>"},
+		{"2026-02-17T12:00:00Z retry 3 at 12:00:05", "<TIME> retry <NUM> at <TIME>"},
+		{"request 3f2b8c1e-9a4d-4e2f-8b1a-0c9d8e7f6a5b failed", "request <UUID> failed"},
+		{"connect to 10.0.0.12:5432 refused", "connect to <IP>:<NUM> refused"},
+		{"dial [fe80::1ff:fe23:4567:890a]:443 and ::1", "dial [<IP>]:<NUM> and <IP>"},
+		{"std::vector<int>::size is fine", "std::vector<int>::size is fine"},
+		{"open /tmp/tmpa8f3k2/input.json: no such file", "open <PATH>: no such file"},
+		{`wrote path="./out/run-17/log.txt" and C:\Users\ci\cache.bin`, `wrote path="<PATH>" and <PATH>`},
+		{"GET https://api.example.com/v1/items?page=4 took 12 ms", "GET <URL> took <NUM> ms"},
+		{"notify ops+alerts@example.org failed", "notify <EMAIL> failed"},
+		{"cache miss for sha 9f86d081884c7d65 (deadbeef, 12345678)", "cache miss for sha <HASH> (deadbeef, <NUM>)"},
+		{"50/100 steps, ratio 1/2", "<NUM>/<NUM> steps, ratio <NUM>/<NUM>"},
+	}
+	for _, tt := range tests {
+		if got := n.Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q)\n  got:  %q\n  want: %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizerUserRulesRunFirst(t *testing.T) {
+	n, err := NewNormalizer([]NormalizeRule{
+		{Name: "request-id", Pattern: `req_[A-Za-z0-9]+`, Replace: "<REQ>"},
+		{Pattern: `attempt=(\w+)`, Replace: "attempt=$1?"},
+	})
+	if err != nil {
+		t.Fatalf("NewNormalizer: %v", err)
+	}
+	if got := n.Normalize("req_8xK2p91 failed attempt=3"); got != "<REQ> failed attempt=<NUM>?" {
+		t.Fatalf("unexpected normalization %q", got)
+	}
+
+	for _, bad := range []NormalizeRule{
+		{Name: "empty"},
+		{Name: "syntax", Pattern: `req_(`},
+		{Name: "anything", Pattern: `x*`},
+	} {
+		if _, err := NewNormalizer([]NormalizeRule{bad}); err == nil || !strings.Contains(err.Error(), bad.Name) {
+			t.Fatalf("expected rule %q to be rejected by name, got %v", bad.Name, err)
+		}
+	}
+}
+
+func TestSetNormalizerAppliesToNormalizeLog(t *testing.T) {
+	t.Cleanup(func() { SetNormalizer(nil) })
+	n, err := NewNormalizer([]NormalizeRule{{Pattern: `tenant-\w+`, Replace: "<TENANT>"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetNormalizer(n)
+	if got := NormalizeLog("sync tenant-acme page 2"); got != "sync <TENANT> page <NUM>" {
+		t.Fatalf("expected the installed rules to apply, got %q", got)
+	}
+	SetNormalizer(nil)
+	if got := NormalizeLog("sync tenant-acme page 2"); got != "sync tenant-acme page <NUM>" {
+		t.Fatalf("expected the built-in classes alone, got %q", got)
+	}
+}
+
+func TestCommandNormalizerKeepsPathsAndIgnoresUserRules(t *testing.T) {
+	t.Cleanup(func() { SetNormalizer(nil) })
+	n, err := NewNormalizer([]NormalizeRule{{Pattern: `shard`, Replace: "<PART>"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetNormalizer(n)
+	in := "python3 /srv/jobs/train.py --shard 3 --run 3f2b8c1e-9a4d-4e2f-8b1a-0c9d8e7f6a5b --at 2026-02-17T12:00:00Z --url https://example.com/a"
+	want := "python3 /srv/jobs/train.py --shard <NUM> --run <UUID> --at <TIME> --url https://example.com/a"
+	if got := CommandNormalizer.Normalize(in); got != want {
+		t.Fatalf("Normalize(%q)\n  got:  %q\n  want: %q", in, got, want)
+	}
+}
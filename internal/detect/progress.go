@@ -0,0 +1,104 @@
+package detect
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// progressFresh is how long a parsed progress reading counts as forward
+// progress after it last moved: a bar stuck at 40% stops vouching for the
+// run after this long.
+const progressFresh = time.Minute
+
+// Progress is one reading of a progress bar or step counter, parsed from an
+// in-place (\r) revision of an output line.
+type Progress struct {
+	Fraction float64       // Completed share, 0..1
+	Done     float64       // Counter, when the line has one: steps, items or MB
+	Total    float64       // Counter total; 0 without a counter
+	ETA      time.Duration // Time left, when the line gives it
+}
+
+var (
+	progressPercent = regexp.MustCompile(`(\d{1,3}(?:\.\d+)?)\s?%`)
+	progressCounter = regexp.MustCompile(`(?:^|[^\w.])(\d+(?:\.\d+)?)\s?/\s?(\d+(?:\.\d+)?)\b`)
+	// tqdm's [elapsed<left], pip's "eta 0:00:05" and Keras' "ETA: 12s".
+	progressETA = regexp.MustCompile(`(?i)(?:\beta:?\s*|<)((?:\d+:)?\d{1,2}:\d{2}\b|\d+(?:\.\d+)?[hms](?:\d+[ms])*\b)`)
+)
+
+// ParseProgress reads a percentage or a done/total counter, and an ETA when
+// one is given, from s. It reports false when s has neither.
+func ParseProgress(s string) (Progress, bool) {
+	if !strings.ContainsAny(s, "%/") {
+		return Progress{}, false
+	}
+	var p Progress
+	found := false
+	// The last counter on a line is the innermost one: "Epoch 3/10 12/100".
+	matches := progressCounter.FindAllStringSubmatch(s, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		done, err1 := strconv.ParseFloat(matches[i][1], 64)
+		total, err2 := strconv.ParseFloat(matches[i][2], 64)
+		if err1 == nil && err2 == nil && total > 0 && done <= total {
+			p.Done, p.Total = done, total
+			p.Fraction = done / total
+			found = true
+			break
+		}
+	}
+	if m := progressPercent.FindStringSubmatch(s); m != nil {
+		if pct, err := strconv.ParseFloat(m[1], 64); err == nil && pct <= 100 {
+			p.Fraction = pct / 100
+			found = true
+		}
+	}
+	if !found {
+		return Progress{}, false
+	}
+	if m := progressETA.FindStringSubmatch(s); m != nil {
+		p.ETA = parseETA(m[1])
+	}
+	return p, true
+}
+
+// parseETA reads [h:]mm:ss or a Go-style duration such as 1m20s.
+func parseETA(s string) time.Duration {
+	if !strings.Contains(s, ":") {
+		d, _ := time.ParseDuration(s)
+		return d
+	}
+	var total time.Duration
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + time.Duration(n)
+	}
+	return total * time.Second
+}
+
+// ProgressTracker follows progress readings and remembers when they last
+// moved forward. It is not safe for concurrent use.
+type ProgressTracker struct {
+	last       Progress
+	seen       bool
+	advancedAt time.Time
+}
+
+// Observe records a reading taken at at. A higher fraction than the
+// previous reading of the same bar, one with the same counter total, is an
+// advance; anything else is a new bar starting.
+func (t *ProgressTracker) Observe(p Progress, at time.Time) {
+	if t.seen && p.Total == t.last.Total && p.Fraction > t.last.Fraction {
+		t.advancedAt = at
+	}
+	t.last, t.seen = p, true
+}
+
+// AdvancedAt returns when progress last moved forward, or the zero time.
+func (t *ProgressTracker) AdvancedAt() time.Time {
+	return t.advancedAt
+}
@@ -0,0 +1,83 @@
+package detect
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		ok   bool
+		want Progress
+	}{
+		{line: " 45%|████▌     | 45/100 [00:12<00:34,  3.21it/s]", ok: true, want: Progress{Fraction: 0.45, Done: 45, Total: 100, ETA: 34 * time.Second}},
+		{line: "   ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━ 12.3/50.0 MB 4.1 MB/s eta 0:00:09", ok: true, want: Progress{Fraction: 0.246, Done: 12.3, Total: 50, ETA: 9 * time.Second}},
+		{line: "Epoch 3/10 12/100 [==>...........................] - ETA: 1m20s - loss: 0.4120", ok: true, want: Progress{Fraction: 0.12, Done: 12, Total: 100, ETA: 80 * time.Second}},
+		{line: "Downloading weights: 87.5% complete", ok: true, want: Progress{Fraction: 0.875}},
+		{line: "step 7/7 done", ok: true, want: Progress{Fraction: 1, Done: 7, Total: 7}},
+		{line: "backup written to 2026/03/14 archive", ok: false},
+		{line: "GET /v1/2 returned 404", ok: false},
+		{line: "retrying request, error rate 250% of budget", ok: false},
+		{line: "agent is thinking about the next tool call", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseProgress(tt.line)
+		if ok != tt.ok {
+			t.Fatalf("ParseProgress(%q) ok = %v, want %v (%+v)", tt.line, ok, tt.ok, got)
+		}
+		if !ok {
+			continue
+		}
+		got.Fraction = math.Round(got.Fraction*1e6) / 1e6
+		if got != tt.want {
+			t.Fatalf("ParseProgress(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestProgressTrackerOnlyCountsAdvances(t *testing.T) {
+	var tr ProgressTracker
+	start := time.Now()
+	tr.Observe(Progress{Fraction: 0.4}, start)
+	if !tr.AdvancedAt().IsZero() {
+		t.Fatal("expected a first reading not to count as an advance")
+	}
+	tr.Observe(Progress{Fraction: 0.4}, start.Add(time.Second))
+	tr.Observe(Progress{Fraction: 0.1}, start.Add(2*time.Second)) // A new bar
+	if !tr.AdvancedAt().IsZero() {
+		t.Fatal("expected a stalled or restarting bar not to count as an advance")
+	}
+	tr.Observe(Progress{Fraction: 0.2}, start.Add(3*time.Second))
+	if got := tr.AdvancedAt(); !got.Equal(start.Add(3 * time.Second)) {
+		t.Fatalf("expected the advance to be stamped, got %v", got)
+	}
+
+	// A counter with another total belongs to a different bar.
+	tr = ProgressTracker{}
+	tr.Observe(Progress{Fraction: 0.25, Done: 1, Total: 4}, start)
+	tr.Observe(Progress{Fraction: 0.5, Done: 5, Total: 10}, start.Add(time.Second))
+	if !tr.AdvancedAt().IsZero() {
+		t.Fatal("expected readings from different bars not to count as an advance")
+	}
+}
+
+func TestProgressDetectorUsesRecentAdvances(t *testing.T) {
+	now := time.Now()
+	lines := []string{"retrying", "retrying", "retrying", "retrying"}
+	for _, tt := range []struct {
+		advancedAt time.Time
+		want       bool
+	}{
+		{time.Time{}, false},
+		{now.Add(-10 * time.Second), true},
+		{now.Add(-progressFresh - time.Second), false},
+	} {
+		var out Result
+		progressDetector{}.Detect(Input{Lines: lines, Sample: Sample{Time: now, ProgressAdvancedAt: tt.advancedAt}}, &out)
+		if out.Telemetry.ProgressLike != tt.want {
+			t.Fatalf("advanced at %v: ProgressLike = %v, want %v", tt.advancedAt, out.Telemetry.ProgressLike, tt.want)
+		}
+	}
+}
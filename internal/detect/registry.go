@@ -0,0 +1,80 @@
+package detect
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory builds a detector from its settings block in config, which may
+// be nil.
+type Factory func(settings map[string]interface{}) (Detector, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+func init() {
+	for _, d := range Default() {
+		d := d
+		Register(d.Name(), func(map[string]interface{}) (Detector, error) { return d, nil })
+	}
+	Register("cycle", newCycleDetector)
+	Register("tool-calls", newToolCallDetector)
+	Register("traceback", newTracebackDetector)
+}
+
+// Register makes a detector available by name to the `detectors` config
+// key. Registering a name twice replaces the earlier factory.
+func Register(name string, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = f
+}
+
+// Registered returns the names of all registered detectors, sorted.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultNames returns the names of the detectors in Default(), in order.
+func DefaultNames() []string {
+	var names []string
+	for _, d := range Default() {
+		names = append(names, d.Name())
+	}
+	return names
+}
+
+// Build instantiates the named detectors in order. settings maps a
+// detector name to its config block.
+func Build(names []string, settings map[string]map[string]interface{}) ([]Detector, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	seen := make(map[string]bool, len(names))
+	out := make([]Detector, 0, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("detector %q listed twice", name)
+		}
+		seen[name] = true
+		f, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown detector %q", name)
+		}
+		d, err := f(settings[name])
+		if err != nil {
+			return nil, fmt.Errorf("detector %q: %w", name, err)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
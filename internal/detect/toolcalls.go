@@ -0,0 +1,222 @@
+package detect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const defaultToolCallMinRepeats = 3
+
+// ParseFields decodes a JSON object log line. It returns nil for anything
+// else, including JSON arrays and scalars. Numbers are kept as json.Number
+// so large IDs in arguments survive re-encoding unchanged.
+func ParseFields(text string) map[string]interface{} {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "{") {
+		return nil
+	}
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil || dec.More() {
+		return nil
+	}
+	return fields
+}
+
+// toolCallDetector counts identical tool calls and identical errors in
+// structured (JSONL) output. An agent that issues the same call with the
+// same arguments, or hits the same error, min-repeats times is looping even
+// when the surrounding lines differ. It only sees lines decoded in
+// structured-log mode.
+type toolCallDetector struct {
+	minRepeats int
+}
+
+func newToolCallDetector(settings map[string]interface{}) (Detector, error) {
+	d := toolCallDetector{minRepeats: defaultToolCallMinRepeats}
+	if v, ok := settings["min-repeats"]; ok {
+		n, ok := toInt(v)
+		if !ok || n < 2 {
+			return nil, fmt.Errorf("min-repeats must be an integer >= 2")
+		}
+		d.minRepeats = n
+	}
+	return d, nil
+}
+
+func (toolCallDetector) Name() string { return "tool-calls" }
+
+func (d toolCallDetector) Detect(in Input, out *Result) {
+	loop, ok := FindToolLoop(in.Entries, d.minRepeats)
+	if !ok {
+		return
+	}
+	out.Telemetry.LoopTool = loop.Tool
+	out.Telemetry.LoopErrors = loop.Error
+	if loop.Score > out.Telemetry.LogRepetition {
+		out.Telemetry.LogRepetition = loop.Score
+	}
+	out.Pattern = loop.Pattern()
+}
+
+// ToolLoop is the most repeated tool call or error in a window.
+type ToolLoop struct {
+	Tool    string  // Tool name; empty for an error that names no tool
+	Args    string  // Canonical JSON arguments of a repeated call
+	Message string  // Normalized message of a repeated error
+	Error   bool    // The loop is a repeated error rather than a repeated call
+	Count   int     // Identical occurrences in the window
+	Score   float64 // Count as a fraction of all calls (or errors) in the window
+}
+
+// Pattern is the incident pattern for the loop.
+func (t ToolLoop) Pattern() string {
+	if t.Error {
+		if t.Tool == "" {
+			return "error: " + t.Message
+		}
+		return fmt.Sprintf("error %s: %s", t.Tool, t.Message)
+	}
+	return fmt.Sprintf("tool_call %s %s", t.Tool, t.Args)
+}
+
+// FindToolLoop returns the tool call or error that repeats most often among
+// the structured entries, if it repeats at least minRepeats times. Calls
+// match on tool name and canonical arguments; arguments are compared
+// exactly, not normalized. Errors match on normalized message and tool.
+func FindToolLoop(entries []Line, minRepeats int) (ToolLoop, bool) {
+	var calls, errs tally
+	for _, l := range entries {
+		if l.Fields == nil {
+			continue
+		}
+		tool := toolName(l.Fields)
+		if msg, ok := errorMessage(l.Fields); ok {
+			errs.add(ToolLoop{Tool: tool, Message: NormalizeLog(msg), Error: true})
+			continue
+		}
+		if tool != "" {
+			calls.add(ToolLoop{Tool: tool, Args: canonicalArgs(l.Fields)})
+		}
+	}
+
+	best, ok := calls.top(minRepeats)
+	if e, eok := errs.top(minRepeats); eok && (!ok || e.Score > best.Score) {
+		best, ok = e, true
+	}
+	return best, ok
+}
+
+// tally counts identical loops in order of first appearance, so ties go to
+// the earliest.
+type tally struct {
+	order []string
+	seen  map[string]*ToolLoop
+	total int
+}
+
+func (t *tally) add(l ToolLoop) {
+	if t.seen == nil {
+		t.seen = make(map[string]*ToolLoop)
+	}
+	key := l.Tool + "\x00" + l.Args + "\x00" + l.Message
+	t.total++
+	if prev, ok := t.seen[key]; ok {
+		prev.Count++
+		return
+	}
+	l.Count = 1
+	t.seen[key] = &l
+	t.order = append(t.order, key)
+}
+
+func (t *tally) top(minRepeats int) (ToolLoop, bool) {
+	var best *ToolLoop
+	for _, key := range t.order {
+		if l := t.seen[key]; best == nil || l.Count > best.Count {
+			best = l
+		}
+	}
+	if best == nil || best.Count < minRepeats {
+		return ToolLoop{}, false
+	}
+	out := *best
+	out.Score = float64(out.Count) / float64(t.total)
+	return out, true
+}
+
+// toolName reads the tool from the common JSONL shapes:
+// {"tool": "search"}, {"tool_name": "search"} and
+// {"type": "tool_use", "name": "search"}.
+func toolName(f map[string]interface{}) string {
+	for _, key := range []string{"tool", "tool_name"} {
+		if s, ok := f[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	for _, key := range []string{"event", "type"} {
+		switch f[key] {
+		case "tool_call", "tool_use", "function_call":
+			if s, ok := f["name"].(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// canonicalArgs re-encodes the call arguments with sorted keys. Arguments
+// given as a JSON string, as in OpenAI function calls, are decoded first.
+func canonicalArgs(f map[string]interface{}) string {
+	for _, key := range []string{"args", "arguments", "input", "parameters"} {
+		v, ok := f[key]
+		if !ok {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			dec := json.NewDecoder(strings.NewReader(s))
+			dec.UseNumber()
+			var decoded interface{}
+			if dec.Decode(&decoded) == nil && !dec.More() {
+				v = decoded
+			}
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return fmt.Sprint(v)
+		}
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+	return "{}"
+}
+
+// errorMessage returns the message of an error record: an "error" field
+// holding a string or an object with a message, or a record logged at
+// error level.
+func errorMessage(f map[string]interface{}) (string, bool) {
+	switch e := f["error"].(type) {
+	case string:
+		if e != "" {
+			return e, true
+		}
+	case map[string]interface{}:
+		if s, ok := e["message"].(string); ok {
+			return s, true
+		}
+		return canonicalArgs(map[string]interface{}{"args": e}), true
+	}
+	switch strings.ToLower(fmt.Sprint(f["level"])) {
+	case "error", "fatal":
+		for _, key := range []string{"message", "msg"} {
+			if s, ok := f[key].(string); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
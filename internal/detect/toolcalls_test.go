@@ -0,0 +1,130 @@
+package detect
+
+import (
+	"fmt"
+	"testing"
+)
+
+func structured(lines ...string) []Line {
+	out := make([]Line, len(lines))
+	for i, text := range lines {
+		out[i] = Line{Text: text, Fields: ParseFields(text)}
+	}
+	return out
+}
+
+func TestParseFieldsOnlyDecodesObjects(t *testing.T) {
+	if f := ParseFields(`  {"event":"tool_call","tool":"search"}`); f["tool"] != "search" {
+		t.Fatalf("expected decoded object, got %v", f)
+	}
+	for _, text := range []string{"plain text", `["a"]`, `42`, `{"a":1} trailing`, `{"a":`} {
+		if f := ParseFields(text); f != nil {
+			t.Fatalf("ParseFields(%q) = %v, want nil", text, f)
+		}
+	}
+}
+
+func TestFindToolLoopMatchesCanonicalArgs(t *testing.T) {
+	entries := structured(
+		`{"event":"tool_call","tool":"search","args":{"query":"retry policy","page":12345678901234567}}`,
+		`thinking about the results`,
+		`{"event":"tool_call","tool":"search","args":{"page":12345678901234567,"query":"retry policy"}}`,
+		`{"event":"tool_call","tool":"search","args":{"query":"retry policy","page":12345678901234568}}`,
+		`{"type":"function_call","name":"search","arguments":"{\"query\":\"retry policy\",\"page\":12345678901234567}"}`,
+	)
+	loop, ok := FindToolLoop(entries, 3)
+	if !ok {
+		t.Fatal("expected a repeated tool call")
+	}
+	if loop.Tool != "search" || loop.Count != 3 || loop.Error {
+		t.Fatalf("unexpected loop %+v", loop)
+	}
+	if loop.Score != 0.75 {
+		t.Fatalf("score = %.2f, want 0.75 (3 of 4 calls)", loop.Score)
+	}
+	// Numbers in args are compared exactly, not flattened to <NUM>.
+	if want := `tool_call search {"page":12345678901234567,"query":"retry policy"}`; loop.Pattern() != want {
+		t.Fatalf("pattern = %q, want %q", loop.Pattern(), want)
+	}
+
+	if _, ok := FindToolLoop(entries[:3], 3); ok {
+		t.Fatal("two identical calls should stay below min-repeats")
+	}
+}
+
+func TestFindToolLoopCountsRepeatedErrors(t *testing.T) {
+	entries := structured(
+		`{"event":"tool_call","tool":"fetch","args":{"url":"https://a"}}`,
+		`{"event":"tool_result","tool":"fetch","error":"HTTP 503 (request 4411)"}`,
+		`{"event":"tool_call","tool":"fetch","args":{"url":"https://b"}}`,
+		`{"event":"tool_result","tool":"fetch","error":{"message":"HTTP 503 (request 4412)"}}`,
+		`{"event":"tool_call","tool":"fetch","args":{"url":"https://c"}}`,
+		`{"event":"tool_result","tool":"fetch","error":"HTTP 503 (request 4413)"}`,
+	)
+	loop, ok := FindToolLoop(entries, 3)
+	if !ok || !loop.Error || loop.Tool != "fetch" || loop.Score != 1 {
+		t.Fatalf("expected a repeated fetch error, got %+v (ok=%v)", loop, ok)
+	}
+	if want := "error fetch: HTTP <NUM> (request <NUM>)"; loop.Pattern() != want {
+		t.Fatalf("pattern = %q, want %q", loop.Pattern(), want)
+	}
+
+	levelled := structured(
+		`{"level":"error","msg":"connection refused"}`,
+		`{"level":"ERROR","msg":"connection refused"}`,
+		`{"level":"info","msg":"retrying"}`,
+		`{"level":"error","message":"connection refused"}`,
+	)
+	loop, ok = FindToolLoop(levelled, 3)
+	if !ok || loop.Tool != "" || loop.Pattern() != "error: connection refused" {
+		t.Fatalf("expected a repeated untooled error, got %+v (ok=%v)", loop, ok)
+	}
+}
+
+func TestToolCallsDetectorRecordsTool(t *testing.T) {
+	d, err := newToolCallDetector(nil)
+	if err != nil {
+		t.Fatalf("newToolCallDetector: %v", err)
+	}
+	e := NewEngine(6, append(Default(), d)...)
+	for i := 0; i < 6; i++ {
+		text := fmt.Sprintf(`{"ts":"2026-01-02T03:04:0%dZ","event":"tool_call","tool":"search","args":{"q":"x"}}`, i)
+		if i%2 == 1 {
+			text = fmt.Sprintf(`{"event":"thought","text":"trying again, attempt %d"}`, i)
+		}
+		e.ObserveLine(Line{Text: text, Fields: ParseFields(text)})
+	}
+	r := e.Evaluate(Sample{CPUPercent: 90, CPUThreshold: 60})
+	if r.Telemetry.LoopTool != "search" || r.Telemetry.LoopErrors || r.Telemetry.LogRepetition != 1 {
+		t.Fatalf("expected a search loop signal, got %+v", r.Telemetry)
+	}
+	if r.Pattern != `tool_call search {"q":"x"}` {
+		t.Fatalf("unexpected pattern %q", r.Pattern)
+	}
+
+	// Without decoded fields the detector has nothing to count.
+	plain := NewEngine(6, d)
+	for i := 0; i < 6; i++ {
+		plain.ObserveLine(Line{Text: `{"event":"tool_call","tool":"search","args":{"q":"x"}}`})
+	}
+	if r := plain.Evaluate(Sample{}); r.Telemetry.LoopTool != "" {
+		t.Fatalf("expected no tool loop without structured-log mode, got %+v", r.Telemetry)
+	}
+}
+
+func TestToolCallDetectorSettings(t *testing.T) {
+	if _, err := newToolCallDetector(map[string]interface{}{"min-repeats": 1}); err == nil {
+		t.Fatal("expected min-repeats below 2 to be rejected")
+	}
+	d, err := newToolCallDetector(map[string]interface{}{"min-repeats": 4})
+	if err != nil {
+		t.Fatalf("newToolCallDetector: %v", err)
+	}
+	var r Result
+	d.Detect(Input{Entries: structured(
+		`{"tool":"ls","args":{}}`, `{"tool":"ls","args":{}}`, `{"tool":"ls","args":{}}`,
+	)}, &r)
+	if r.Telemetry.LoopTool != "" {
+		t.Fatalf("expected min-repeats 4 to ignore three calls, got %+v", r.Telemetry)
+	}
+}
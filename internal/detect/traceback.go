@@ -0,0 +1,350 @@
+package detect
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTraceMinRepeats = 3
+	defaultTraceWithin     = 10 * time.Minute
+	defaultTraceFrames     = 3
+	maxTraceLines          = 60  // Lines kept as the sample of one trace
+	maxRecentTraces        = 100 // Traces remembered for fingerprint counts
+)
+
+var (
+	pyTraceStart  = regexp.MustCompile(`^\s*Traceback \(most recent call last\):\s*$`)
+	pyFrame       = regexp.MustCompile(`^\s+File "([^"]+)", line \d+, in (.+)$`)
+	exceptionName = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?::|$)`)
+
+	goPanicStart = regexp.MustCompile(`^(?:panic|fatal error): (.*)$`)
+	goGoroutine  = regexp.MustCompile(`^goroutine \d+ \[.*\]:$`)
+	goFrameFile  = regexp.MustCompile(`^\s+\S+\.go:\d+`)
+	goFrameFunc  = regexp.MustCompile(`^([\w./*()\-]+)\(.*\)$`)
+
+	throwHeader = regexp.MustCompile(`^(?:Exception in thread "[^"]*" |Uncaught |Caused by: )?([A-Za-z_$][\w$.]*(?:Error|Exception|Throwable)[\w$]*)(?::\s.*)?$`)
+	atFrame     = regexp.MustCompile(`^\s+at\s+(.+)$`)
+	javaFrame   = regexp.MustCompile(`^([\w$./<>]+)\((?:[\w$]+\.(?:java|kt|scala|groovy):\d+|Native Method|Unknown Source)\)$`)
+	nodeFrame   = regexp.MustCompile(`^(?:async\s+)?(?:(.+?)\s+\()?([^()\s]+?)(?::\d+){1,2}\)?$`)
+	javaMore    = regexp.MustCompile(`^\s+(?:\.\.\. \d+ more|Suppressed: .*)$|^Caused by: `)
+)
+
+// Trace is one stack trace reassembled from several output lines.
+type Trace struct {
+	Language  string   // python, go, node or java
+	Exception string   // Exception type; the normalized panic message for Go
+	Frames    []string // Innermost first, without line numbers
+	Lines     []string // The trace as printed, capped at maxTraceLines
+	Time      time.Time
+}
+
+// Fingerprint identifies a trace by language, exception type and its top
+// frames. Line numbers and addresses are left out so the same crash
+// fingerprints alike after unrelated edits.
+func (t Trace) Fingerprint(frames int) string {
+	top := t.Frames
+	if len(top) > frames {
+		top = top[:frames]
+	}
+	fp := t.Language + ":" + t.Exception
+	if len(top) > 0 {
+		fp += " @ " + strings.Join(top, " < ")
+	}
+	return fp
+}
+
+// traceAssembler turns a stream of lines into stack traces. Python traces
+// end at their exception line. Go, Node and Java traces end at the first
+// line that does not continue them, or when flushed.
+type traceAssembler struct {
+	cur      *Trace
+	header   string // A possible Node/Java exception line, waiting for its first frame
+	frameEnd bool   // The first frame block has ended; later frames are kept as text only
+	draining string // Language whose leftover continuation lines follow an early flush
+}
+
+// Feed adds a line and returns any traces it completed.
+func (a *traceAssembler) Feed(line string, at time.Time) []Trace {
+	var done []Trace
+	if a.cur != nil {
+		if a.continueTrace(line) {
+			if t, ok := a.finishPython(line); ok {
+				done = append(done, t)
+			}
+			return done
+		}
+		if t, ok := a.Flush(); ok {
+			done = append(done, t)
+		}
+		a.cur = nil
+	}
+	if a.draining != "" {
+		if a.continues(a.draining, line) {
+			return done
+		}
+		a.draining = ""
+	}
+	a.start(line, at)
+	return done
+}
+
+// Flush completes a Go, Node or Java trace that has at least one frame.
+// Later lines of the same trace are skipped rather than starting a new one.
+func (a *traceAssembler) Flush() (Trace, bool) {
+	t := a.cur
+	if t == nil || t.Language == "python" || len(t.Frames) == 0 {
+		return Trace{}, false
+	}
+	a.cur = nil
+	a.draining = t.Language
+	return *t, true
+}
+
+func (a *traceAssembler) start(line string, at time.Time) {
+	header := a.header
+	a.header = ""
+	a.frameEnd = false
+	switch {
+	case pyTraceStart.MatchString(line):
+		a.cur = &Trace{Language: "python", Lines: []string{line}, Time: at}
+	case goPanicStart.MatchString(line):
+		msg := goPanicStart.FindStringSubmatch(line)[1]
+		a.cur = &Trace{Language: "go", Exception: NormalizeLog(msg), Lines: []string{line}, Time: at}
+	case header != "" && atFrame.MatchString(line):
+		frame := atFrame.FindStringSubmatch(line)[1]
+		lang := "node"
+		if javaFrame.MatchString(frame) {
+			lang = "java"
+		}
+		a.cur = &Trace{
+			Language:  lang,
+			Exception: throwHeader.FindStringSubmatch(header)[1],
+			Lines:     []string{header, line},
+			Time:      at,
+		}
+		a.addFrame(frame)
+	case throwHeader.MatchString(line):
+		a.header = line
+	}
+}
+
+// continueTrace reports whether line belongs to the current trace and
+// records it.
+func (a *traceAssembler) continueTrace(line string) bool {
+	t := a.cur
+	if !a.continues(t.Language, line) {
+		return false
+	}
+	if len(t.Lines) < maxTraceLines {
+		t.Lines = append(t.Lines, line)
+	}
+	switch t.Language {
+	case "python":
+		if m := pyFrame.FindStringSubmatch(line); m != nil {
+			t.Frames = append(t.Frames, path.Base(m[1])+":"+m[2])
+		}
+	case "go":
+		if goGoroutine.MatchString(line) && len(t.Frames) > 0 {
+			a.frameEnd = true
+		} else if m := goFrameFunc.FindStringSubmatch(line); m != nil && !a.frameEnd {
+			fn := m[1]
+			if i := strings.LastIndex(line, "("); i > 0 {
+				fn = line[:i]
+			}
+			if !strings.HasPrefix(fn, "runtime.") && !strings.HasPrefix(fn, "panic") {
+				t.Frames = append(t.Frames, fn)
+			}
+		}
+	default:
+		if strings.HasPrefix(line, "Caused by: ") {
+			a.frameEnd = true
+		} else if m := atFrame.FindStringSubmatch(line); m != nil && !a.frameEnd {
+			a.addFrame(m[1])
+		}
+	}
+	return true
+}
+
+// continues reports whether line can be part of a trace in lang.
+func (a *traceAssembler) continues(lang, line string) bool {
+	switch lang {
+	case "python":
+		// Frames and source lines are indented; the first unindented line
+		// is the exception and ends the trace.
+		return true
+	case "go":
+		return strings.TrimSpace(line) == "" || goGoroutine.MatchString(line) || goFrameFile.MatchString(line) ||
+			goFrameFunc.MatchString(line) || strings.HasPrefix(line, "created by ") || strings.HasPrefix(line, "[signal ")
+	default:
+		return atFrame.MatchString(line) || javaMore.MatchString(line)
+	}
+}
+
+// finishPython ends a Python trace at its exception line.
+func (a *traceAssembler) finishPython(line string) (Trace, bool) {
+	t := a.cur
+	if t.Language != "python" || line == "" || line[0] == ' ' || line[0] == '\t' || pyTraceStart.MatchString(line) {
+		return Trace{}, false
+	}
+	if m := exceptionName.FindStringSubmatch(line); m != nil {
+		t.Exception = m[1]
+	} else {
+		t.Exception = NormalizeLog(line)
+	}
+	// Python prints the innermost call last.
+	for i, j := 0, len(t.Frames)-1; i < j; i, j = i+1, j-1 {
+		t.Frames[i], t.Frames[j] = t.Frames[j], t.Frames[i]
+	}
+	a.cur = nil
+	return *t, true
+}
+
+func (a *traceAssembler) addFrame(frame string) {
+	t := a.cur
+	if t.Language == "java" {
+		if m := javaFrame.FindStringSubmatch(frame); m != nil {
+			t.Frames = append(t.Frames, m[1])
+		}
+		return
+	}
+	m := nodeFrame.FindStringSubmatch(frame)
+	if m == nil {
+		t.Frames = append(t.Frames, frame)
+		return
+	}
+	file := path.Base(m[2])
+	if m[1] == "" {
+		t.Frames = append(t.Frames, file)
+		return
+	}
+	t.Frames = append(t.Frames, file+":"+m[1])
+}
+
+// tracebackDetector reassembles stack traces from stderr (and from output
+// whose stream is unknown) and raises a loop signal when the same
+// fingerprint recurs min-repeats times within the time window. It holds
+// state across lines, so every engine needs its own instance.
+type tracebackDetector struct {
+	minRepeats int
+	within     time.Duration
+	frames     int
+
+	asm    traceAssembler
+	recent []Trace
+}
+
+func newTracebackDetector(settings map[string]interface{}) (Detector, error) {
+	d := &tracebackDetector{minRepeats: defaultTraceMinRepeats, within: defaultTraceWithin, frames: defaultTraceFrames}
+	if v, ok := settings["min-repeats"]; ok {
+		n, ok := toInt(v)
+		if !ok || n < 2 {
+			return nil, fmt.Errorf("min-repeats must be an integer >= 2")
+		}
+		d.minRepeats = n
+	}
+	if v, ok := settings["frames"]; ok {
+		n, ok := toInt(v)
+		if !ok || n < 1 {
+			return nil, fmt.Errorf("frames must be an integer >= 1")
+		}
+		d.frames = n
+	}
+	if v, ok := settings["within"]; ok {
+		var within time.Duration
+		var err error
+		switch w := v.(type) {
+		case string:
+			within, err = time.ParseDuration(w)
+		default:
+			secs, ok := toFloat(v)
+			if !ok {
+				err = fmt.Errorf("not a number")
+			}
+			within = time.Duration(secs * float64(time.Second))
+		}
+		if err != nil || within <= 0 {
+			return nil, fmt.Errorf("within must be a positive duration like 10m")
+		}
+		d.within = within
+	}
+	return d, nil
+}
+
+func (*tracebackDetector) Name() string { return "traceback" }
+
+func (d *tracebackDetector) ObserveLine(l Line) {
+	if l.Stream == "stdout" {
+		return
+	}
+	at := l.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	d.remember(d.asm.Feed(l.Text, at)...)
+}
+
+func (d *tracebackDetector) remember(traces ...Trace) {
+	d.recent = append(d.recent, traces...)
+	if over := len(d.recent) - maxRecentTraces; over > 0 {
+		d.recent = append([]Trace(nil), d.recent[over:]...)
+	}
+}
+
+func (d *tracebackDetector) Detect(in Input, out *Result) {
+	if t, ok := d.asm.Flush(); ok {
+		d.remember(t)
+	}
+	if !in.Sample.Time.IsZero() {
+		cutoff := in.Sample.Time.Add(-d.within)
+		keep := d.recent[:0]
+		for _, t := range d.recent {
+			if !t.Time.Before(cutoff) {
+				keep = append(keep, t)
+			}
+		}
+		d.recent = keep
+	}
+	if len(d.recent) == 0 {
+		return
+	}
+
+	counts := make(map[string]int, len(d.recent))
+	best, bestCount := "", 0
+	for _, t := range d.recent {
+		fp := t.Fingerprint(d.frames)
+		counts[fp]++
+		if counts[fp] > bestCount {
+			best, bestCount = fp, counts[fp]
+		}
+	}
+	if bestCount < d.minRepeats {
+		return
+	}
+	var sample Trace
+	for _, t := range d.recent {
+		if t.Fingerprint(d.frames) == best {
+			sample = t // The most recent occurrence
+		}
+	}
+	sample.Frames = append([]string(nil), sample.Frames...)
+	sample.Lines = append([]string(nil), sample.Lines...)
+
+	score := float64(bestCount) / float64(len(d.recent))
+	if score > out.Telemetry.LogRepetition {
+		out.Telemetry.LogRepetition = score
+	}
+	out.Telemetry.RepeatedTrace = bestCount
+	out.Pattern = best
+	out.Trace = &TraceMatch{Trace: sample, Fingerprint: best, Count: bestCount}
+}
+
+// TraceMatch is the stack trace behind a traceback loop signal.
+type TraceMatch struct {
+	Trace
+	Fingerprint string
+	Count       int // Occurrences within the detector's window
+}
@@ -0,0 +1,180 @@
+package detect
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const pythonTrace = `Traceback (most recent call last):
+  File "/app/agent.py", line 88, in <module>
+    main()
+  File "/app/agent.py", line 80, in main
+    run_step(state)
+  File "/app/tools.py", line 41, in fetch_page
+    return cache[url]
+           ~~~~~^^^^^
+KeyError: 'https://example.com/page/17'`
+
+const goTrace = `panic: runtime error: index out of range [5] with length 3
+
+goroutine 1 [running]:
+main.(*Agent).step(0xc000012345, 0x5)
+	/src/agent/main.go:42 +0x1d
+main.(*Agent).Run(...)
+	/src/agent/main.go:30
+main.main()
+	/src/agent/main.go:12 +0x25
+exit status 2`
+
+const nodeTrace = `TypeError: Cannot read properties of undefined (reading 'id')
+    at fetchPage (/app/tools.js:10:21)
+    at async runStep (/app/agent.js:55:3)
+    at /app/agent.js:70:5
+done`
+
+const javaTrace = `Exception in thread "main" java.lang.IllegalStateException: queue drained (size=0)
+	at com.acme.agent.Queue.take(Queue.java:71)
+	at com.acme.agent.Worker.run(Worker.java:33)
+	at java.base/java.lang.Thread.run(Thread.java:833)
+Caused by: java.io.IOException: closed
+	at com.acme.agent.Conn.read(Conn.java:12)
+	... 3 more
+next line`
+
+func assemble(text string) []Trace {
+	var a traceAssembler
+	var out []Trace
+	for _, line := range strings.Split(text, "\n") {
+		out = append(out, a.Feed(line, time.Time{})...)
+	}
+	if t, ok := a.Flush(); ok {
+		out = append(out, t)
+	}
+	return out
+}
+
+func TestTraceAssemblerFingerprintsLanguages(t *testing.T) {
+	cases := []struct {
+		name, text, fingerprint string
+		lines                   int
+	}{
+		{"python", pythonTrace, "python:KeyError @ tools.py:fetch_page < agent.py:main < agent.py:<module>", 9},
+		{"go", goTrace, "go:runtime error: index out of range [<NUM>] with length <NUM> @ main.(*Agent).step < main.(*Agent).Run < main.main", 9},
+		{"node", nodeTrace, "node:TypeError @ tools.js:fetchPage < agent.js:runStep < agent.js", 4},
+		{"java", javaTrace, "java:java.lang.IllegalStateException @ com.acme.agent.Queue.take < com.acme.agent.Worker.run < java.base/java.lang.Thread.run", 7},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			traces := assemble(tc.text)
+			if len(traces) != 1 {
+				t.Fatalf("expected one trace, got %d: %+v", len(traces), traces)
+			}
+			if fp := traces[0].Fingerprint(3); fp != tc.fingerprint {
+				t.Fatalf("fingerprint = %q\nwant          %q", fp, tc.fingerprint)
+			}
+			if len(traces[0].Lines) != tc.lines {
+				t.Fatalf("sample has %d lines, want %d: %q", len(traces[0].Lines), tc.lines, traces[0].Lines)
+			}
+		})
+	}
+}
+
+func TestTraceAssemblerIgnoresPlainErrors(t *testing.T) {
+	text := "Error: connection refused\nretrying in 5s\nValueError: bad input\n  indented detail"
+	if traces := assemble(text); len(traces) != 0 {
+		t.Fatalf("expected no traces, got %+v", traces)
+	}
+	// A trace split by an early flush is counted once.
+	var a traceAssembler
+	lines := strings.Split(nodeTrace, "\n")
+	got := a.Feed(lines[0], time.Time{})
+	got = append(got, a.Feed(lines[1], time.Time{})...)
+	if t1, ok := a.Flush(); ok {
+		got = append(got, t1)
+	}
+	for _, line := range lines[2:] {
+		got = append(got, a.Feed(line, time.Time{})...)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Frames, []string{"tools.js:fetchPage"}) {
+		t.Fatalf("expected one flushed trace, got %+v", got)
+	}
+}
+
+func TestTracebackDetectorFlagsRecurringTrace(t *testing.T) {
+	d, err := newTracebackDetector(nil)
+	if err != nil {
+		t.Fatalf("newTracebackDetector: %v", err)
+	}
+	e := NewEngine(10, d)
+	start := time.Now()
+	feed := func(text, stream string, at time.Time) {
+		for _, line := range strings.Split(text, "\n") {
+			e.ObserveLine(Line{Text: line, Stream: stream, Time: at})
+		}
+	}
+
+	// The same crash on stdout does not count.
+	for i := 0; i < 3; i++ {
+		feed(pythonTrace, "stdout", start)
+	}
+	if r := e.Evaluate(Sample{Time: start}); r.Trace != nil {
+		t.Fatalf("expected stdout traces to be ignored, got %+v", r.Trace)
+	}
+
+	feed(pythonTrace, "stderr", start)
+	feed(strings.Replace(pythonTrace, "line 41", "line 44", 1), "stderr", start)
+	feed(goTrace, "stderr", start)
+	if r := e.Evaluate(Sample{Time: start}); r.Trace != nil {
+		t.Fatalf("two occurrences should stay below min-repeats, got %+v", r.Trace)
+	}
+	feed(strings.Replace(pythonTrace, "17", "18", 1), "", start.Add(time.Minute))
+
+	r := e.Evaluate(Sample{Time: start.Add(time.Minute)})
+	if r.Trace == nil || r.Trace.Count != 3 {
+		t.Fatalf("expected the python trace three times, got %+v", r.Trace)
+	}
+	if r.Telemetry.RepeatedTrace != 3 || r.Telemetry.LogRepetition != 0.75 {
+		t.Fatalf("unexpected telemetry %+v", r.Telemetry)
+	}
+	if r.Pattern != r.Trace.Fingerprint || !strings.HasPrefix(r.Pattern, "python:KeyError @ tools.py:fetch_page") {
+		t.Fatalf("unexpected pattern %q", r.Pattern)
+	}
+	if last := r.Trace.Lines[len(r.Trace.Lines)-1]; last != "KeyError: 'https://example.com/page/18'" {
+		t.Fatalf("expected the latest occurrence as the sample, got %q", last)
+	}
+
+	// Occurrences older than the window drop out.
+	if r := e.Evaluate(Sample{Time: start.Add(defaultTraceWithin + 30*time.Second)}); r.Trace != nil {
+		t.Fatalf("expected old traces to expire, got %+v", r.Trace)
+	}
+}
+
+func TestTracebackDetectorSettings(t *testing.T) {
+	for _, settings := range []map[string]interface{}{
+		{"min-repeats": 1},
+		{"frames": 0},
+		{"within": "soon"},
+		{"within": -5},
+	} {
+		if _, err := newTracebackDetector(settings); err == nil {
+			t.Fatalf("expected %v to be rejected", settings)
+		}
+	}
+	d, err := newTracebackDetector(map[string]interface{}{"min-repeats": 2, "frames": 1, "within": 90})
+	if err != nil {
+		t.Fatalf("newTracebackDetector: %v", err)
+	}
+	td := d.(*tracebackDetector)
+	if td.minRepeats != 2 || td.frames != 1 || td.within != 90*time.Second {
+		t.Fatalf("unexpected settings %+v", td)
+	}
+
+	// Each build gets its own state.
+	a, _ := Build([]string{"traceback"}, nil)
+	b, _ := Build([]string{"traceback"}, nil)
+	if a[0] == b[0] {
+		t.Fatal("expected separate traceback detector instances per build")
+	}
+}
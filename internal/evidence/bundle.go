@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -15,6 +16,7 @@ import (
 	"time"
 
 	"flowforge/internal/database"
+	"flowforge/internal/recording"
 )
 
 const (
@@ -29,6 +31,7 @@ type ExportOptions struct {
 	AuditLimit    int
 	DecisionLimit int
 	ChainLimit    int
+	Recordings    []string // run --record files, copied in as recording_<name>
 }
 
 type BundleFile struct {
@@ -74,6 +77,7 @@ type bundleSummary struct {
 	DecisionCount      int    `json:"decision_count"`
 	IncidentChainCount int    `json:"incident_chain_count,omitempty"`
 	SelectedIncidentID string `json:"selected_incident_id,omitempty"`
+	RecordingCount     int    `json:"recording_count,omitempty"`
 }
 
 func Export(opts ExportOptions, signingKey []byte) (ExportResult, error) {
@@ -149,6 +153,18 @@ func Export(opts ExportOptions, signingKey []byte) (ExportResult, error) {
 	if err := record("decision_traces.json", decisions); err != nil {
 		return ExportResult{}, err
 	}
+	for _, src := range opts.Recordings {
+		f, err := attachRecording(opts.OutDir, src)
+		if err != nil {
+			return ExportResult{}, err
+		}
+		for _, prev := range files {
+			if prev.Path == f.Path {
+				return ExportResult{}, fmt.Errorf("recording %s: another recording is already bundled as %s", src, f.Path)
+			}
+		}
+		files = append(files, f)
+	}
 
 	summary := bundleSummary{
 		GeneratedAt:        generatedAt,
@@ -158,6 +174,7 @@ func Export(opts ExportOptions, signingKey []byte) (ExportResult, error) {
 		DecisionCount:      len(decisions),
 		IncidentChainCount: len(chain),
 		SelectedIncidentID: strings.TrimSpace(opts.IncidentID),
+		RecordingCount:     len(opts.Recordings),
 	}
 	if err := record("summary.json", summary); err != nil {
 		return ExportResult{}, err
@@ -292,6 +309,33 @@ func writeJSONPayload(outDir, filename string, payload any) (BundleFile, error)
 	return fileDigest(fullPath)
 }
 
+// attachRecording copies a recording into the bundle after checking that
+// it decodes, so a truncated or unrelated file is not signed as evidence.
+func attachRecording(outDir, src string) (BundleFile, error) {
+	if _, err := recording.ReadFile(src); err != nil {
+		return BundleFile{}, fmt.Errorf("recording %s: %w", src, err)
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return BundleFile{}, fmt.Errorf("recording %s: %w", src, err)
+	}
+	defer in.Close()
+
+	fullPath := filepath.Join(outDir, "recording_"+filepath.Base(src))
+	out, err := os.Create(fullPath)
+	if err != nil {
+		return BundleFile{}, fmt.Errorf("write %s: %w", filepath.Base(fullPath), err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return BundleFile{}, fmt.Errorf("copy recording %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return BundleFile{}, fmt.Errorf("write %s: %w", filepath.Base(fullPath), err)
+	}
+	return fileDigest(fullPath)
+}
+
 func fileDigest(path string) (BundleFile, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
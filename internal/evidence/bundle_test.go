@@ -5,8 +5,10 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"flowforge/internal/database"
+	"flowforge/internal/recording"
 )
 
 func setupEvidenceTestDB(t *testing.T) string {
@@ -129,3 +131,47 @@ func TestVerifyDetectsTampering(t *testing.T) {
 		t.Fatalf("expected mismatch error, got %v", err)
 	}
 }
+
+func TestExportAttachesRecordings(t *testing.T) {
+	setupEvidenceTestDB(t)
+	seedEvidenceData(t)
+
+	recPath := filepath.Join(t.TempDir(), "agent.jsonl")
+	w, err := recording.Create(recPath, recording.Header{Command: "python3 demo/runaway.py"}, time.Now())
+	if err != nil {
+		t.Fatalf("create recording: %v", err)
+	}
+	w.Line(time.Now(), "stdout", "retrying")
+	w.Sample(time.Now(), recording.Sample{CPUPercent: 95})
+	if err := w.Close(); err != nil {
+		t.Fatalf("close recording: %v", err)
+	}
+
+	outDir := filepath.Join(t.TempDir(), "bundle")
+	key := []byte("0123456789abcdef0123456789abcdef")
+	result, err := Export(ExportOptions{OutDir: outDir, Recordings: []string{recPath}}, key)
+	if err != nil {
+		t.Fatalf("export bundle: %v", err)
+	}
+	found := false
+	for _, f := range result.Manifest.Files {
+		found = found || f.Path == "recording_agent.jsonl"
+	}
+	if !found {
+		t.Fatalf("expected recording_agent.jsonl in manifest, got %+v", result.Manifest.Files)
+	}
+	if _, err := recording.ReadFile(filepath.Join(outDir, "recording_agent.jsonl")); err != nil {
+		t.Fatalf("bundled recording does not decode: %v", err)
+	}
+	if _, err := Verify(outDir, key); err != nil {
+		t.Fatalf("verify bundle: %v", err)
+	}
+
+	notes := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(notes, []byte("not a recording\n"), 0o644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+	if _, err := Export(ExportOptions{OutDir: filepath.Join(t.TempDir(), "bad"), Recordings: []string{notes}}, key); err == nil {
+		t.Fatal("expected a file that is not a recording to be rejected")
+	}
+}
@@ -14,6 +14,8 @@ type Store struct {
 	authFailures              uint64
 	processKills              uint64
 	processRestarts           uint64
+	processPauses             uint64
+	processResumes            uint64
 	restartBudgetBlocked      uint64
 	controlPlaneReplayTotal   uint64
 	controlPlaneConflictTotal uint64
@@ -71,6 +73,18 @@ func (s *Store) IncProcessRestart() {
 	s.processRestarts++
 }
 
+func (s *Store) IncProcessPause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processPauses++
+}
+
+func (s *Store) IncProcessResume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processResumes++
+}
+
 func (s *Store) IncRestartBudgetBlocked() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -163,6 +177,14 @@ func (s *Store) Prometheus(activeProcess bool) string {
 	b.WriteString("# TYPE flowforge_process_restart_total counter\n")
 	fmt.Fprintf(&b, "flowforge_process_restart_total %d\n", s.processRestarts)
 
+	b.WriteString("# HELP flowforge_process_pause_total Process pause actions.\n")
+	b.WriteString("# TYPE flowforge_process_pause_total counter\n")
+	fmt.Fprintf(&b, "flowforge_process_pause_total %d\n", s.processPauses)
+
+	b.WriteString("# HELP flowforge_process_resume_total Process resume actions.\n")
+	b.WriteString("# TYPE flowforge_process_resume_total counter\n")
+	fmt.Fprintf(&b, "flowforge_process_resume_total %d\n", s.processResumes)
+
 	b.WriteString("# HELP flowforge_restart_budget_block_total Restart requests blocked by restart budget.\n")
 	b.WriteString("# TYPE flowforge_restart_budget_block_total counter\n")
 	fmt.Fprintf(&b, "flowforge_restart_budget_block_total %d\n", s.restartBudgetBlocked)
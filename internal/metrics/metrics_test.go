@@ -11,6 +11,8 @@ func TestPrometheusIncludesLifecycleLatencyMetrics(t *testing.T) {
 	store.ObserveStopLatency(3.6, false)
 	store.ObserveRestartLatency(1.2, true)
 	store.IncRestartBudgetBlocked()
+	store.IncProcessPause()
+	store.IncProcessResume()
 	store.IncControlPlaneIdempotentReplay()
 	store.IncControlPlaneIdempotencyConflict()
 
@@ -28,6 +30,8 @@ func TestPrometheusIncludesLifecycleLatencyMetrics(t *testing.T) {
 		"flowforge_stop_slo_target_seconds 3.0",
 		"flowforge_restart_slo_target_seconds 5.0",
 		"flowforge_restart_budget_block_total 1",
+		"flowforge_process_pause_total 1",
+		"flowforge_process_resume_total 1",
 		"flowforge_controlplane_idempotent_replay_total 1",
 		"flowforge_controlplane_idempotency_conflict_total 1",
 	}
@@ -0,0 +1,222 @@
+package patterns
+
+import (
+	"math"
+	"sort"
+)
+
+// gramSize is the q of the q-grams the index is built from.
+const gramSize = 3
+
+// index is an inverted index from padded q-grams to the stored strings that
+// contain them. It narrows a similarity search down to the strings that can
+// reach the threshold; callers verify those candidates exactly. An index is
+// not safe for concurrent use.
+//
+// The filter is lossless. One edit destroys at most q of a string's padded
+// q-grams, so strings within Levenshtein distance k share at least
+// max(|G(a)|, |G(b)|) - q*k of them, counted as multisets. Numbering repeated
+// grams (abc#1, abc#2) turns that multiset count into a set intersection.
+// A query that needs B of its n grams in common needs at least one of any
+// n-B+1 of them, so only the rarest grams have to be read; reading more lets
+// each entry be held to its own count, less the grams that were skipped.
+type index struct {
+	postings map[gram][]int32 // Entries holding each gram, ascending
+	lengths  []int            // Rune length of each entry
+	counts   []uint32         // Scratch: grams shared with the current query
+}
+
+// gram is a q-gram packed 21 bits per rune, with its occurrence number in
+// the string.
+type gram struct {
+	runes uint64
+	n     uint32
+}
+
+// commonGramShare is the share of all entries above which a gram's postings
+// are only read when the count filter needs them: counting a gram found in
+// most entries costs more than verifying the few candidates it would rule out.
+const commonGramShare = 16
+
+func newIndex() *index {
+	return &index{postings: make(map[gram][]int32)}
+}
+
+// add indexes s and returns its entry number.
+func (x *index) add(s string) int {
+	id := len(x.lengths)
+	x.lengths = append(x.lengths, len([]rune(s)))
+	x.counts = append(x.counts, 0)
+	for _, g := range grams(s) {
+		x.postings[g] = append(x.postings[g], int32(id))
+	}
+	return id
+}
+
+// candidates returns, in ascending order, every entry whose Levenshtein
+// similarity to query (1 - distance/longer length, as strutil computes it)
+// may be at least threshold.
+func (x *index) candidates(query string, threshold float64) []int {
+	qLen := len([]rune(query))
+	// Similarity t needs |len(a) - len(b)| <= distance <= (1-t) * longer length.
+	lo, hi := 0, math.MaxInt32
+	if threshold > 0 {
+		lo = int(math.Ceil(threshold*float64(qLen) - 1e-9))
+		hi = int(math.Floor(float64(qLen)/threshold + 1e-9))
+	}
+	inRange := func(id int) bool {
+		l := x.lengths[id]
+		return l >= lo && l <= hi
+	}
+
+	qGrams := grams(query)
+	need := len(qGrams) - gramSize*maxDistance(threshold, hi)
+	if threshold <= 0 || need <= 0 {
+		// Too loose a threshold for the count filter: every entry of a
+		// plausible length is a candidate.
+		var out []int
+		for id := range x.lengths {
+			if inRange(id) {
+				out = append(out, id)
+			}
+		}
+		return out
+	}
+
+	sort.Slice(qGrams, func(i, j int) bool {
+		return len(x.postings[qGrams[i]]) < len(x.postings[qGrams[j]])
+	})
+	mustRead := len(qGrams) - need + 1
+	common := len(x.lengths) / commonGramShare
+	var touched []int32
+	read := 0
+	for _, g := range qGrams {
+		ids := x.postings[g]
+		if read >= mustRead && len(ids) > common {
+			break
+		}
+		for _, id := range ids {
+			if x.counts[id] == 0 {
+				touched = append(touched, id)
+			}
+			x.counts[id]++
+		}
+		read++
+	}
+
+	skipped := len(qGrams) - read
+	var out []int
+	for _, id := range touched {
+		shared := int(x.counts[id])
+		x.counts[id] = 0
+		if !inRange(int(id)) {
+			continue
+		}
+		l := x.lengths[id]
+		longer := max(qLen, l)
+		if shared+skipped >= max(len(qGrams), l+gramSize-1)-gramSize*maxDistance(threshold, longer) {
+			out = append(out, int(id))
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// maxDistance is the most edits two strings, the longer of them n runes,
+// can be apart and still be threshold similar.
+func maxDistance(threshold float64, n int) int {
+	return int(math.Floor((1-threshold)*float64(n) + 1e-9))
+}
+
+// similar reports whether a and b are at least threshold similar, with the
+// same result strutil.Similarity gives under metrics.NewLevenshtein. The
+// distance is computed in a band around the diagonal and given up on as soon
+// as it cannot stay within the threshold.
+func similar(a, b string, threshold float64) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) > len(rb) {
+		ra, rb = rb, ra
+	}
+	n := len(rb)
+	if n == 0 {
+		return false
+	}
+	k := maxDistance(threshold, n)
+	if threshold <= 0 {
+		k = n
+	}
+	if n-len(ra) > k {
+		return false
+	}
+
+	// Cells outside the band hold k+1: any distance derived from them is
+	// over k as well, so the ones within k stay exact.
+	prev, cur := make([]int, n+1), make([]int, n+1)
+	for j := range prev {
+		prev[j] = min(j, k+1)
+	}
+	for i := 1; i <= len(ra); i++ {
+		from, to := max(1, i-k), min(n, i+k)
+		cur[from-1] = k + 1
+		if from == 1 {
+			cur[0] = min(i, k+1)
+		}
+		best := cur[from-1]
+		for j := from; j <= to; j++ {
+			d := prev[j-1]
+			if ra[i-1] != rb[j-1] {
+				d++
+			}
+			d = min(d, prev[j]+1, cur[j-1]+1)
+			cur[j] = d
+			best = min(best, d)
+		}
+		if to < n {
+			cur[to+1] = k + 1
+		}
+		if best > k {
+			return false
+		}
+		prev, cur = cur, prev
+	}
+	d := prev[n]
+	return d <= k && 1-float64(d)/float64(n) >= threshold
+}
+
+// grams returns the numbered q-grams of s padded with q-1 sentinels on each
+// side, so that every rune, even in a short string, is in q grams.
+func grams(s string) []gram {
+	const pad = 0
+	runes := make([]rune, 0, len(s)+2*(gramSize-1))
+	for i := 0; i < gramSize-1; i++ {
+		runes = append(runes, pad)
+	}
+	runes = append(runes, []rune(s)...)
+	for i := 0; i < gramSize-1; i++ {
+		runes = append(runes, pad)
+	}
+
+	out := make([]gram, len(runes)-gramSize+1)
+	var counts map[uint64]uint32 // Only for long lines; rescanning is cheaper below that
+	if len(out) > 64 {
+		counts = make(map[uint64]uint32, len(out))
+	}
+	for i := range out {
+		var packed uint64
+		for _, r := range runes[i : i+gramSize] {
+			packed = packed<<21 | uint64(r)&(1<<21-1)
+		}
+		out[i] = gram{runes: packed, n: 1}
+		if counts != nil {
+			counts[packed]++
+			out[i].n = counts[packed]
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if out[j].runes == packed {
+				out[i].n++
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package patterns
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+)
+
+// The index may only skip entries that cannot reach the threshold, and the
+// banded verification must agree with strutil, so an indexed search finds
+// exactly what comparing against every entry finds.
+func TestIndexCandidatesAreLossless(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	words := []string{"retrying", "request", "<NUM>", "tool", "call", "failed:", "timeout", "page", "of", "error", "é", "x"}
+	phrase := func() string {
+		n := 1 + rng.Intn(8)
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = words[rng.Intn(len(words))]
+		}
+		return strings.Join(parts, " ")
+	}
+	mutate := func(s string) string {
+		r := []rune(s)
+		for edits := rng.Intn(4); edits > 0 && len(r) > 0; edits-- {
+			i := rng.Intn(len(r))
+			switch rng.Intn(3) {
+			case 0:
+				r = append(r[:i], r[i+1:]...)
+			case 1:
+				r[i] = 'z'
+			default:
+				r = append(r[:i], append([]rune{'q'}, r[i:]...)...)
+			}
+		}
+		return string(r)
+	}
+
+	var stored []string
+	x := newIndex()
+	for i := 0; i < 200; i++ {
+		s := phrase()
+		stored = append(stored, s)
+		x.add(s)
+	}
+	lev := metrics.NewLevenshtein()
+	for _, threshold := range []float64{0.5, 0.8, 0.9, 0.95, 1} {
+		for i := 0; i < 100; i++ {
+			query := mutate(stored[rng.Intn(len(stored))])
+			if query == "" {
+				continue
+			}
+			candidate := make(map[int]bool)
+			for _, id := range x.candidates(query, threshold) {
+				candidate[id] = true
+			}
+			for id, s := range stored {
+				want := strutil.Similarity(query, s, lev) >= threshold
+				if want && !candidate[id] {
+					t.Fatalf("threshold %.2f: %q misses %q", threshold, query, s)
+				}
+				if similar(query, s, threshold) != want {
+					t.Fatalf("threshold %.2f: similar(%q, %q) = %v", threshold, query, s, !want)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexPrunesDissimilarEntries(t *testing.T) {
+	x := newIndex()
+	for _, s := range []string{"fetching page <NUM> of <NUM>", "tool call failed: timeout", "fetching page <NUM> of <NUM>!", "ok"} {
+		x.add(s)
+	}
+	got := x.candidates("fetching page <NUM> of <NUM>", MatchSimilarity)
+	if len(got) != 2 || got[0] != 0 || got[1] != 2 {
+		t.Fatalf("expected entries 0 and 2, got %v", got)
+	}
+}
@@ -0,0 +1,197 @@
+package patterns
+
+import (
+	"encoding/json"
+	"errors"
+	"flowforge/internal/database"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// BlacklistFile is the flat JSON list older releases kept in the working
+	// directory. Load imports it into an empty registry.
+	BlacklistFile = "pattern_blacklist.json"
+
+	// MatchSimilarity is how close a normalized line must be to a known
+	// pattern to match it.
+	MatchSimilarity = 0.9
+	// dedupSimilarity is how close a newly learned pattern must be to a
+	// stored one to count as the same pattern.
+	dedupSimilarity = 0.95
+)
+
+// Registry is the known bad patterns a run matches its output against and
+// learns new patterns into, loaded once at startup. Every stored pattern is
+// indexed so learning can find near duplicates; only enabled, unexpired
+// ones match. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	patterns []database.Pattern // By ID; entry i is index entry i
+	active   []bool
+	nActive  int
+	index    *index
+}
+
+// NewRegistry indexes ps, matching the enabled and unexpired ones.
+func NewRegistry(ps []database.Pattern) *Registry {
+	r := &Registry{index: newIndex()}
+	for _, p := range ps {
+		r.add(p, p.Enabled && !p.Expired)
+	}
+	return r
+}
+
+func (r *Registry) add(p database.Pattern, active bool) {
+	r.index.add(p.Pattern)
+	r.patterns = append(r.patterns, p)
+	r.active = append(r.active, active)
+	if active {
+		r.nActive++
+	}
+}
+
+// Load reads the registry from the database. When it is empty and a legacy
+// pattern_blacklist.json exists, its patterns are imported first with the
+// warn action.
+func Load() (*Registry, error) {
+	if n, err := database.CountPatterns(); err != nil {
+		return NewRegistry(nil), err
+	} else if n == 0 {
+		if imported, err := importLegacy(BlacklistFile); err != nil {
+			fmt.Printf("[FlowForge] Warning: could not import %s: %v\n", BlacklistFile, err)
+		} else if imported > 0 {
+			fmt.Printf("[FlowForge] 📋 Imported %d patterns from %s\n", imported, BlacklistFile)
+		}
+	}
+	all, err := database.ListPatterns()
+	if err != nil {
+		return NewRegistry(nil), err
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return NewRegistry(all), nil
+}
+
+func importLegacy(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	ps, err := Parse(data)
+	if err != nil {
+		return 0, err
+	}
+	return database.ImportPatterns(ps)
+}
+
+// Len returns the number of active patterns.
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.nActive
+}
+
+// Match returns the active pattern with the lowest ID that normalized is at
+// least MatchSimilarity similar to.
+func (r *Registry) Match(normalized string) (database.Pattern, bool) {
+	if r.Len() == 0 || normalized == "" {
+		return database.Pattern{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(normalized, MatchSimilarity, true)
+}
+
+// find verifies the index candidates for s in ID order.
+func (r *Registry) find(s string, threshold float64, activeOnly bool) (database.Pattern, bool) {
+	for _, id := range r.index.candidates(s, threshold) {
+		if activeOnly && !r.active[id] {
+			continue
+		}
+		if similar(s, r.patterns[id].Pattern, threshold) {
+			return r.patterns[id], true
+		}
+	}
+	return database.Pattern{}, false
+}
+
+// Learn records a pattern a run was stopped or alerted for. A pattern close
+// to one already stored, enabled or not, counts as a hit on it; otherwise
+// it is added with action and ttl. Patterns learned during a run apply from
+// the next run on.
+func (r *Registry) Learn(pattern, command, action string, ttl time.Duration) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if known, ok := r.find(pattern, dedupSimilarity, false); ok {
+		return database.RecordPatternHit(known.ID)
+	}
+	p, err := database.AddPattern(database.Pattern{
+		Pattern:       pattern,
+		SourceCommand: command,
+		Action:        action,
+		TTLSeconds:    int64(ttl / time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save pattern: %v", err)
+	}
+	r.add(p, false)
+	fmt.Printf("[FlowForge] 📋 Pattern registry updated (%d patterns)\n", len(r.patterns))
+	return nil
+}
+
+// Parse decodes a patterns file: either an export (a JSON array of pattern
+// objects) or a legacy blacklist (a JSON array of strings). Objects without
+// an "enabled" key are enabled.
+func Parse(data []byte) ([]database.Pattern, error) {
+	var ps []database.Pattern
+	var legacy []string
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, errors.New("expected a JSON array of patterns or of strings")
+		}
+		for i, item := range raw {
+			p := database.Pattern{Enabled: true}
+			if err := json.Unmarshal(item, &p); err != nil {
+				return nil, fmt.Errorf("pattern %d: %v", i+1, err)
+			}
+			ps = append(ps, p)
+		}
+		return ps, nil
+	}
+	for _, s := range legacy {
+		ps = append(ps, database.Pattern{Pattern: s, Action: database.PatternActionWarn, Enabled: true})
+	}
+	return ps, nil
+}
+
+// Export writes ps in the format Parse and import read. IDs are local to a
+// database and are left out.
+func Export(w io.Writer, ps []database.Pattern) error {
+	out := make([]database.Pattern, 0, len(ps))
+	for _, p := range ps {
+		p.ID = 0
+		p.Expired = false
+		out = append(out, p)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) // Normalized patterns are full of <NUM>
+	return enc.Encode(out)
+}
@@ -0,0 +1,109 @@
+package patterns
+
+import (
+	"bytes"
+	"flowforge/internal/database"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func useTempDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("FLOWFORGE_DB_PATH", filepath.Join(t.TempDir(), "patterns-test.db"))
+	database.CloseDB()
+	if err := database.InitDB(); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(database.CloseDB)
+}
+
+func TestParseAcceptsExportsAndLegacyLists(t *testing.T) {
+	legacy, err := Parse([]byte(`["retrying request <NUM>", "connection refused"]`))
+	if err != nil {
+		t.Fatalf("Parse legacy: %v", err)
+	}
+	if len(legacy) != 2 || legacy[0].Pattern != "retrying request <NUM>" || legacy[0].Action != database.PatternActionWarn || !legacy[0].Enabled {
+		t.Fatalf("unexpected legacy patterns: %+v", legacy)
+	}
+
+	exported, err := Parse([]byte(`[{"pattern":"a","action":"kill","hit_count":3},{"pattern":"b","action":"alert","enabled":false}]`))
+	if err != nil {
+		t.Fatalf("Parse export: %v", err)
+	}
+	if len(exported) != 2 || !exported[0].Enabled || exported[0].HitCount != 3 || exported[1].Enabled {
+		t.Fatalf("expected a missing enabled key to mean enabled, got %+v", exported)
+	}
+
+	if _, err := Parse([]byte(`{"pattern":"a"}`)); err == nil {
+		t.Fatal("expected a non-array to be rejected")
+	}
+}
+
+func TestLoadImportsLegacyBlacklistOnce(t *testing.T) {
+	useTempDB(t)
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	if err := os.WriteFile(BlacklistFile, []byte(`["fetching page <NUM> of <NUM>"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if r.Len() != 1 {
+		t.Fatalf("expected the legacy pattern to be imported, got %d", r.Len())
+	}
+	p, ok := r.Match("fetching page <NUM> of <NUM>.")
+	if !ok || p.Action != database.PatternActionWarn {
+		t.Fatalf("expected a near match on the imported pattern, got %+v, %v", p, ok)
+	}
+	if _, ok := r.Match("training epoch <NUM> complete"); ok {
+		t.Fatal("expected an unrelated line not to match")
+	}
+
+	// A non-empty registry ignores the file, so a disabled pattern stays off.
+	if err := database.SetPatternEnabled(p.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if r, err = Load(); err != nil || r.Len() != 0 {
+		t.Fatalf("expected no active patterns, got %d, %v", r.Len(), err)
+	}
+}
+
+func TestLearnCountsNearDuplicatesAsHits(t *testing.T) {
+	useTempDB(t)
+
+	r := NewRegistry(nil)
+	if err := r.Learn("tool call failed: search returned <NUM> results", "python3 agent.py", database.PatternActionAlert, 0); err != nil {
+		t.Fatalf("Learn: %v", err)
+	}
+	// Learned in this run: deduplicated against, but not matched until the next.
+	if err := r.Learn("tool call failed: search returned <NUM> result", "python3 agent.py", database.PatternActionKill, 0); err != nil {
+		t.Fatalf("Learn: %v", err)
+	}
+	if _, ok := r.Match("tool call failed: search returned <NUM> results"); ok || r.Len() != 0 {
+		t.Fatalf("expected the learned pattern to stay inactive for this run, got %d active", r.Len())
+	}
+	all, err := database.ListPatterns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].HitCount != 2 || all[0].Action != database.PatternActionAlert || all[0].SourceCommand != "python3 agent.py" {
+		t.Fatalf("expected one alert pattern with 2 hits, got %+v", all)
+	}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, all); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	round, err := Parse(buf.Bytes())
+	if err != nil || len(round) != 1 || round[0].ID != 0 || round[0].Pattern != all[0].Pattern || round[0].HitCount != 2 {
+		t.Fatalf("expected the export to parse back without IDs, got %+v, %v", round, err)
+	}
+}
@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+)
+
+// Adaptive decider defaults.
+const (
+	DefaultZScore  = 3.0
+	DefaultMinRuns = 3
+)
+
+// AdaptiveDecider judges a run against what the same command normally does
+// instead of fixed profile numbers. Once a metric has MinRuns of history:
+//
+//   - the CPU limit becomes the learned mean plus ZScore standard deviations,
+//     held for CPUWindow, and feeds the usual CPU-and-loop kill rule;
+//   - the entropy floor becomes the learned mean minus ZScore deviations;
+//   - memory and output rate far above normal raise an alert.
+//
+// The other limits (memory cap, deadlines, hang, disk, repetition) apply as
+// in ThresholdDecider, and metrics without enough history fall back to it.
+type AdaptiveDecider struct {
+	Baseline Baseline
+	ZScore   float64
+	MinRuns  int
+
+	cpuOverSince time.Duration // Runtime when CPU went above the learned limit; -1 when below
+}
+
+// NewAdaptiveDecider creates a decider for a command's learned baseline.
+// Non-positive zScore or minRuns take the defaults.
+func NewAdaptiveDecider(b Baseline, zScore float64, minRuns int) *AdaptiveDecider {
+	if zScore <= 0 {
+		zScore = DefaultZScore
+	}
+	if minRuns <= 0 {
+		minRuns = DefaultMinRuns
+	}
+	return &AdaptiveDecider{Baseline: b, ZScore: zScore, MinRuns: minRuns, cpuOverSince: -1}
+}
+
+// Learned reports whether m has enough history to replace static limits.
+func (d *AdaptiveDecider) Learned(m Metric) bool {
+	return d.Baseline[m].Count >= d.MinRuns
+}
+
+// limits returns the learned mean and the spread ZScore deviations away.
+func (d *AdaptiveDecider) limits(m Metric) (mean, spread float64) {
+	s := d.Baseline[m]
+	sd := s.StdDev()
+	if floor := minSpread(m, s.Mean); sd < floor {
+		sd = floor
+	}
+	return s.Mean, d.ZScore * sd
+}
+
+// zScore is how many (floored) deviations x lies from the learned mean.
+func (d *AdaptiveDecider) zScore(m Metric, x float64) float64 {
+	mean, spread := d.limits(m)
+	return (x - mean) / (spread / d.ZScore)
+}
+
+func (d *AdaptiveDecider) Evaluate(t Telemetry, p Policy) Decision {
+	b := staticBreaches(t, p)
+	// A zero CPU limit means the caller only checks liveness limits on
+	// this tick (see livenessOnly in cmd).
+	if p.MaxCPUPercent <= 0 {
+		d.cpuOverSince = -1
+		return decide(t, p, b)
+	}
+
+	if d.Learned(MetricCPU) {
+		mean, spread := d.limits(MetricCPU)
+		limit := mean + spread
+		if t.CPUPercent <= limit {
+			d.cpuOverSince = -1
+		} else if d.cpuOverSince < 0 {
+			d.cpuOverSince = t.Runtime
+		}
+		overFor := time.Duration(0)
+		if d.cpuOverSince >= 0 {
+			overFor = t.Runtime - d.cpuOverSince
+		}
+		b.cpu = d.cpuOverSince >= 0 && (p.CPUWindow <= 0 || overFor >= p.CPUWindow)
+		b.cpuReason = fmt.Sprintf("CPU %.0f%% above learned %.0f%% (z=%.1f)", t.CPUPercent, mean, d.zScore(MetricCPU, t.CPUPercent))
+		if p.CPUWindow > 0 {
+			b.cpuReason += fmt.Sprintf(" for %ds", int(p.CPUWindow.Seconds()))
+		}
+	}
+	if p.MinLogEntropy > 0 && d.Learned(MetricEntropy) {
+		mean, spread := d.limits(MetricEntropy)
+		b.entropy = t.LogEntropy < mean-spread
+		b.entropyReason = fmt.Sprintf("log entropy %.2f below learned %.2f (z=%.1f)", t.LogEntropy, mean, d.zScore(MetricEntropy, t.LogEntropy))
+	}
+	if d.Learned(MetricMemory) {
+		if mean, spread := d.limits(MetricMemory); t.MemoryMB > mean+spread {
+			b.deviations = append(b.deviations, fmt.Sprintf("memory %.0fMB above learned %.0fMB (z=%.1f)", t.MemoryMB, mean, d.zScore(MetricMemory, t.MemoryMB)))
+		}
+	}
+	if d.Learned(MetricOutputRate) {
+		if mean, spread := d.limits(MetricOutputRate); t.LinesPerSec > mean+spread {
+			b.deviations = append(b.deviations, fmt.Sprintf("output %.0f lines/s above learned %.1f (z=%.1f)", t.LinesPerSec, mean, d.zScore(MetricOutputRate, t.LinesPerSec)))
+		}
+	}
+	return decide(t, p, b)
+}
@@ -0,0 +1,105 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func learnedBaseline() Baseline {
+	return Baseline{
+		MetricCPU:        {Count: 5, Mean: 92, Variance: 4},
+		MetricMemory:     {Count: 5, Mean: 400, Variance: 400},
+		MetricEntropy:    {Count: 5, Mean: 0.7, Variance: 0.0025},
+		MetricOutputRate: {Count: 5, Mean: 20, Variance: 16},
+	}
+}
+
+func TestAdaptiveDeciderFallsBackWithoutHistory(t *testing.T) {
+	p := Policy{MaxCPUPercent: 60, CPUWindow: 10 * time.Second, MinLogEntropy: 0.2, MaxLogRepetition: 0.8}
+	tel := Telemetry{CPUPercent: 95, CPUOverFor: 30 * time.Second, LogRepetition: 0.9, LogEntropy: 0.1, Runtime: time.Minute}
+
+	d := NewAdaptiveDecider(Baseline{MetricCPU: {Count: 2, Mean: 95, Variance: 1}}, 0, 0)
+	if d.ZScore != DefaultZScore || d.MinRuns != DefaultMinRuns {
+		t.Fatalf("expected defaults, got z=%.1f min-runs=%d", d.ZScore, d.MinRuns)
+	}
+	got, want := d.Evaluate(tel, p), NewThresholdDecider().Evaluate(tel, p)
+	if got != want {
+		t.Fatalf("expected static decision %+v, got %+v", want, got)
+	}
+}
+
+func TestAdaptiveDeciderLearnsBusyCommand(t *testing.T) {
+	p := Policy{MaxCPUPercent: 60, CPUWindow: 10 * time.Second, MinLogEntropy: 0.2, MaxLogRepetition: 0.8}
+	d := NewAdaptiveDecider(learnedBaseline(), 3, 3)
+
+	// 95% CPU is normal for this job, even though it is above max-cpu.
+	normal := Telemetry{CPUPercent: 95, CPUOverFor: time.Minute, LogEntropy: 0.68, MemoryMB: 420, LinesPerSec: 22, Runtime: time.Minute}
+	if out := d.Evaluate(normal, p); out.Action != ActionContinue {
+		t.Fatalf("expected CONTINUE for a normal run, got %s: %s", out.Action.String(), out.Reason)
+	}
+	if out := (ThresholdDecider{}).Evaluate(normal, p); out.Action != ActionAlert {
+		t.Fatalf("static thresholds should alert on the same sample, got %s", out.Action.String())
+	}
+
+	// Output collapsing into a loop is judged against the learned entropy;
+	// 0.5 is well above the static 0.2 floor but far below this job's norm.
+	looping := normal
+	looping.LogEntropy = 0.5
+	out := d.Evaluate(looping, p)
+	if out.Action != ActionAlert || !strings.Contains(out.Reason, "log entropy 0.50 below learned 0.70 (z=-4.0)") {
+		t.Fatalf("expected an entropy alert, got %s: %s", out.Action.String(), out.Reason)
+	}
+
+	// Memory and output far above normal alert without killing.
+	flood := normal
+	flood.MemoryMB, flood.LinesPerSec = 700, 90
+	out = d.Evaluate(flood, p)
+	if out.Action != ActionAlert {
+		t.Fatalf("expected an alert, got %s", out.Action.String())
+	}
+	for _, want := range []string{"memory 700MB above learned 400MB (z=7.5)", "output 90 lines/s above learned 20.0 (z=17.5)"} {
+		if !strings.Contains(out.Reason, want) {
+			t.Fatalf("expected %q in %q", want, out.Reason)
+		}
+	}
+}
+
+func TestAdaptiveDeciderHoldsCPUDeviationForWindow(t *testing.T) {
+	p := Policy{MaxCPUPercent: 90, CPUWindow: 10 * time.Second, MinLogEntropy: 0.2, MaxLogRepetition: 0.8}
+	b := learnedBaseline()
+	b[MetricCPU] = Stat{Count: 5, Mean: 10, Variance: 4} // Floors to a spread of 5 points
+	d := NewAdaptiveDecider(b, 3, 3)
+
+	tel := Telemetry{CPUPercent: 40, LogRepetition: 0.9, LogEntropy: 0.7, Runtime: time.Minute}
+	if out := d.Evaluate(tel, p); out.Action != ActionAlert || strings.Contains(out.Reason, "CPU") {
+		t.Fatalf("expected only the repetition alert before the CPU window, got %s: %s", out.Action.String(), out.Reason)
+	}
+	// Held past CPUWindow, the deviation joins the loop signal and kills.
+	tel.Runtime += 5 * time.Second
+	d.Evaluate(tel, p)
+	tel.Runtime += 6 * time.Second
+	out := d.Evaluate(tel, p)
+	if out.Action != ActionKill {
+		t.Fatalf("expected KILL for CPU far above baseline plus a loop, got %s: %s", out.Action.String(), out.Reason)
+	}
+	if !strings.HasPrefix(out.Reason, "CPU 40% above learned 10% (z=6.0) for 10s AND log repetition exceeded 0.80") {
+		t.Fatalf("unexpected reason %q", out.Reason)
+	}
+
+	// Dropping back under the learned limit restarts the window.
+	tel.CPUPercent = 20
+	tel.Runtime += time.Second
+	d.Evaluate(tel, p)
+	tel.CPUPercent = 40
+	tel.Runtime += time.Second
+	if out := d.Evaluate(tel, p); out.Action == ActionKill {
+		t.Fatalf("expected the CPU window to restart, got %s", out.Reason)
+	}
+
+	// Liveness ticks skip learned checks entirely.
+	live := Policy{MaxRuntime: time.Hour}
+	if out := d.Evaluate(Telemetry{CPUPercent: 99, MemoryMB: 5000, Runtime: 2 * time.Minute}, live); out.Action != ActionContinue {
+		t.Fatalf("expected CONTINUE on a liveness-only tick, got %s: %s", out.Action.String(), out.Reason)
+	}
+}
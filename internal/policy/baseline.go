@@ -0,0 +1,120 @@
+package policy
+
+import "math"
+
+// Metric names a telemetry value that baselines are learned for.
+type Metric string
+
+const (
+	MetricCPU        Metric = "cpu_percent"
+	MetricMemory     Metric = "memory_mb"
+	MetricEntropy    Metric = "log_entropy"
+	MetricOutputRate Metric = "lines_per_sec"
+)
+
+// Metrics lists every learned metric.
+var Metrics = []Metric{MetricCPU, MetricMemory, MetricEntropy, MetricOutputRate}
+
+// BaselineRuns is how many runs a baseline remembers: each new run carries
+// at least 1/BaselineRuns of the weight, so old behaviour fades out.
+const BaselineRuns = 20
+
+// MinRunSamples is how many samples of a metric a run needs before it is
+// merged; a run that exits after a second says little about normal load.
+const MinRunSamples = 5
+
+// Stat is a running mean and variance. Within a run Count is the number of
+// samples; in a baseline it is the number of runs merged.
+type Stat struct {
+	Count    int
+	Mean     float64
+	Variance float64
+}
+
+// Add folds one sample in (Welford's algorithm, population variance).
+func (s *Stat) Add(x float64) {
+	s.Count++
+	delta := x - s.Mean
+	s.Mean += delta / float64(s.Count)
+	s.Variance += (delta*(x-s.Mean) - s.Variance) / float64(s.Count)
+}
+
+// StdDev returns the standard deviation.
+func (s Stat) StdDev() float64 {
+	return math.Sqrt(math.Max(s.Variance, 0))
+}
+
+// Merge folds a finished run's per-sample statistics into a baseline. The
+// run counts as one observation with weight 1/min(runs, BaselineRuns); the
+// variance keeps both the spread within runs and the drift between them.
+func (s Stat) Merge(run Stat) Stat {
+	if run.Count == 0 {
+		return s
+	}
+	n := s.Count + 1
+	alpha := 1 / math.Min(float64(n), BaselineRuns)
+	delta := run.Mean - s.Mean
+	return Stat{
+		Count:    n,
+		Mean:     s.Mean + alpha*delta,
+		Variance: (1-alpha)*(s.Variance+alpha*delta*delta) + alpha*run.Variance,
+	}
+}
+
+// Baseline is what a command normally looks like, learned across runs.
+type Baseline map[Metric]Stat
+
+// RunStats accumulates one run's telemetry for merging into a Baseline.
+type RunStats map[Metric]*Stat
+
+// Observe records the sample's resource metrics. withOutput adds the
+// output metrics, which only mean something once the log window is full.
+func (r RunStats) Observe(t Telemetry, withOutput bool) {
+	r.add(MetricCPU, t.CPUPercent)
+	r.add(MetricMemory, t.MemoryMB)
+	r.add(MetricOutputRate, t.LinesPerSec)
+	if withOutput {
+		r.add(MetricEntropy, t.LogEntropy)
+	}
+}
+
+func (r RunStats) add(m Metric, x float64) {
+	s, ok := r[m]
+	if !ok {
+		s = &Stat{}
+		r[m] = s
+	}
+	s.Add(x)
+}
+
+// Merge returns b with the run folded in, skipping metrics with fewer
+// than MinRunSamples samples.
+func (b Baseline) Merge(run RunStats) Baseline {
+	out := make(Baseline, len(Metrics))
+	for m, s := range b {
+		out[m] = s
+	}
+	for m, s := range run {
+		if s.Count >= MinRunSamples {
+			out[m] = out[m].Merge(*s)
+		}
+	}
+	return out
+}
+
+// minSpread keeps a near-constant baseline from flagging noise: a job that
+// always idles at 1% CPU should not alert at 3%.
+func minSpread(m Metric, mean float64) float64 {
+	switch m {
+	case MetricCPU:
+		return 5
+	case MetricMemory:
+		return math.Max(0.1*mean, 16)
+	case MetricEntropy:
+		return 0.05
+	case MetricOutputRate:
+		return math.Max(0.1*mean, 1)
+	default:
+		return 0
+	}
+}
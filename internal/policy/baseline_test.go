@@ -0,0 +1,62 @@
+package policy
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestStatAddMatchesBatchMoments(t *testing.T) {
+	xs := []float64{10, 12, 9, 30, 14, 11}
+	var s Stat
+	for _, x := range xs {
+		s.Add(x)
+	}
+	mean, variance := 0.0, 0.0
+	for _, x := range xs {
+		mean += x / float64(len(xs))
+	}
+	for _, x := range xs {
+		variance += (x - mean) * (x - mean) / float64(len(xs))
+	}
+	if s.Count != len(xs) || !near(s.Mean, mean) || !near(s.Variance, variance) {
+		t.Fatalf("got %+v, want mean %.4f variance %.4f", s, mean, variance)
+	}
+}
+
+func TestBaselineMergeWeighsRunsAndFades(t *testing.T) {
+	run := func(m Metric, xs ...float64) RunStats {
+		r := make(RunStats)
+		for _, x := range xs {
+			r.add(m, x)
+		}
+		return r
+	}
+
+	// The first run is the baseline.
+	b := Baseline{}.Merge(run(MetricCPU, 40, 40, 50, 50, 45))
+	if s := b[MetricCPU]; s.Count != 1 || !near(s.Mean, 45) || !near(s.Variance, 20) {
+		t.Fatalf("unexpected first-run baseline %+v", s)
+	}
+	// A second, identical-spread run at a different level averages the
+	// means and adds the drift between them to the variance.
+	b = b.Merge(run(MetricCPU, 60, 60, 70, 70, 65))
+	if s := b[MetricCPU]; s.Count != 2 || !near(s.Mean, 55) || !near(s.Variance, 20+100) {
+		t.Fatalf("unexpected two-run baseline %+v", s)
+	}
+
+	// Runs with too few samples are ignored, and other metrics are kept.
+	b = b.Merge(run(MetricMemory, 100, 100))
+	if _, ok := b[MetricMemory]; ok || b[MetricCPU].Count != 2 {
+		t.Fatalf("expected a short run to be skipped, got %+v", b)
+	}
+
+	// Past BaselineRuns, old history fades: a level shift takes over.
+	for i := 0; i < 5*BaselineRuns; i++ {
+		b = b.Merge(run(MetricCPU, 90, 90, 90, 90, 90))
+	}
+	if s := b[MetricCPU]; s.Count != 2+5*BaselineRuns || math.Abs(s.Mean-90) > 0.5 || s.StdDev() > 2 {
+		t.Fatalf("expected the baseline to follow the new level, got %+v", s)
+	}
+}
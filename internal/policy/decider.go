@@ -15,6 +15,9 @@ const (
 	ActionKill
 	ActionRestart
 	ActionLogOnly
+	// ActionPause freezes the process group so an operator can inspect it
+	// before deciding to resume or kill.
+	ActionPause
 )
 
 func (a Action) String() string {
@@ -29,20 +32,61 @@ func (a Action) String() string {
 		return "RESTART"
 	case ActionLogOnly:
 		return "LOG_ONLY"
+	case ActionPause:
+		return "PAUSE"
 	default:
 		return "UNKNOWN"
 	}
 }
 
 type Telemetry struct {
-	CPUPercent    float64
-	CPUOverFor    time.Duration
-	MemoryMB      float64
-	LogRepetition float64 // 0..1 where 1 means highly repetitive
-	LogEntropy    float64 // 0..1 where 0 means repetitive
-	RawDiversity  float64 // 0..1 where 1 means highly diverse raw lines
-	ProgressLike  bool    // true when output suggests forward progress, not stagnation
-	RolloutKey    string  // Stable key for deterministic canary sampling
+	CPUPercent       float64
+	CPUOverFor       time.Duration
+	MemoryMB         float64
+	LogRepetition    float64        // 0..1 where 1 means highly repetitive
+	LogEntropy       float64        // 0..1 where 0 means repetitive
+	RawDiversity     float64        // 0..1 where 1 means highly diverse raw lines
+	ProgressLike     bool           // true when output suggests forward progress, not stagnation
+	RolloutKey       string         // Stable key for deterministic canary sampling
+	Runtime          time.Duration  // Wall-clock time since the run started
+	SilentFor        time.Duration  // Time since the process last wrote any output
+	LoopPeriod       int            // Lines per repeating block when output cycles; 0 when it does not
+	LoopTool         string         // Tool whose identical calls or errors repeat in structured output
+	LoopErrors       bool           // The structured loop is a repeated error rather than a repeated call
+	RepeatedTrace    int            // Times the same stack trace recurred; 0 when none did
+	StuckFor         time.Duration  // Time without CPU, output, I/O or context-switch progress
+	StuckState       string         // Where the stuck tree waits, e.g. "python3 sleeping in futex_wait_queue"
+	WriteBytesPerSec float64        // Bytes the tree sent to storage per second, averaged over a few seconds
+	WriteBytes       uint64         // Bytes the tree sent to storage since the attempt started
+	LinesPerSec      float64        // Output lines per second over the last few seconds
+	Rules            []RuleMatch    // User-defined rules that fired since the previous sample
+	KnownPatterns    []PatternMatch // Registry patterns with an alert or kill action matched since the previous sample
+	Tokens           int64          // Tokens counted in the run's output so far, across restarts
+	CostUSD          float64        // Estimated cost of Tokens at the model's price
+}
+
+// Actions a user-defined rule can ask for.
+const (
+	RuleActionAlert = "alert"
+	RuleActionPause = "pause"
+	RuleActionKill  = "kill"
+)
+
+// RuleMatch is a user-defined detection rule that reached its hit count.
+type RuleMatch struct {
+	Name     string
+	Severity string
+	Action   string // RuleActionAlert, RuleActionPause or RuleActionKill
+	Hits     int
+	Window   time.Duration
+}
+
+// PatternMatch is output matching a known bad pattern from the pattern
+// registry.
+type PatternMatch struct {
+	ID      int64
+	Pattern string
+	Kill    bool // The pattern's action is kill; alert otherwise
 }
 
 type RolloutMode string
@@ -54,15 +98,25 @@ const (
 )
 
 type Policy struct {
-	MaxCPUPercent    float64
-	CPUWindow        time.Duration
-	MaxMemoryMB      float64
-	MaxLogRepetition float64
-	MinLogEntropy    float64
-	RestartOnBreach  bool
-	ShadowMode       bool
-	RolloutMode      RolloutMode
-	CanaryPercent    int // 0..100: percent of sampled runs where destructive action is enforced in canary mode
+	MaxCPUPercent       float64
+	CPUWindow           time.Duration
+	MaxMemoryMB         float64
+	MaxLogRepetition    float64
+	MinLogEntropy       float64
+	RestartOnBreach     bool
+	PauseOnBreach       bool // Takes precedence over RestartOnBreach
+	ShadowMode          bool
+	RolloutMode         RolloutMode
+	CanaryPercent       int           // 0..100: percent of sampled runs where destructive action is enforced in canary mode
+	MaxRuntime          time.Duration // Wall-clock deadline for the whole run; 0 disables
+	MaxSilence          time.Duration // Longest allowed gap between outputs; 0 disables
+	MaxStuck            time.Duration // Longest allowed time without any progress (see Telemetry.StuckFor); 0 disables
+	MaxWriteBytesPerSec float64       // Highest allowed storage write rate; 0 disables
+	MaxWriteBytes       uint64        // Most bytes an attempt may write to storage; 0 disables
+	MaxTokens           int64         // Most tokens the whole run may produce; 0 disables
+	MaxCostUSD          float64       // Most the whole run may spend, in dollars; 0 disables
+	BudgetAlertPercent  int           // Alert once either budget is this percent used; 0 disables the alert
+	PauseOnBudget       bool          // Freeze the run instead of killing it when a budget runs out
 
 	// Metadata fields used by callers when recording shadow-mode evidence.
 	DryRunEventType   string
@@ -70,10 +124,27 @@ type Policy struct {
 	DryRunEventPrefix string
 }
 
+// Breach names for limits that are reported as their own incident type
+// rather than as a detected loop.
+const (
+	BreachDeadline      = "DEADLINE_EXCEEDED"
+	BreachOutputStalled = "OUTPUT_STALLED"
+	BreachHung          = "PROCESS_HUNG"
+	BreachDiskWrites    = "DISK_WRITE_LIMIT"
+	BreachRule          = "RULE_MATCHED"
+	BreachBudget        = "BUDGET_EXCEEDED"
+	BreachKnownPattern  = "KNOWN_PATTERN"
+)
+
+// limitWarnFraction is how far into MaxRuntime or MaxSilence a run may get
+// before the decider raises an alert ahead of the destructive action.
+const limitWarnFraction = 0.8
+
 type Decision struct {
 	Action         Action
 	IntendedAction Action
 	Reason         string
+	Breach         string // BreachDeadline, BreachBudget, BreachHung, BreachOutputStalled, BreachDiskWrites, BreachRule or BreachKnownPattern when one of them drove the decision
 }
 
 type Decider interface {
@@ -87,31 +158,165 @@ func NewThresholdDecider() Decider {
 }
 
 func (ThresholdDecider) Evaluate(t Telemetry, p Policy) Decision {
-	cpuBreach := p.MaxCPUPercent > 0 && t.CPUPercent > p.MaxCPUPercent
-	if cpuBreach && p.CPUWindow > 0 && t.CPUOverFor < p.CPUWindow {
-		cpuBreach = false
+	return decide(t, p, staticBreaches(t, p))
+}
+
+// breaches are the limits one telemetry sample crossed. Deciders fill them
+// in and decide turns them into an action.
+type breaches struct {
+	cpu, mem, writeRate, writeTotal, repetition, entropy           bool
+	deadline, deadlineNear, silence, silenceNear, stuck, stuckNear bool
+	tokens, tokensNear, cost, costNear                             bool
+	ruleKill, rulePause, patternKill                               bool
+
+	cpuReason, entropyReason string   // Replace the static wording when set
+	deviations               []string // Alert-only findings
+	ruleReasons              []string // One per rule or known pattern that fired
+}
+
+// staticBreaches checks t against the fixed limits in p.
+func staticBreaches(t Telemetry, p Policy) breaches {
+	b := breaches{
+		cpu:        p.MaxCPUPercent > 0 && t.CPUPercent > p.MaxCPUPercent,
+		mem:        p.MaxMemoryMB > 0 && t.MemoryMB > p.MaxMemoryMB,
+		writeRate:  p.MaxWriteBytesPerSec > 0 && t.WriteBytesPerSec > p.MaxWriteBytesPerSec,
+		writeTotal: p.MaxWriteBytes > 0 && t.WriteBytes > p.MaxWriteBytes,
+		repetition: p.MaxLogRepetition > 0 && t.LogRepetition > p.MaxLogRepetition,
+		entropy:    p.MinLogEntropy > 0 && t.LogEntropy < p.MinLogEntropy,
+		deadline:   p.MaxRuntime > 0 && t.Runtime >= p.MaxRuntime,
+		silence:    p.MaxSilence > 0 && t.SilentFor >= p.MaxSilence,
+		stuck:      p.MaxStuck > 0 && t.StuckFor >= p.MaxStuck,
 	}
-	memBreach := p.MaxMemoryMB > 0 && t.MemoryMB > p.MaxMemoryMB
-	repetitionBreach := p.MaxLogRepetition > 0 && t.LogRepetition > p.MaxLogRepetition
-	entropyBreach := p.MinLogEntropy > 0 && t.LogEntropy < p.MinLogEntropy
+	if b.cpu && p.CPUWindow > 0 && t.CPUOverFor < p.CPUWindow {
+		b.cpu = false
+	}
+	b.deadlineNear = !b.deadline && nearLimit(t.Runtime, p.MaxRuntime)
+	b.silenceNear = !b.silence && nearLimit(t.SilentFor, p.MaxSilence)
+	b.stuckNear = !b.stuck && nearLimit(t.StuckFor, p.MaxStuck)
+	b.tokens = p.MaxTokens > 0 && t.Tokens >= p.MaxTokens
+	b.cost = p.MaxCostUSD > 0 && t.CostUSD >= p.MaxCostUSD
+	b.tokensNear = !b.tokens && nearBudget(float64(t.Tokens), float64(p.MaxTokens), p.BudgetAlertPercent)
+	b.costNear = !b.cost && nearBudget(t.CostUSD, p.MaxCostUSD, p.BudgetAlertPercent)
+	for _, r := range t.Rules {
+		b.ruleReasons = append(b.ruleReasons, ruleReason(r))
+		switch r.Action {
+		case RuleActionKill:
+			b.ruleKill = true
+		case RuleActionPause:
+			b.rulePause = true
+		}
+	}
+	for _, m := range t.KnownPatterns {
+		b.ruleReasons = append(b.ruleReasons, fmt.Sprintf("output matches known pattern #%d %q", m.ID, truncatePattern(m.Pattern)))
+		b.patternKill = b.patternKill || m.Kill
+	}
+	return b
+}
 
-	reasons := make([]string, 0, 4)
+// truncatePattern shortens a known pattern for the decision reason.
+func truncatePattern(s string) string {
+	const max = 60
+	if r := []rune(s); len(r) > max {
+		return string(r[:max]) + "..."
+	}
+	return s
+}
+
+func ruleReason(r RuleMatch) string {
+	times := fmt.Sprintf("%d times", r.Hits)
+	if r.Hits == 1 {
+		times = "once"
+	}
+	if r.Window > 0 && r.Hits > 1 {
+		times += " in " + r.Window.String()
+	}
+	return fmt.Sprintf("rule %q matched %s (%s)", r.Name, times, r.Severity)
+}
+
+// decide turns the breaches into a decision, applying the rollout mode.
+func decide(t Telemetry, p Policy, b breaches) Decision {
+	cpuBreach, memBreach := b.cpu, b.mem
+	writeRateBreach, writeTotalBreach := b.writeRate, b.writeTotal
+	repetitionBreach, entropyBreach := b.repetition, b.entropy
+	deadlineBreach, silenceBreach, stuckBreach := b.deadline, b.silence, b.stuck
+	deadlineNear, silenceNear, stuckNear := b.deadlineNear, b.silenceNear, b.stuckNear
+
+	reasons := make([]string, 0, 6)
 	if cpuBreach {
-		if p.CPUWindow > 0 {
+		switch {
+		case b.cpuReason != "":
+			reasons = append(reasons, b.cpuReason)
+		case p.CPUWindow > 0:
 			reasons = append(reasons, fmt.Sprintf("CPU exceeded %.0f%% for %ds", p.MaxCPUPercent, int(p.CPUWindow.Seconds())))
-		} else {
+		default:
 			reasons = append(reasons, fmt.Sprintf("CPU exceeded %.0f%%", p.MaxCPUPercent))
 		}
 	}
 	if memBreach {
 		reasons = append(reasons, fmt.Sprintf("memory exceeded %.0fMB", p.MaxMemoryMB))
 	}
+	if writeRateBreach {
+		reasons = append(reasons, fmt.Sprintf("disk writes exceeded %s/s (%s/s)", formatBytes(p.MaxWriteBytesPerSec), formatBytes(t.WriteBytesPerSec)))
+	}
+	if writeTotalBreach {
+		reasons = append(reasons, fmt.Sprintf("wrote more than %s to disk (%s)", formatBytes(float64(p.MaxWriteBytes)), formatBytes(float64(t.WriteBytes))))
+	}
 	if repetitionBreach {
-		reasons = append(reasons, fmt.Sprintf("log repetition exceeded %.2f", p.MaxLogRepetition))
+		switch {
+		case t.LoopErrors && t.LoopTool != "":
+			reasons = append(reasons, fmt.Sprintf("log repetition exceeded %.2f (tool %q keeps failing with the same error)", p.MaxLogRepetition, t.LoopTool))
+		case t.LoopErrors:
+			reasons = append(reasons, fmt.Sprintf("log repetition exceeded %.2f (same error repeating)", p.MaxLogRepetition))
+		case t.LoopTool != "":
+			reasons = append(reasons, fmt.Sprintf("log repetition exceeded %.2f (tool %q called repeatedly with identical args)", p.MaxLogRepetition, t.LoopTool))
+		case t.RepeatedTrace > 0:
+			reasons = append(reasons, fmt.Sprintf("log repetition exceeded %.2f (same stack trace %d times)", p.MaxLogRepetition, t.RepeatedTrace))
+		case t.LoopPeriod > 1:
+			reasons = append(reasons, fmt.Sprintf("log repetition exceeded %.2f (output cycles every %d lines)", p.MaxLogRepetition, t.LoopPeriod))
+		default:
+			reasons = append(reasons, fmt.Sprintf("log repetition exceeded %.2f", p.MaxLogRepetition))
+		}
 	}
 	if entropyBreach {
-		reasons = append(reasons, fmt.Sprintf("log entropy dropped below %.2f", p.MinLogEntropy))
+		if b.entropyReason != "" {
+			reasons = append(reasons, b.entropyReason)
+		} else {
+			reasons = append(reasons, fmt.Sprintf("log entropy dropped below %.2f", p.MinLogEntropy))
+		}
+	}
+	if deadlineBreach {
+		reasons = append(reasons, fmt.Sprintf("runtime exceeded %s", p.MaxRuntime))
+	} else if deadlineNear {
+		reasons = append(reasons, fmt.Sprintf("runtime %s approaching %s limit", t.Runtime.Truncate(time.Second), p.MaxRuntime))
+	}
+	if silenceBreach {
+		reasons = append(reasons, fmt.Sprintf("no output for %s", p.MaxSilence))
+	} else if silenceNear {
+		reasons = append(reasons, fmt.Sprintf("no output for %s of %s allowed", t.SilentFor.Truncate(time.Second), p.MaxSilence))
+	}
+	if stuckBreach || stuckNear {
+		where := ""
+		if t.StuckState != "" {
+			where = " (" + t.StuckState + ")"
+		}
+		if stuckBreach {
+			reasons = append(reasons, fmt.Sprintf("no CPU, output, I/O or context-switch progress for %s%s", p.MaxStuck, where))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("no progress for %s of %s allowed%s", t.StuckFor.Truncate(time.Second), p.MaxStuck, where))
+		}
+	}
+	if b.tokens {
+		reasons = append(reasons, fmt.Sprintf("token budget exhausted: %d of %d tokens", t.Tokens, p.MaxTokens))
+	} else if b.tokensNear {
+		reasons = append(reasons, fmt.Sprintf("used %d of %d token budget (%.0f%%)", t.Tokens, p.MaxTokens, 100*float64(t.Tokens)/float64(p.MaxTokens)))
 	}
+	if b.cost {
+		reasons = append(reasons, fmt.Sprintf("spend $%.2f exceeded $%.2f budget", t.CostUSD, p.MaxCostUSD))
+	} else if b.costNear {
+		reasons = append(reasons, fmt.Sprintf("spent $%.2f of $%.2f budget (%.0f%%)", t.CostUSD, p.MaxCostUSD, 100*t.CostUSD/p.MaxCostUSD))
+	}
+	reasons = append(reasons, b.ruleReasons...)
+	reasons = append(reasons, b.deviations...)
 
 	if len(reasons) == 0 {
 		return Decision{
@@ -123,11 +328,37 @@ func (ThresholdDecider) Evaluate(t Telemetry, p Policy) Decision {
 
 	potentialRuntimeRisk := cpuBreach && (repetitionBreach || entropyBreach)
 	progressGuard := potentialRuntimeRisk && t.ProgressLike && t.RawDiversity >= 0.85
-	highRisk := memBreach || (potentialRuntimeRisk && !progressGuard)
+	diskBreach := writeRateBreach || writeTotalBreach
+	budgetBreach := b.tokens || b.cost
+	limitRisk := memBreach || diskBreach || silenceBreach || stuckBreach || deadlineBreach || (potentialRuntimeRisk && !progressGuard)
+	killRisk := limitRisk || b.ruleKill || b.patternKill || (budgetBreach && !p.PauseOnBudget)
+	pauseRisk := b.rulePause || (budgetBreach && p.PauseOnBudget)
+	highRisk := killRisk || pauseRisk
+
+	breach := ""
+	if deadlineBreach {
+		breach = BreachDeadline
+	} else if budgetBreach {
+		breach = BreachBudget
+	} else if stuckBreach {
+		breach = BreachHung
+	} else if silenceBreach {
+		breach = BreachOutputStalled
+	} else if diskBreach {
+		breach = BreachDiskWrites
+	} else if (b.ruleKill || b.rulePause) && !limitRisk {
+		breach = BreachRule
+	} else if b.patternKill && !limitRisk {
+		breach = BreachKnownPattern
+	}
 
 	action := ActionAlert
 	if highRisk {
-		if p.RestartOnBreach {
+		// A pause rule or budget freezes the run unless a kill is also called for.
+		if p.PauseOnBreach || (pauseRisk && !killRisk) {
+			action = ActionPause
+		} else if p.RestartOnBreach && !deadlineBreach && !budgetBreach {
+			// A restarted run would still be past its deadline or budget.
 			action = ActionRestart
 		} else {
 			action = ActionKill
@@ -139,13 +370,14 @@ func (ThresholdDecider) Evaluate(t Telemetry, p Policy) Decision {
 	}
 
 	reason := strings.Join(reasons, " AND ")
-	if action == ActionKill || action == ActionRestart {
+	if action == ActionKill || action == ActionRestart || action == ActionPause {
 		switch normalizeRolloutMode(p.RolloutMode, p.ShadowMode) {
 		case RolloutShadow:
 			return Decision{
 				Action:         ActionLogOnly,
 				IntendedAction: action,
 				Reason:         fmt.Sprintf("Shadow mode: would %s. %s", action.String(), reason),
+				Breach:         breach,
 			}
 		case RolloutCanary:
 			percent := clampCanaryPercent(p.CanaryPercent)
@@ -155,12 +387,14 @@ func (ThresholdDecider) Evaluate(t Telemetry, p Policy) Decision {
 					Action:         ActionLogOnly,
 					IntendedAction: action,
 					Reason:         fmt.Sprintf("Canary mode: log-only (%d%%, bucket=%d) would %s. %s", percent, bucket, action.String(), reason),
+					Breach:         breach,
 				}
 			}
 			return Decision{
 				Action:         action,
 				IntendedAction: action,
 				Reason:         fmt.Sprintf("Canary mode: enforce (%d%%, bucket=%d). %s", percent, bucket, reason),
+				Breach:         breach,
 			}
 		}
 	}
@@ -169,9 +403,34 @@ func (ThresholdDecider) Evaluate(t Telemetry, p Policy) Decision {
 		Action:         action,
 		IntendedAction: action,
 		Reason:         reason,
+		Breach:         breach,
 	}
 }
 
+// formatBytes renders a byte count with a binary unit, e.g. "64.0MB".
+func formatBytes(n float64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%.0fB", n)
+	}
+	i := 0
+	for n >= unit && i < 4 {
+		n /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f%cB", n, "KMGT"[i-1])
+}
+
+// nearLimit reports whether v has reached limitWarnFraction of a non-zero limit.
+func nearLimit(v, limit time.Duration) bool {
+	return limit > 0 && float64(v) >= float64(limit)*limitWarnFraction
+}
+
+// nearBudget reports whether v has reached percent of a non-zero budget.
+func nearBudget(v, budget float64, percent int) bool {
+	return budget > 0 && percent > 0 && v >= budget*float64(percent)/100
+}
+
 func normalizeRolloutMode(mode RolloutMode, shadowMode bool) RolloutMode {
 	switch RolloutMode(strings.ToLower(strings.TrimSpace(string(mode)))) {
 	case RolloutEnforce, RolloutCanary, RolloutShadow:
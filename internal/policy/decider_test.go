@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -33,6 +34,112 @@ func TestEvaluateEnforceKillWithReason(t *testing.T) {
 	}
 }
 
+func TestEvaluateKillReasonNamesOutputCycle(t *testing.T) {
+	d := NewThresholdDecider()
+	out := d.Evaluate(Telemetry{
+		CPUPercent:    95,
+		CPUOverFor:    31 * time.Second,
+		LogEntropy:    0.30,
+		LogRepetition: 1.0,
+		RawDiversity:  0.30,
+		LoopPeriod:    3,
+	}, Policy{
+		MaxCPUPercent:    90,
+		CPUWindow:        30 * time.Second,
+		MinLogEntropy:    0.20,
+		MaxLogRepetition: 0.80,
+	})
+
+	if out.Action != ActionKill {
+		t.Fatalf("expected ActionKill, got %s", out.Action.String())
+	}
+	expected := "CPU exceeded 90% for 30s AND log repetition exceeded 0.80 (output cycles every 3 lines)"
+	if out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+}
+
+func TestEvaluateKillReasonNamesLoopingTool(t *testing.T) {
+	d := NewThresholdDecider()
+	telemetry := Telemetry{
+		CPUPercent:    95,
+		CPUOverFor:    31 * time.Second,
+		LogEntropy:    0.30,
+		LogRepetition: 1.0,
+		RawDiversity:  0.30,
+		LoopTool:      "search",
+	}
+	p := Policy{
+		MaxCPUPercent:    90,
+		CPUWindow:        30 * time.Second,
+		MinLogEntropy:    0.20,
+		MaxLogRepetition: 0.80,
+	}
+
+	out := d.Evaluate(telemetry, p)
+	expected := "CPU exceeded 90% for 30s AND log repetition exceeded 0.80 (tool \"search\" called repeatedly with identical args)"
+	if out.Action != ActionKill || out.Reason != expected {
+		t.Fatalf("unexpected decision %s\nexpected: %q\ngot:      %q", out.Action.String(), expected, out.Reason)
+	}
+
+	telemetry.LoopErrors = true
+	expected = "CPU exceeded 90% for 30s AND log repetition exceeded 0.80 (tool \"search\" keeps failing with the same error)"
+	if out := d.Evaluate(telemetry, p); out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+}
+
+func TestEvaluateAlertReasonCountsRepeatedTrace(t *testing.T) {
+	d := NewThresholdDecider()
+	out := d.Evaluate(Telemetry{
+		CPUPercent:    5,
+		LogEntropy:    0.90,
+		LogRepetition: 1.0,
+		RawDiversity:  0.90,
+		RepeatedTrace: 4,
+	}, Policy{
+		MaxCPUPercent:    90,
+		MinLogEntropy:    0.20,
+		MaxLogRepetition: 0.80,
+	})
+
+	if out.Action != ActionAlert {
+		t.Fatalf("expected ActionAlert, got %s", out.Action.String())
+	}
+	if expected := "log repetition exceeded 0.80 (same stack trace 4 times)"; out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+}
+
+func TestEvaluatePauseOnBreachPrefersPause(t *testing.T) {
+	d := NewThresholdDecider()
+	p := Policy{
+		MaxCPUPercent:    90,
+		CPUWindow:        30 * time.Second,
+		MinLogEntropy:    0.20,
+		MaxLogRepetition: 0.80,
+		RestartOnBreach:  true,
+		PauseOnBreach:    true,
+	}
+	telemetry := Telemetry{
+		CPUPercent:    95,
+		CPUOverFor:    31 * time.Second,
+		LogEntropy:    0.10,
+		LogRepetition: 0.95,
+	}
+
+	out := d.Evaluate(telemetry, p)
+	if out.Action != ActionPause || out.Action.String() != "PAUSE" {
+		t.Fatalf("expected ActionPause, got %s", out.Action.String())
+	}
+
+	p.RolloutMode = RolloutShadow
+	out = d.Evaluate(telemetry, p)
+	if out.Action != ActionLogOnly || out.IntendedAction != ActionPause {
+		t.Fatalf("expected shadow log-only with intended PAUSE, got %s/%s", out.Action.String(), out.IntendedAction.String())
+	}
+}
+
 func TestEvaluateShadowModeReturnsLogOnly(t *testing.T) {
 	d := NewThresholdDecider()
 	p := Policy{
@@ -130,6 +237,231 @@ func TestEvaluateProgressGuardSuppressesDestructiveAction(t *testing.T) {
 	}
 }
 
+func TestEvaluateDeadlineAlertsThenKills(t *testing.T) {
+	d := NewThresholdDecider()
+	p := Policy{MaxRuntime: 10 * time.Minute, RestartOnBreach: true}
+
+	near := d.Evaluate(Telemetry{Runtime: 9 * time.Minute}, p)
+	if near.Action != ActionAlert || near.Breach != "" {
+		t.Fatalf("expected plain alert near the deadline, got %s breach=%q", near.Action.String(), near.Breach)
+	}
+
+	over := d.Evaluate(Telemetry{Runtime: 10 * time.Minute}, p)
+	if over.Action != ActionKill {
+		t.Fatalf("expected ActionKill past the deadline even with restart-on-breach, got %s", over.Action.String())
+	}
+	if over.Breach != BreachDeadline {
+		t.Fatalf("expected breach %s, got %q", BreachDeadline, over.Breach)
+	}
+	if over.Reason != "runtime exceeded 10m0s" {
+		t.Fatalf("unexpected reason: %q", over.Reason)
+	}
+}
+
+func TestEvaluateOutputStalledHonorsRollout(t *testing.T) {
+	d := NewThresholdDecider()
+	p := Policy{MaxSilence: time.Minute, RestartOnBreach: true, RolloutMode: RolloutShadow}
+
+	out := d.Evaluate(Telemetry{SilentFor: 2 * time.Minute}, p)
+	if out.Action != ActionLogOnly || out.IntendedAction != ActionRestart {
+		t.Fatalf("expected shadow log-only for RESTART, got %s/%s", out.Action.String(), out.IntendedAction.String())
+	}
+	if out.Breach != BreachOutputStalled {
+		t.Fatalf("expected breach %s, got %q", BreachOutputStalled, out.Breach)
+	}
+
+	idle := d.Evaluate(Telemetry{SilentFor: 30 * time.Second}, p)
+	if idle.Action != ActionContinue {
+		t.Fatalf("expected continue below the warning point, got %s", idle.Action.String())
+	}
+}
+
+func TestEvaluateProcessHungAlertsThenKills(t *testing.T) {
+	d := NewThresholdDecider()
+	p := Policy{MaxStuck: time.Minute, MaxSilence: 5 * time.Minute}
+	telemetry := Telemetry{StuckFor: 50 * time.Second, SilentFor: 50 * time.Second, StuckState: "python3 sleeping in futex_wait_queue"}
+
+	warn := d.Evaluate(telemetry, p)
+	if warn.Action != ActionAlert || warn.Breach != "" {
+		t.Fatalf("expected a plain alert near the limit, got %s/%q", warn.Action.String(), warn.Breach)
+	}
+	if expected := "no progress for 50s of 1m0s allowed (python3 sleeping in futex_wait_queue)"; warn.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, warn.Reason)
+	}
+
+	telemetry.StuckFor, telemetry.SilentFor = 90*time.Second, 90*time.Second
+	out := d.Evaluate(telemetry, p)
+	if out.Action != ActionKill || out.Breach != BreachHung {
+		t.Fatalf("expected KILL with breach %s, got %s/%q", BreachHung, out.Action.String(), out.Breach)
+	}
+	if expected := "no CPU, output, I/O or context-switch progress for 1m0s (python3 sleeping in futex_wait_queue)"; out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+}
+
+func TestEvaluateDiskWriteLimits(t *testing.T) {
+	d := NewThresholdDecider()
+	p := Policy{MaxWriteBytesPerSec: 50 << 20, MaxWriteBytes: 2 << 30, RestartOnBreach: true}
+
+	if out := d.Evaluate(Telemetry{WriteBytesPerSec: 10 << 20, WriteBytes: 1 << 30}, p); out.Action != ActionContinue {
+		t.Fatalf("expected CONTINUE under both limits, got %s: %s", out.Action.String(), out.Reason)
+	}
+
+	out := d.Evaluate(Telemetry{WriteBytesPerSec: 120 << 20, WriteBytes: 1 << 30}, p)
+	if out.Action != ActionRestart || out.Breach != BreachDiskWrites {
+		t.Fatalf("expected RESTART with breach %s, got %s/%q", BreachDiskWrites, out.Action.String(), out.Breach)
+	}
+	if expected := "disk writes exceeded 50.0MB/s (120.0MB/s)"; out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+
+	p.RestartOnBreach = false
+	out = d.Evaluate(Telemetry{WriteBytesPerSec: 1 << 20, WriteBytes: 3<<30 + 512<<20}, p)
+	if out.Action != ActionKill || out.Breach != BreachDiskWrites {
+		t.Fatalf("expected KILL with breach %s, got %s/%q", BreachDiskWrites, out.Action.String(), out.Breach)
+	}
+	if expected := "wrote more than 2.0GB to disk (3.5GB)"; out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+
+	// A deadline breach still names the incident.
+	p.MaxRuntime = time.Minute
+	out = d.Evaluate(Telemetry{WriteBytes: 3 << 30, Runtime: 2 * time.Minute}, p)
+	if out.Breach != BreachDeadline {
+		t.Fatalf("expected %s to take precedence, got %q", BreachDeadline, out.Breach)
+	}
+}
+
+func TestEvaluateRuleMatches(t *testing.T) {
+	d := NewThresholdDecider()
+	p := Policy{MaxCPUPercent: 90, MaxLogRepetition: 0.8, MinLogEntropy: 0.2}
+	rateLimited := RuleMatch{Name: "rate-limited", Severity: "warning", Action: RuleActionAlert, Hits: 5, Window: time.Minute}
+
+	out := d.Evaluate(Telemetry{LogEntropy: 0.9, Rules: []RuleMatch{rateLimited}}, p)
+	if out.Action != ActionAlert || out.Breach != "" {
+		t.Fatalf("expected ALERT without breach, got %s/%q", out.Action.String(), out.Breach)
+	}
+	if expected := `rule "rate-limited" matched 5 times in 1m0s (warning)`; out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+
+	authFailed := RuleMatch{Name: "auth-failed", Severity: "critical", Action: RuleActionPause, Hits: 1, Window: time.Minute}
+	out = d.Evaluate(Telemetry{LogEntropy: 0.9, Rules: []RuleMatch{authFailed}}, p)
+	if out.Action != ActionPause || out.Breach != BreachRule {
+		t.Fatalf("expected PAUSE with breach %s, got %s/%q", BreachRule, out.Action.String(), out.Breach)
+	}
+	if expected := `rule "auth-failed" matched once (critical)`; out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+
+	// Kill wins over pause, and restart-on-breach applies as for any breach.
+	authFailed.Action = RuleActionKill
+	out = d.Evaluate(Telemetry{LogEntropy: 0.9, Rules: []RuleMatch{rateLimited, authFailed}}, Policy{RestartOnBreach: true})
+	if out.Action != ActionRestart || out.Breach != BreachRule {
+		t.Fatalf("expected RESTART with breach %s, got %s/%q", BreachRule, out.Action.String(), out.Breach)
+	}
+
+	p.RolloutMode = RolloutShadow
+	out = d.Evaluate(Telemetry{LogEntropy: 0.9, Rules: []RuleMatch{authFailed}}, p)
+	if out.Action != ActionLogOnly || out.IntendedAction != ActionKill {
+		t.Fatalf("expected LOG_ONLY intending KILL under shadow rollout, got %s/%s", out.Action.String(), out.IntendedAction.String())
+	}
+
+	// A limit breach names the incident over the rule.
+	p = Policy{MaxSilence: time.Minute}
+	out = d.Evaluate(Telemetry{SilentFor: 2 * time.Minute, Rules: []RuleMatch{authFailed}}, p)
+	if out.Breach != BreachOutputStalled {
+		t.Fatalf("expected %s to take precedence, got %q", BreachOutputStalled, out.Breach)
+	}
+}
+
+func TestEvaluateKnownPatterns(t *testing.T) {
+	d := NewThresholdDecider()
+	p := Policy{MaxCPUPercent: 90, MaxLogRepetition: 0.8, MinLogEntropy: 0.2}
+	alert := PatternMatch{ID: 7, Pattern: "rate limited, sleeping <NUM>s"}
+
+	out := d.Evaluate(Telemetry{LogEntropy: 0.9, KnownPatterns: []PatternMatch{alert}}, p)
+	if out.Action != ActionAlert || out.Breach != "" {
+		t.Fatalf("expected ALERT without breach, got %s/%q", out.Action.String(), out.Breach)
+	}
+	if expected := `output matches known pattern #7 "rate limited, sleeping <NUM>s"`; out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+
+	kill := PatternMatch{ID: 9, Pattern: strings.Repeat("x", 80), Kill: true}
+	out = d.Evaluate(Telemetry{LogEntropy: 0.9, KnownPatterns: []PatternMatch{kill}}, p)
+	if out.Action != ActionKill || out.Breach != BreachKnownPattern {
+		t.Fatalf("expected KILL with breach %s, got %s/%q", BreachKnownPattern, out.Action.String(), out.Breach)
+	}
+	if !strings.HasSuffix(out.Reason, `..."`) || len(out.Reason) > 110 {
+		t.Fatalf("expected a truncated pattern in the reason, got %q", out.Reason)
+	}
+
+	p.PauseOnBreach = true
+	out = d.Evaluate(Telemetry{LogEntropy: 0.9, KnownPatterns: []PatternMatch{kill}}, p)
+	if out.Action != ActionPause || out.Breach != BreachKnownPattern {
+		t.Fatalf("expected PAUSE with breach %s, got %s/%q", BreachKnownPattern, out.Action.String(), out.Breach)
+	}
+
+	// A kill rule names the incident over the pattern.
+	rule := RuleMatch{Name: "auth-failed", Severity: "critical", Action: RuleActionKill, Hits: 1}
+	out = d.Evaluate(Telemetry{LogEntropy: 0.9, Rules: []RuleMatch{rule}, KnownPatterns: []PatternMatch{kill}}, Policy{})
+	if out.Breach != BreachRule {
+		t.Fatalf("expected %s to take precedence, got %q", BreachRule, out.Breach)
+	}
+}
+
+func TestEvaluateBudgets(t *testing.T) {
+	d := NewThresholdDecider()
+	p := Policy{MaxTokens: 100000, MaxCostUSD: 5, BudgetAlertPercent: 80, RestartOnBreach: true}
+
+	out := d.Evaluate(Telemetry{Tokens: 50000, CostUSD: 2.5}, p)
+	if out.Action != ActionContinue {
+		t.Fatalf("expected CONTINUE at half the budget, got %s (%s)", out.Action.String(), out.Reason)
+	}
+
+	out = d.Evaluate(Telemetry{Tokens: 85000, CostUSD: 2.5}, p)
+	if out.Action != ActionAlert || out.Breach != "" {
+		t.Fatalf("expected ALERT without breach near the token budget, got %s/%q", out.Action.String(), out.Breach)
+	}
+	if expected := "used 85000 of 100000 token budget (85%)"; out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+
+	// A restart would still be over budget, so the run is killed.
+	out = d.Evaluate(Telemetry{Tokens: 90000, CostUSD: 5.12}, p)
+	if out.Action != ActionKill || out.Breach != BreachBudget {
+		t.Fatalf("expected KILL with breach %s, got %s/%q", BreachBudget, out.Action.String(), out.Breach)
+	}
+	if expected := "used 90000 of 100000 token budget (90%) AND spend $5.12 exceeded $5.00 budget"; out.Reason != expected {
+		t.Fatalf("unexpected reason\nexpected: %q\ngot:      %q", expected, out.Reason)
+	}
+
+	p.PauseOnBudget = true
+	out = d.Evaluate(Telemetry{Tokens: 100000}, p)
+	if out.Action != ActionPause || out.Breach != BreachBudget {
+		t.Fatalf("expected PAUSE with breach %s, got %s/%q", BreachBudget, out.Action.String(), out.Breach)
+	}
+
+	// A kill-worthy limit overrides a pausing budget.
+	p.MaxMemoryMB = 512
+	out = d.Evaluate(Telemetry{Tokens: 100000, MemoryMB: 600}, p)
+	if out.Action != ActionKill {
+		t.Fatalf("expected KILL when memory is also breached, got %s", out.Action.String())
+	}
+
+	p = Policy{MaxCostUSD: 5}
+	out = d.Evaluate(Telemetry{CostUSD: 4.9}, p)
+	if out.Action != ActionContinue {
+		t.Fatalf("expected CONTINUE without an alert percent, got %s (%s)", out.Action.String(), out.Reason)
+	}
+	p.RolloutMode = RolloutShadow
+	out = d.Evaluate(Telemetry{CostUSD: 6}, p)
+	if out.Action != ActionLogOnly || out.IntendedAction != ActionKill || out.Breach != BreachBudget {
+		t.Fatalf("expected LOG_ONLY intending KILL under shadow rollout, got %s/%s/%q", out.Action.String(), out.IntendedAction.String(), out.Breach)
+	}
+}
+
 func TestEvaluateCanaryModeReturnsLogOnlyOutsideSample(t *testing.T) {
 	d := NewThresholdDecider()
 	key := "run-canary-log-only"
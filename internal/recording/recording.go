@@ -0,0 +1,192 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Version is the format version this package reads and writes.
+const Version = 1
+
+// Event kinds.
+const (
+	KindHeader   = "header"
+	KindLine     = "line"
+	KindSample   = "sample"
+	KindDecision = "decision"
+)
+
+// maxEventBytes bounds one encoded event; long log lines are kept whole.
+const maxEventBytes = 4 << 20
+
+// Header describes the recorded run.
+type Header struct {
+	Version      int     `json:"version"`
+	Command      string  `json:"command"`
+	RunID        string  `json:"run_id,omitempty"`
+	PollInterval int     `json:"poll_interval_ms,omitempty"`
+	LogWindow    int     `json:"log_window,omitempty"`
+	MaxCPU       float64 `json:"max_cpu,omitempty"`
+	Model        string  `json:"model,omitempty"` // Prices the tokens counted in the output
+}
+
+// Sample is one telemetry reading of the process tree, as the monitor took
+// it. Values the replay can rebuild from the lines (silence, output rate,
+// token spend) are not stored.
+type Sample struct {
+	CPUPercent       float64 `json:"cpu"`
+	MemoryMB         float64 `json:"memory_mb"`
+	StuckForSeconds  float64 `json:"stuck_for_s,omitempty"`
+	StuckState       string  `json:"stuck_state,omitempty"`
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec,omitempty"`
+	WriteBytes       uint64  `json:"write_bytes,omitempty"`
+	Paused           bool    `json:"paused,omitempty"` // The group was frozen; nothing was judged
+	// Seconds since a progress bar or counter in the output last moved
+	// forward; omitted when none has. Revisions drawn with \r are not
+	// recorded as lines, so the replay cannot rebuild this.
+	ProgressAgeSeconds float64 `json:"progress_age_s,omitempty"`
+}
+
+// StuckFor returns StuckForSeconds as a duration.
+func (s Sample) StuckFor() time.Duration {
+	return time.Duration(s.StuckForSeconds * float64(time.Second))
+}
+
+// ProgressAdvancedAt returns when progress last moved forward, for a sample
+// taken at at, or the zero time.
+func (s Sample) ProgressAdvancedAt(at time.Time) time.Time {
+	if s.ProgressAgeSeconds <= 0 {
+		return time.Time{}
+	}
+	return at.Add(-time.Duration(s.ProgressAgeSeconds * float64(time.Second)))
+}
+
+// Decision is what the live decider concluded on a sample. Replay makes its
+// own decisions and ignores these; they show what the run actually did.
+type Decision struct {
+	Action         string `json:"action"`
+	IntendedAction string `json:"intended_action,omitempty"` // Set when rollout made Action log-only
+	Reason         string `json:"reason"`
+	Breach         string `json:"breach,omitempty"`
+	Pattern        string `json:"pattern,omitempty"`
+}
+
+// Event is one line of a recording, a JSON Lines file. The first line is a
+// header; every other line is an output line, a telemetry sample or a
+// decision, stamped with the wall-clock time it was seen:
+//
+//	{"kind":"header","t":"...","header":{"version":1,"command":"python3 agent.py"}}
+//	{"kind":"line","t":"...","stream":"stderr","text":"retrying in 5s"}
+//	{"kind":"sample","t":"...","sample":{"cpu":97.5,"memory_mb":212.4}}
+//	{"kind":"decision","t":"...","decision":{"action":"ALERT","reason":"..."}}
+type Event struct {
+	Kind     string    `json:"kind"`
+	Time     time.Time `json:"t"`
+	Header   *Header   `json:"header,omitempty"`
+	Stream   string    `json:"stream,omitempty"` // "stdout", "stderr" or "" for a merged log
+	Text     string    `json:"text,omitempty"`
+	Sample   *Sample   `json:"sample,omitempty"`
+	Decision *Decision `json:"decision,omitempty"`
+}
+
+// Recording is a decoded recording file.
+type Recording struct {
+	Header Header
+	Start  time.Time // Header time, or the first event's when there is no header
+	Events []Event   // Lines, samples and decisions in time order
+}
+
+// Span returns the time between Start and the last event.
+func (r *Recording) Span() time.Duration {
+	if len(r.Events) == 0 {
+		return 0
+	}
+	return r.Events[len(r.Events)-1].Time.Sub(r.Start)
+}
+
+// Count returns how many events of kind the recording holds.
+func (r *Recording) Count(kind string) int {
+	n := 0
+	for _, ev := range r.Events {
+		if ev.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// ReadFile decodes the recording at path.
+func ReadFile(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read decodes a recording. Blank lines are skipped; unknown kinds and
+// events without a time are errors, as is a header from a newer version.
+func Read(r io.Reader) (*Recording, error) {
+	rec := &Recording{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxEventBytes)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		raw := sc.Bytes()
+		if len(raw) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(raw, &ev); err != nil {
+			return nil, fmt.Errorf("recording line %d: %v", lineNo, err)
+		}
+		if ev.Time.IsZero() {
+			return nil, fmt.Errorf("recording line %d: missing time", lineNo)
+		}
+		switch ev.Kind {
+		case KindHeader:
+			if ev.Header == nil {
+				return nil, fmt.Errorf("recording line %d: header event without header", lineNo)
+			}
+			if ev.Header.Version > Version {
+				return nil, fmt.Errorf("recording line %d: format version %d is newer than supported %d", lineNo, ev.Header.Version, Version)
+			}
+			rec.Header = *ev.Header
+			rec.Start = ev.Time
+		case KindLine:
+			rec.Events = append(rec.Events, ev)
+		case KindSample:
+			if ev.Sample == nil {
+				return nil, fmt.Errorf("recording line %d: sample event without sample", lineNo)
+			}
+			rec.Events = append(rec.Events, ev)
+		case KindDecision:
+			if ev.Decision == nil {
+				return nil, fmt.Errorf("recording line %d: decision event without decision", lineNo)
+			}
+			rec.Events = append(rec.Events, ev)
+		default:
+			return nil, fmt.Errorf("recording line %d: unknown kind %q", lineNo, ev.Kind)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("recording: %v", err)
+	}
+
+	// Writers append from several goroutines; order by time, keeping the
+	// file order for ties so lines stay ahead of the sample that saw them.
+	sort.SliceStable(rec.Events, func(i, j int) bool {
+		return rec.Events[i].Time.Before(rec.Events[j].Time)
+	})
+	if rec.Start.IsZero() && len(rec.Events) > 0 {
+		rec.Start = rec.Events[0].Time
+	}
+	return rec, nil
+}
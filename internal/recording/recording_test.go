@@ -0,0 +1,63 @@
+package recording
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadOrdersEventsAndKeepsHeader(t *testing.T) {
+	input := `{"kind":"header","t":"2026-03-01T12:00:00Z","header":{"version":1,"command":"python3 agent.py","run_id":"r1"}}
+{"kind":"sample","t":"2026-03-01T12:00:01Z","sample":{"cpu":40,"memory_mb":100}}
+{"kind":"line","t":"2026-03-01T12:00:00.500Z","stream":"stdout","text":"first"}
+
+{"kind":"line","t":"2026-03-01T12:00:01Z","stream":"stderr","text":"second"}
+`
+	rec, err := Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if rec.Header.Command != "python3 agent.py" || rec.Header.RunID != "r1" {
+		t.Fatalf("unexpected header: %+v", rec.Header)
+	}
+	if got := rec.Span(); got != time.Second {
+		t.Fatalf("expected a 1s span from the header, got %s", got)
+	}
+	var kinds []string
+	for _, ev := range rec.Events {
+		kinds = append(kinds, ev.Kind+":"+ev.Text)
+	}
+	// Ties keep file order.
+	if got := strings.Join(kinds, ","); got != "line:first,sample:,line:second" {
+		t.Fatalf("unexpected event order: %s", got)
+	}
+	if rec.Count(KindSample) != 1 || rec.Count(KindLine) != 2 {
+		t.Fatalf("unexpected counts: %d samples, %d lines", rec.Count(KindSample), rec.Count(KindLine))
+	}
+}
+
+func TestReadStartsAtFirstEventWithoutHeader(t *testing.T) {
+	rec, err := Read(strings.NewReader(`{"kind":"line","t":"2026-03-01T12:00:03Z","text":"late"}
+{"kind":"line","t":"2026-03-01T12:00:02Z","text":"early"}`))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if want := time.Date(2026, 3, 1, 12, 0, 2, 0, time.UTC); !rec.Start.Equal(want) {
+		t.Fatalf("expected start %s, got %s", want, rec.Start)
+	}
+}
+
+func TestReadRejectsInvalidEvents(t *testing.T) {
+	for name, input := range map[string]string{
+		"bad json":       `{"kind":`,
+		"missing time":   `{"kind":"line","text":"x"}`,
+		"unknown kind":   `{"kind":"marker","t":"2026-03-01T12:00:00Z"}`,
+		"empty sample":   `{"kind":"sample","t":"2026-03-01T12:00:00Z"}`,
+		"newer version":  `{"kind":"header","t":"2026-03-01T12:00:00Z","header":{"version":2}}`,
+		"missing header": `{"kind":"header","t":"2026-03-01T12:00:00Z"}`,
+	} {
+		if _, err := Read(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"flowforge/internal/redact"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Writer appends events to a recording. It is safe for concurrent use: the
+// log observer writes lines while the monitor writes samples and decisions.
+// The command, output lines and decision text go through redact.Line first.
+//
+// Lines are buffered and flushed with each sample or decision, so a crash
+// loses at most one poll interval. The first write error is kept and
+// returned by Close; later events are dropped rather than stalling the run.
+type Writer struct {
+	mu     sync.Mutex
+	out    *bufio.Writer
+	enc    *json.Encoder
+	closer io.Closer
+	err    error
+}
+
+// Create writes a new recording to path, replacing any file there.
+func Create(path string, h Header, start time.Time) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	w := NewWriter(f, h, start)
+	w.closer = f
+	if w.err != nil {
+		_ = f.Close()
+		return nil, w.err
+	}
+	return w, nil
+}
+
+// NewWriter starts a recording on out with h as its header; Version is
+// filled in.
+func NewWriter(out io.Writer, h Header, start time.Time) *Writer {
+	bw := bufio.NewWriter(out)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false) // Keep <NUM> and <REDACTED> readable
+	w := &Writer{out: bw, enc: enc}
+
+	h.Version = Version
+	h.Command = redact.Line(h.Command)
+	w.write(Event{Kind: KindHeader, Time: start, Header: &h}, true)
+	return w
+}
+
+// Line records one complete output line.
+func (w *Writer) Line(at time.Time, stream, text string) {
+	w.write(Event{Kind: KindLine, Time: at, Stream: stream, Text: redact.Line(text)}, false)
+}
+
+// Sample records one telemetry reading.
+func (w *Writer) Sample(at time.Time, s Sample) {
+	w.write(Event{Kind: KindSample, Time: at, Sample: &s}, true)
+}
+
+// Decision records what the decider concluded.
+func (w *Writer) Decision(at time.Time, d Decision) {
+	d.Reason = redact.Line(d.Reason)
+	d.Pattern = redact.Line(d.Pattern)
+	w.write(Event{Kind: KindDecision, Time: at, Decision: &d}, true)
+}
+
+func (w *Writer) write(ev Event, flush bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return
+	}
+	if err := w.enc.Encode(ev); err != nil {
+		w.err = err
+		return
+	}
+	if flush {
+		w.err = w.out.Flush()
+	}
+}
+
+// Close flushes the recording and closes the file it was created with. It
+// returns the first error seen while recording.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = w.out.Flush()
+	}
+	if w.closer != nil {
+		if err := w.closer.Close(); err != nil && w.err == nil {
+			w.err = err
+		}
+		w.closer = nil
+	}
+	return w.err
+}
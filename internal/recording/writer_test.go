@@ -0,0 +1,73 @@
+package recording
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterRoundTripsThroughRead(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Header{Command: "agent.py --api-key=sk-live-123", RunID: "r1", PollInterval: 500}, start)
+	w.Line(start.Add(100*time.Millisecond), "stdout", "calling api with token=abc123")
+	w.Sample(start.Add(500*time.Millisecond), Sample{CPUPercent: 91, MemoryMB: 120, StuckForSeconds: 1.5, StuckState: "D (io_schedule)", ProgressAgeSeconds: 2})
+	w.Decision(start.Add(500*time.Millisecond), Decision{Action: "LOG_ONLY", IntendedAction: "KILL", Reason: "Shadow mode: would KILL."})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "abc123") || strings.Contains(buf.String(), "sk-live-123") {
+		t.Fatalf("expected secrets to be redacted:\n%s", buf.String())
+	}
+	rec, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if rec.Header.Version != Version || rec.Header.RunID != "r1" || !rec.Start.Equal(start) {
+		t.Fatalf("unexpected header: %+v at %s", rec.Header, rec.Start)
+	}
+	if len(rec.Events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(rec.Events))
+	}
+	if s := rec.Events[1].Sample; s == nil || s.CPUPercent != 91 || s.StuckFor() != 1500*time.Millisecond || !s.ProgressAdvancedAt(rec.Events[1].Time).Equal(start.Add(-1500*time.Millisecond)) {
+		t.Fatalf("unexpected sample: %+v", rec.Events[1].Sample)
+	}
+	if d := rec.Events[2].Decision; d == nil || d.IntendedAction != "KILL" {
+		t.Fatalf("unexpected decision: %+v", rec.Events[2].Decision)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriterKeepsFirstError(t *testing.T) {
+	w := NewWriter(failingWriter{}, Header{Command: "x"}, time.Now())
+	w.Line(time.Now(), "stdout", "dropped")
+	if err := w.Close(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected the write error from Close, got %v", err)
+	}
+}
+
+func TestCreateWritesReadableFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	w, err := Create(path, Header{Command: "sleep 1"}, time.Now())
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	w.Sample(time.Now(), Sample{Paused: true})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	rec, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if rec.Header.Command != "sleep 1" || rec.Count(KindSample) != 1 || !rec.Events[0].Sample.Paused {
+		t.Fatalf("unexpected recording: %+v", rec)
+	}
+}
@@ -0,0 +1,393 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"flowforge/internal/detect"
+	"flowforge/internal/policy"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Match kinds.
+const (
+	MatchRegex      = "regex"
+	MatchNormalized = "normalized"
+	MatchJSONPath   = "json-path"
+)
+
+// Severities, for the reason text and the rule_match event.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+const defaultWindow = time.Minute
+
+// Spec is one rule as written in a rules file:
+//
+//	rules:
+//	  - name: rate-limited
+//	    match: regex            # regex (default), normalized or json-path
+//	    pattern: 'HTTP 429'
+//	    stream: stderr          # stdout, stderr or omitted for both
+//	    window: 1m              # default 1m
+//	    min_hits: 5             # default 1
+//	    severity: warning       # info, warning (default) or critical
+//	    action: pause           # alert (default), pause or kill
+//	  - name: auth-failed
+//	    match: json-path
+//	    path: $.error.code      # structured lines only
+//	    pattern: '^40[13]$'     # regex on the value; omit to match any value
+//	    action: kill
+type Spec struct {
+	Name     string `yaml:"name"`
+	Match    string `yaml:"match"`
+	Pattern  string `yaml:"pattern"`
+	Path     string `yaml:"path"`
+	Stream   string `yaml:"stream"`
+	Window   string `yaml:"window"`
+	MinHits  int    `yaml:"min_hits"`
+	Severity string `yaml:"severity"`
+	Action   string `yaml:"action"`
+}
+
+type file struct {
+	Rules []Spec `yaml:"rules"`
+}
+
+// Firing is a rule reaching min_hits matches within its window.
+type Firing struct {
+	Rule     string
+	Severity string
+	Action   string
+	Hits     int
+	Window   time.Duration
+	Stream   string
+	Line     string // The line that completed the count
+	Time     time.Time
+}
+
+// Match returns the firing as the policy decider sees it.
+func (f Firing) Match() policy.RuleMatch {
+	return policy.RuleMatch{Name: f.Rule, Severity: f.Severity, Action: f.Action, Hits: f.Hits, Window: f.Window}
+}
+
+type rule struct {
+	Spec
+	window time.Duration
+	re     *regexp.Regexp // regex, and json-path with a pattern
+	want   string         // normalized
+	path   []pathStep     // json-path
+
+	hits      []time.Time
+	lastFired time.Time
+}
+
+// Set is a compiled rules file. It is safe for concurrent use: the log
+// observer calls Observe for every line and the monitor calls Take once
+// per poll.
+type Set struct {
+	mu         sync.Mutex
+	rules      []*rule
+	pending    []Firing
+	normalized bool // Some rule compares normalized text
+	structured bool // Some rule reads JSON fields
+}
+
+// Load reads and compiles a rules file.
+func Load(path string) (*Set, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("rules file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse decodes rules YAML and compiles it. Unknown keys are errors, so a
+// misspelt min_hits does not silently fall back to 1.
+func Parse(r io.Reader) (*Set, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var f file
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return Compile(f.Rules)
+}
+
+// Compile validates specs, fills in defaults and compiles their patterns.
+func Compile(specs []Spec) (*Set, error) {
+	s := &Set{}
+	seen := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		r, err := compile(spec)
+		if err != nil {
+			name := spec.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("rule %s: name used twice", r.Name)
+		}
+		seen[r.Name] = true
+		s.rules = append(s.rules, r)
+		s.normalized = s.normalized || r.Match == MatchNormalized
+		s.structured = s.structured || r.Match == MatchJSONPath
+	}
+	return s, nil
+}
+
+func compile(spec Spec) (*rule, error) {
+	spec.Name = strings.TrimSpace(spec.Name)
+	if spec.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	r := &rule{Spec: spec, window: defaultWindow}
+
+	var err error
+	switch r.Match = strings.ToLower(strings.TrimSpace(spec.Match)); r.Match {
+	case "", MatchRegex:
+		r.Match = MatchRegex
+		if spec.Pattern == "" {
+			return nil, errors.New("regex rules need a pattern")
+		}
+		if r.re, err = regexp.Compile(spec.Pattern); err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+	case MatchNormalized:
+		if spec.Pattern == "" {
+			return nil, errors.New("normalized rules need a pattern")
+		}
+		// A raw example line works as well as its normalized form.
+		r.want = detect.NormalizeLog(spec.Pattern)
+	case MatchJSONPath:
+		if r.path, err = parsePath(spec.Path); err != nil {
+			return nil, fmt.Errorf("path: %w", err)
+		}
+		if spec.Pattern != "" {
+			if r.re, err = regexp.Compile(spec.Pattern); err != nil {
+				return nil, fmt.Errorf("pattern: %w", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("match must be %s, %s or %s, got %q", MatchRegex, MatchNormalized, MatchJSONPath, spec.Match)
+	}
+
+	switch r.Stream = strings.ToLower(strings.TrimSpace(spec.Stream)); r.Stream {
+	case "", "stdout", "stderr":
+	default:
+		return nil, fmt.Errorf("stream must be stdout or stderr, got %q", spec.Stream)
+	}
+	if spec.Window != "" {
+		if r.window, err = time.ParseDuration(spec.Window); err != nil || r.window <= 0 {
+			return nil, fmt.Errorf("window must be a positive duration such as 1m, got %q", spec.Window)
+		}
+	}
+	if spec.MinHits < 0 {
+		return nil, fmt.Errorf("min_hits must be at least 1, got %d", spec.MinHits)
+	}
+	if r.MinHits == 0 {
+		r.MinHits = 1
+	}
+	switch r.Severity = strings.ToLower(strings.TrimSpace(spec.Severity)); r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return nil, fmt.Errorf("severity must be info, warning or critical, got %q", spec.Severity)
+	}
+	switch r.Action = strings.ToLower(strings.TrimSpace(spec.Action)); r.Action {
+	case "":
+		r.Action = policy.RuleActionAlert
+	case policy.RuleActionAlert, policy.RuleActionPause, policy.RuleActionKill:
+	default:
+		return nil, fmt.Errorf("action must be alert, pause or kill, got %q", spec.Action)
+	}
+	return r, nil
+}
+
+// Len returns the number of rules.
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rules)
+}
+
+// Observe checks one output line against every rule and returns the rules
+// it made fire. A rule fires when min_hits of its matches fall within its
+// window, then at most once per window while matches continue. Lines from
+// an unknown stream (a tailed log file) match rules for either stream.
+func (s *Set) Observe(l detect.Line) []Firing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var normalized string
+	if s.normalized {
+		normalized = detect.NormalizeLog(l.Text)
+	}
+	fields := l.Fields
+	if fields == nil && s.structured {
+		fields = detect.ParseFields(l.Text)
+	}
+
+	var fired []Firing
+	for _, r := range s.rules {
+		if r.Stream != "" && l.Stream != "" && r.Stream != l.Stream {
+			continue
+		}
+		if !r.matches(l.Text, normalized, fields) {
+			continue
+		}
+		cutoff := l.Time.Add(-r.window)
+		kept := r.hits[:0]
+		for _, t := range r.hits {
+			if t.After(cutoff) {
+				kept = append(kept, t)
+			}
+		}
+		r.hits = append(kept, l.Time)
+		if len(r.hits) < r.MinHits || (!r.lastFired.IsZero() && l.Time.Sub(r.lastFired) < r.window) {
+			continue
+		}
+		fired = append(fired, Firing{
+			Rule:     r.Name,
+			Severity: r.Severity,
+			Action:   r.Action,
+			Hits:     len(r.hits),
+			Window:   r.window,
+			Stream:   l.Stream,
+			Line:     l.Text,
+			Time:     l.Time,
+		})
+		r.lastFired = l.Time
+		r.hits = r.hits[:0]
+	}
+	s.pending = append(s.pending, fired...)
+	return fired
+}
+
+// Take returns the firings since the previous Take.
+func (s *Set) Take() []Firing {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.pending
+	s.pending = nil
+	return out
+}
+
+func (r *rule) matches(text, normalized string, fields map[string]interface{}) bool {
+	switch r.Match {
+	case MatchNormalized:
+		return normalized == r.want
+	case MatchJSONPath:
+		v, ok := lookup(fields, r.path)
+		if !ok {
+			return false
+		}
+		return r.re == nil || r.re.MatchString(valueString(v))
+	default:
+		return r.re.MatchString(text)
+	}
+}
+
+// pathStep is one object key or array index of a json-path.
+type pathStep struct {
+	key   string
+	index int // Used when key is ""
+}
+
+// parsePath accepts dotted paths with optional array indexes, with or
+// without the leading "$.": $.error.code, tool_calls[0].name.
+func parsePath(p string) ([]pathStep, error) {
+	p = strings.TrimSpace(p)
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$"), ".")
+	if p == "" {
+		return nil, errors.New("json-path rules need a path such as $.error.code")
+	}
+	var steps []pathStep
+	for _, part := range strings.Split(p, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && rest == "" {
+			return nil, fmt.Errorf("empty segment in %q", p)
+		}
+		if key != "" {
+			steps = append(steps, pathStep{key: key})
+		}
+		for rest != "" {
+			idx, after, ok := strings.Cut(rest, "]")
+			n, err := strconv.Atoi(idx)
+			if !ok || err != nil || n < 0 {
+				return nil, fmt.Errorf("bad index in %q", part)
+			}
+			steps = append(steps, pathStep{index: n})
+			if after == "" {
+				break
+			}
+			if !strings.HasPrefix(after, "[") {
+				return nil, fmt.Errorf("bad index in %q", part)
+			}
+			rest = after[1:]
+		}
+	}
+	return steps, nil
+}
+
+func lookup(fields map[string]interface{}, path []pathStep) (interface{}, bool) {
+	if fields == nil {
+		return nil, false
+	}
+	var cur interface{} = fields
+	for _, step := range path {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[step.key]
+			if step.key == "" || !ok {
+				return nil, false
+			}
+			cur = v
+		case []interface{}:
+			if step.key != "" || step.index >= len(node) {
+				return nil, false
+			}
+			cur = node[step.index]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// valueString renders a decoded JSON value for pattern matching: strings
+// as is, numbers as written, anything else as compact JSON.
+func valueString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	default:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+}
@@ -0,0 +1,143 @@
+package rules
+
+import (
+	"flowforge/internal/detect"
+	"flowforge/internal/policy"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleRules = `
+rules:
+  - name: rate-limited
+    pattern: 'HTTP 429'
+    stream: stderr
+    window: 10s
+    min_hits: 3
+    action: pause
+  - name: stuck-page
+    match: normalized
+    pattern: 'fetching page 12 of 40'
+    min_hits: 2
+  - name: auth-failed
+    match: json-path
+    path: $.error.details[0].code
+    pattern: '^40[13]$'
+    severity: critical
+    action: kill
+`
+
+func parseRules(t *testing.T, yaml string) *Set {
+	t.Helper()
+	s, err := Parse(strings.NewReader(yaml))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return s
+}
+
+func TestRegexRuleNeedsMinHitsWithinWindow(t *testing.T) {
+	s := parseRules(t, sampleRules)
+	start := time.Now()
+	line := func(offset time.Duration, stream string) []Firing {
+		return s.Observe(detect.Line{Text: "GET /v1/search: HTTP 429 Too Many Requests", Stream: stream, Time: start.Add(offset)})
+	}
+
+	line(0, "stderr")
+	line(time.Second, "stdout") // Wrong stream
+	line(12*time.Second, "stderr")
+	if fired := line(13*time.Second, "stderr"); len(fired) != 0 {
+		t.Fatalf("expected the first hit to have left the window, got %+v", fired)
+	}
+	fired := line(14*time.Second, "stderr")
+	if len(fired) != 1 || fired[0].Rule != "rate-limited" || fired[0].Hits != 3 || fired[0].Action != policy.RuleActionPause {
+		t.Fatalf("expected rate-limited to fire with 3 hits, got %+v", fired)
+	}
+	if fired[0].Severity != SeverityWarning || fired[0].Window != 10*time.Second {
+		t.Fatalf("expected defaults and the configured window, got %+v", fired[0])
+	}
+
+	// At most once per window, then again once min_hits recur.
+	for i := 15; i < 24; i++ {
+		if fired := line(time.Duration(i)*time.Second, "stderr"); len(fired) != 0 {
+			t.Fatalf("expected no second firing within the window, got %+v at %ds", fired, i)
+		}
+	}
+	if fired := line(24*time.Second, "stderr"); len(fired) != 1 {
+		t.Fatalf("expected a second firing once the window passed, got %+v", fired)
+	}
+
+	if taken := s.Take(); len(taken) != 2 {
+		t.Fatalf("expected Take to return both firings, got %d", len(taken))
+	}
+	if taken := s.Take(); len(taken) != 0 {
+		t.Fatalf("expected Take to drain, got %d", len(taken))
+	}
+}
+
+func TestNormalizedAndJSONPathRules(t *testing.T) {
+	s := parseRules(t, sampleRules)
+	now := time.Now()
+
+	s.Observe(detect.Line{Text: "fetching page 3 of 40", Time: now})
+	fired := s.Observe(detect.Line{Text: "fetching page 4 of 40", Time: now})
+	if len(fired) != 1 || fired[0].Rule != "stuck-page" {
+		t.Fatalf("expected the normalized rule to fire on the second variant, got %+v", fired)
+	}
+
+	if fired := s.Observe(detect.Line{Text: `{"error":{"details":[{"code":500}]}}`, Stream: "stdout", Time: now}); len(fired) != 0 {
+		t.Fatalf("expected code 500 not to match, got %+v", fired)
+	}
+	fired = s.Observe(detect.Line{Text: `{"error":{"details":[{"code":403}]}}`, Stream: "stdout", Time: now})
+	if len(fired) != 1 || fired[0].Rule != "auth-failed" || fired[0].Match().Action != policy.RuleActionKill {
+		t.Fatalf("expected auth-failed to fire from the raw JSON line, got %+v", fired)
+	}
+	// Already-decoded fields from structured-log mode are used as is.
+	s2 := parseRules(t, sampleRules)
+	fields := detect.ParseFields(`{"error":{"details":[{"code":"401"}]}}`)
+	if fired := s2.Observe(detect.Line{Text: "ignored", Fields: fields, Time: now}); len(fired) != 1 {
+		t.Fatalf("expected decoded fields to match, got %+v", fired)
+	}
+}
+
+func TestJSONPathWithoutPatternMatchesPresence(t *testing.T) {
+	s := parseRules(t, `
+rules:
+  - name: any-error
+    match: json-path
+    path: error
+`)
+	now := time.Now()
+	if fired := s.Observe(detect.Line{Text: `{"level":"info"}`, Time: now}); len(fired) != 0 {
+		t.Fatalf("expected no match without the field, got %+v", fired)
+	}
+	if fired := s.Observe(detect.Line{Text: `{"error":null}`, Time: now}); len(fired) != 1 {
+		t.Fatalf("expected a match on the field, got %+v", fired)
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	for name, yaml := range map[string]string{
+		"unknown key":    "rules:\n  - name: a\n    pattern: x\n    min_hit: 3\n",
+		"missing name":   "rules:\n  - pattern: x\n",
+		"bad regex":      "rules:\n  - name: a\n    pattern: '('\n",
+		"no pattern":     "rules:\n  - name: a\n",
+		"bad match":      "rules:\n  - name: a\n    match: glob\n    pattern: x\n",
+		"bad stream":     "rules:\n  - name: a\n    pattern: x\n    stream: stdin\n",
+		"bad window":     "rules:\n  - name: a\n    pattern: x\n    window: soon\n",
+		"bad severity":   "rules:\n  - name: a\n    pattern: x\n    severity: high\n",
+		"bad action":     "rules:\n  - name: a\n    pattern: x\n    action: restart\n",
+		"negative hits":  "rules:\n  - name: a\n    pattern: x\n    min_hits: -1\n",
+		"duplicate name": "rules:\n  - name: a\n    pattern: x\n  - name: a\n    pattern: y\n",
+		"missing path":   "rules:\n  - name: a\n    match: json-path\n",
+		"bad index":      "rules:\n  - name: a\n    match: json-path\n    path: items[x]\n",
+	} {
+		if _, err := Parse(strings.NewReader(yaml)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if s, err := Parse(strings.NewReader("")); err != nil || s.Len() != 0 {
+		t.Fatalf("expected an empty file to give no rules, got %v, %v", s, err)
+	}
+}
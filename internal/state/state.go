@@ -2,42 +2,164 @@ package state
 
 import (
 	"encoding/json"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
+// DefaultWorkerID identifies the worker addressed by callers that do not name
+// one explicitly (single-run mode and legacy API routes).
+const DefaultWorkerID = "default"
+
+// ProcessSample is one process's share of a supervised process tree.
+type ProcessSample struct {
+	PID     int     `json:"pid"`
+	PPID    int     `json:"ppid"`
+	Name    string  `json:"name"`
+	CPU     float64 `json:"cpu"`
+	RSSMB   float64 `json:"rss_mb"`
+	Threads int     `json:"threads"`
+	FDs     int     `json:"fds"`
+	State   string  `json:"state,omitempty"` // Kernel state letter: R, S, D, Z, T
+	WChan   string  `json:"wchan,omitempty"` // Kernel function the process waits in
+}
+
 // ProcessState holds the runtime state of the supervised process
 type ProcessState struct {
-	CPU        float64  `json:"cpu"`
-	LastLine   string   `json:"last_line"`
-	Status     string   `json:"status"` // RUNNING, STOPPED, LOOP_DETECTED, WATCHDOG_ALERT
-	Command    string   `json:"command"`
-	Args       []string `json:"args"` // Secure: Exact arguments for restart
-	Dir        string   `json:"dir"`  // Working directory
-	PID        int      `json:"pid"`
-	Reason     string   `json:"reason"`
-	CPUScore   float64  `json:"cpu_score"`
-	Entropy    float64  `json:"entropy_score"`
-	Confidence float64  `json:"confidence_score"`
-	Lifecycle  string   `json:"lifecycle"`
-	Timestamp  int64    `json:"timestamp"`
+	WorkerID   string          `json:"worker_id"`
+	CPU        float64         `json:"cpu"` // Summed across the process tree
+	LastLine   string          `json:"last_line"`
+	Status     string          `json:"status"` // RUNNING, STOPPED, LOOP_DETECTED, WATCHDOG_ALERT
+	Command    string          `json:"command"`
+	Args       []string        `json:"args"` // Secure: Exact arguments for restart
+	Dir        string          `json:"dir"`  // Working directory
+	PID        int             `json:"pid"`
+	Reason     string          `json:"reason"`
+	CPUScore   float64         `json:"cpu_score"`
+	Entropy    float64         `json:"entropy_score"`
+	Confidence float64         `json:"confidence_score"`
+	Lifecycle  string          `json:"lifecycle"`
+	RSSMB      float64         `json:"rss_mb"`
+	Threads    int             `json:"threads"`
+	FDs        int             `json:"fds"`
+	Processes  []ProcessSample `json:"processes,omitempty"`    // Per-process breakdown, busiest first
+	Tokens     int64           `json:"tokens"`                 // Counted in the run's output so far
+	CostUSD    float64         `json:"cost_usd"`               // Estimated spend of Tokens
+	MaxTokens  int64           `json:"max_tokens,omitempty"`   // Token budget; 0 when unlimited
+	MaxCostUSD float64         `json:"max_cost_usd,omitempty"` // Dollar budget; 0 when unlimited
+	Timestamp  int64           `json:"timestamp"`
 }
 
-var (
-	currentState      ProcessState
-	lifecycleOverride string
+// Tracker holds the runtime state of a single supervised worker.
+type Tracker struct {
+	id                string
 	mu                sync.RWMutex
+	current           ProcessState
+	lifecycleOverride string
+}
+
+var (
+	registryMu sync.RWMutex
+	trackers   = map[string]*Tracker{
+		DefaultWorkerID: newTracker(DefaultWorkerID),
+	}
 )
 
-// UpdateState safely updates the global process state
-func UpdateState(cpu float64, lastLine, status, command string, args []string, dir string, pid int) {
-	mu.Lock()
-	defer mu.Unlock()
+func newTracker(id string) *Tracker {
+	return &Tracker{
+		id:      id,
+		current: ProcessState{WorkerID: id},
+	}
+}
+
+// NormalizeWorkerID trims id and maps an empty value to DefaultWorkerID.
+func NormalizeWorkerID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return DefaultWorkerID
+	}
+	return id
+}
+
+// Worker returns the tracker for workerID, creating it on first use.
+func Worker(workerID string) *Tracker {
+	workerID = NormalizeWorkerID(workerID)
+
+	registryMu.RLock()
+	t, ok := trackers[workerID]
+	registryMu.RUnlock()
+	if ok {
+		return t
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if t, ok := trackers[workerID]; ok {
+		return t
+	}
+	t = newTracker(workerID)
+	trackers[workerID] = t
+	return t
+}
+
+// LookupWorker returns the tracker for workerID without creating it.
+func LookupWorker(workerID string) (*Tracker, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	t, ok := trackers[NormalizeWorkerID(workerID)]
+	return t, ok
+}
+
+// RemoveWorker drops the tracker for workerID. The default worker is reset
+// instead of removed so legacy callers always have a target.
+func RemoveWorker(workerID string) {
+	workerID = NormalizeWorkerID(workerID)
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if workerID == DefaultWorkerID {
+		trackers[workerID] = newTracker(workerID)
+		return
+	}
+	delete(trackers, workerID)
+}
+
+// Workers returns a snapshot of every worker that has reported state,
+// ordered by worker ID.
+func Workers() []ProcessState {
+	registryMu.RLock()
+	list := make([]*Tracker, 0, len(trackers))
+	for _, t := range trackers {
+		list = append(list, t)
+	}
+	registryMu.RUnlock()
+
+	out := make([]ProcessState, 0, len(list))
+	for _, t := range list {
+		st := t.Get()
+		if st.Timestamp == 0 {
+			continue
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].WorkerID < out[j].WorkerID })
+	return out
+}
+
+// ID returns the worker ID this tracker reports for.
+func (t *Tracker) ID() string {
+	return t.id
+}
+
+// UpdateState safely replaces the worker's process state.
+func (t *Tracker) UpdateState(cpu float64, lastLine, status, command string, args []string, dir string, pid int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	argsCopy := append([]string(nil), args...)
 
-	currentState = ProcessState{
+	t.current = ProcessState{
+		WorkerID:  t.id,
 		CPU:       cpu,
 		LastLine:  lastLine,
 		Status:    status,
@@ -46,73 +168,129 @@ func UpdateState(cpu float64, lastLine, status, command string, args []string, d
 		Dir:       dir,
 		PID:       pid,
 		Lifecycle: deriveLifecycle(status, pid),
-		Timestamp: time.Now().UnixMilli(),
+		// Spend accumulates over the whole run, restarts included.
+		Tokens:     t.current.Tokens,
+		CostUSD:    t.current.CostUSD,
+		MaxTokens:  t.current.MaxTokens,
+		MaxCostUSD: t.current.MaxCostUSD,
+		Timestamp:  time.Now().UnixMilli(),
 	}
-	if lifecycleOverride != "" {
-		currentState.Lifecycle = lifecycleOverride
+	if t.lifecycleOverride != "" {
+		t.current.Lifecycle = t.lifecycleOverride
 	}
 }
 
 // UpdateDecision updates decision diagnostics while preserving current process identity.
-func UpdateDecision(reason string, cpuScore, entropy, confidence float64) {
-	mu.Lock()
-	defer mu.Unlock()
-	currentState.Reason = reason
-	currentState.CPUScore = cpuScore
-	currentState.Entropy = entropy
-	currentState.Confidence = confidence
-	currentState.Timestamp = time.Now().UnixMilli()
+func (t *Tracker) UpdateDecision(reason string, cpuScore, entropy, confidence float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current.Reason = reason
+	t.current.CPUScore = cpuScore
+	t.current.Entropy = entropy
+	t.current.Confidence = confidence
+	t.current.Timestamp = time.Now().UnixMilli()
 }
 
-// GetState safely returns a copy of the current state
-func GetState() ProcessState {
-	mu.RLock()
-	defer mu.RUnlock()
-	return currentState
+// UpdateTree records process-tree totals and the per-process breakdown.
+func (t *Tracker) UpdateTree(rssMB float64, threads, fds int, processes []ProcessSample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current.RSSMB = rssMB
+	t.current.Threads = threads
+	t.current.FDs = fds
+	t.current.Processes = append([]ProcessSample(nil), processes...)
+	t.current.Timestamp = time.Now().UnixMilli()
 }
 
-// JSON returns the state as a JSON byte slice (for API)
-func JSON() ([]byte, error) {
-	mu.RLock()
-	defer mu.RUnlock()
-	return json.Marshal(currentState)
+// UpdateSpend records the run's token usage and estimated cost against its
+// budgets.
+func (t *Tracker) UpdateSpend(tokens int64, costUSD float64, maxTokens int64, maxCostUSD float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current.Tokens = tokens
+	t.current.CostUSD = costUSD
+	t.current.MaxTokens = maxTokens
+	t.current.MaxCostUSD = maxCostUSD
+	t.current.Timestamp = time.Now().UnixMilli()
+}
+
+// Get safely returns a copy of the worker's current state.
+func (t *Tracker) Get() ProcessState {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	st := t.current
+	st.Processes = append([]ProcessSample(nil), t.current.Processes...)
+	return st
+}
+
+// JSON returns the worker's state as a JSON byte slice (for API).
+func (t *Tracker) JSON() ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return json.Marshal(t.current)
 }
 
 // UpdateLifecycle updates lifecycle metadata while preserving telemetry fields.
 // pid < 0 preserves the existing PID.
-func UpdateLifecycle(lifecycle, status string, pid int) {
-	mu.Lock()
-	defer mu.Unlock()
+func (t *Tracker) UpdateLifecycle(lifecycle, status string, pid int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	lifecycle = strings.ToUpper(strings.TrimSpace(lifecycle))
 	switch lifecycle {
 	case "STARTING", "STOPPING", "FAILED":
-		lifecycleOverride = lifecycle
+		t.lifecycleOverride = lifecycle
 	case "RUNNING", "STOPPED", "":
-		lifecycleOverride = ""
+		t.lifecycleOverride = ""
 	default:
 		// Keep custom lifecycle values as explicit override.
-		lifecycleOverride = lifecycle
+		t.lifecycleOverride = lifecycle
 	}
 
 	if lifecycle != "" {
-		currentState.Lifecycle = lifecycle
-	} else if lifecycleOverride != "" {
-		currentState.Lifecycle = lifecycleOverride
+		t.current.Lifecycle = lifecycle
+	} else if t.lifecycleOverride != "" {
+		t.current.Lifecycle = t.lifecycleOverride
 	}
 	if status != "" {
-		currentState.Status = status
+		t.current.Status = status
 	}
 	if pid >= 0 {
-		currentState.PID = pid
+		t.current.PID = pid
 	}
-	currentState.Timestamp = time.Now().UnixMilli()
+	t.current.Timestamp = time.Now().UnixMilli()
+}
+
+// UpdateState safely updates the default worker's process state
+func UpdateState(cpu float64, lastLine, status, command string, args []string, dir string, pid int) {
+	Worker(DefaultWorkerID).UpdateState(cpu, lastLine, status, command, args, dir, pid)
+}
+
+// UpdateDecision updates decision diagnostics for the default worker.
+func UpdateDecision(reason string, cpuScore, entropy, confidence float64) {
+	Worker(DefaultWorkerID).UpdateDecision(reason, cpuScore, entropy, confidence)
+}
+
+// GetState safely returns a copy of the default worker's state
+func GetState() ProcessState {
+	return Worker(DefaultWorkerID).Get()
+}
+
+// JSON returns the default worker's state as a JSON byte slice (for API)
+func JSON() ([]byte, error) {
+	return Worker(DefaultWorkerID).JSON()
+}
+
+// UpdateLifecycle updates lifecycle metadata for the default worker.
+// pid < 0 preserves the existing PID.
+func UpdateLifecycle(lifecycle, status string, pid int) {
+	Worker(DefaultWorkerID).UpdateLifecycle(lifecycle, status, pid)
 }
 
 func deriveLifecycle(status string, pid int) string {
 	status = strings.ToUpper(strings.TrimSpace(status))
 	switch status {
-	case "STARTING", "RUNNING", "STOPPING", "STOPPED", "FAILED":
+	case "STARTING", "RUNNING", "PAUSED", "STOPPING", "STOPPED", "FAILED", "CRASH_LOOP":
 		return status
 	case "WATCHDOG_ALERT", "WATCHDOG_WARN", "WATCHDOG_CRITICAL", "PROBING_DETECTED":
 		return "RUNNING"
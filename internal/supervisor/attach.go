@@ -0,0 +1,235 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const foreignPollInterval = 100 * time.Millisecond
+
+// ForeignProcess controls a process FlowForge did not start. It cannot reap
+// the process or read its exit status, so liveness is polled and every
+// action is delivered with signals.
+type ForeignProcess struct {
+	pid int
+
+	// groupLeader is true when pid leads its own process group; only then is
+	// the whole group signalled, so an interactive shell that happens to
+	// share the group is never hit.
+	groupLeader bool
+	// adopts is true when pid descends from FlowForge, whose subreaper
+	// then inherits the group's orphans.
+	adopts bool
+
+	mu            sync.Mutex
+	paused        bool
+	stopRequested bool
+
+	waitCh   chan struct{}
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// Attach starts watching pid. It fails if the process does not exist, is
+// FlowForge itself, or cannot be signalled by this user.
+func Attach(pid int) (*ForeignProcess, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("supervisor: invalid pid %d", pid)
+	}
+	if pid == os.Getpid() {
+		return nil, errors.New("supervisor: cannot attach to FlowForge itself")
+	}
+	if err := syscall.Kill(pid, 0); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return nil, fmt.Errorf("supervisor: process %d does not exist", pid)
+		}
+		return nil, fmt.Errorf("supervisor: cannot signal process %d: %w", pid, err)
+	}
+
+	f := &ForeignProcess{
+		pid:    pid,
+		waitCh: make(chan struct{}),
+	}
+	if pgid, err := syscall.Getpgid(pid); err == nil && pgid == pid && pgid != syscall.Getpgrp() {
+		f.groupLeader = true
+	}
+	f.adopts = f.groupLeader && descendsFromSelf(pid)
+	go f.watch()
+	return f, nil
+}
+
+func (f *ForeignProcess) PID() int { return f.pid }
+
+func (f *ForeignProcess) Exited() bool {
+	select {
+	case <-f.waitCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until the process is gone. The exit status of a foreign
+// process is not observable, so Wait always returns nil.
+func (f *ForeignProcess) Wait() error {
+	<-f.waitCh
+	return nil
+}
+
+// Stop sends SIGTERM to the process (and its group when it leads one) plus
+// any descendants, then escalates to SIGKILL after grace.
+func (f *ForeignProcess) Stop(grace time.Duration) error {
+	f.mu.Lock()
+	f.stopRequested = true
+	f.mu.Unlock()
+	f.stopOnce.Do(func() {
+		f.stopErr = f.stopInternal(grace)
+	})
+	return f.stopErr
+}
+
+// StopRequested reports whether Stop was called.
+func (f *ForeignProcess) StopRequested() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stopRequested
+}
+
+// Pause freezes the process and its descendants with SIGSTOP.
+func (f *ForeignProcess) Pause() error {
+	return f.setPaused(true)
+}
+
+// Resume continues a process frozen by Pause.
+func (f *ForeignProcess) Resume() error {
+	return f.setPaused(false)
+}
+
+// Paused reports whether the process is currently frozen by FlowForge.
+func (f *ForeignProcess) Paused() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.paused
+}
+
+func (f *ForeignProcess) setPaused(paused bool) error {
+	if f.Exited() {
+		return errors.New("supervisor: process has exited")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.paused == paused {
+		return nil
+	}
+	sig := syscall.SIGSTOP
+	if !paused {
+		sig = syscall.SIGCONT
+	}
+	if err := f.signal(sig); err != nil {
+		return fmt.Errorf("supervisor: send %v: %w", sig, err)
+	}
+	f.paused = paused
+	return nil
+}
+
+func (f *ForeignProcess) stopInternal(grace time.Duration) error {
+	if grace <= 0 {
+		grace = defaultGrace
+	}
+	if f.Exited() {
+		return nil
+	}
+
+	termErr := f.signal(syscall.SIGTERM)
+	if f.Paused() {
+		// Stopped processes only act on SIGTERM once they are continued.
+		_ = f.Resume()
+	}
+	if f.waitExit(grace) {
+		return nil
+	}
+
+	killErr := f.signal(syscall.SIGKILL)
+	if !f.waitExit(2 * time.Second) {
+		return fmt.Errorf("supervisor: process %d did not exit after SIGKILL", f.pid)
+	}
+	if termErr != nil && killErr != nil {
+		return fmt.Errorf("supervisor: SIGTERM failed: %v; SIGKILL failed: %v", termErr, killErr)
+	}
+	return nil
+}
+
+// signal delivers sig to the group (when pid leads one), the process and
+// every descendant found through /proc, including ones that left the group.
+func (f *ForeignProcess) signal(sig syscall.Signal) error {
+	targets := append([]int{f.pid}, descendantPIDs(f.pid)...)
+	if f.groupLeader {
+		_ = syscall.Kill(-f.pid, sig)
+	}
+	var firstErr error
+	for _, pid := range targets {
+		if err := syscall.Kill(pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (f *ForeignProcess) waitExit(timeout time.Duration) bool {
+	select {
+	case <-f.waitCh:
+		return true
+	case <-time.After(timeout):
+		return f.Exited()
+	}
+}
+
+// watch polls until the process has exited. Group members FlowForge
+// adopts are reaped here until the group is gone.
+func (f *ForeignProcess) watch() {
+	ticker := time.NewTicker(foreignPollInterval)
+	defer ticker.Stop()
+	exited := false
+	for range ticker.C {
+		if f.adopts {
+			reapAdopted(f.pid)
+		}
+		if !exited && !foreignProcessAlive(f.pid) {
+			close(f.waitCh)
+			exited = true
+		}
+		if exited && (!f.adopts || !processGroupExists(f.pid)) {
+			return
+		}
+	}
+}
+
+// descendsFromSelf reports whether FlowForge is an ancestor of pid.
+func descendsFromSelf(pid int) bool {
+	self := os.Getpid()
+	for pid > 1 {
+		info, ok := readProcInfo(pid)
+		if !ok {
+			return false
+		}
+		if info.PPID == self {
+			return true
+		}
+		pid = info.PPID
+	}
+	return false
+}
+
+// foreignProcessAlive treats zombies as exited: their parent, not
+// FlowForge, is responsible for reaping them.
+func foreignProcessAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil && !errors.Is(err, syscall.EPERM) {
+		return false
+	}
+	info, ok := readProcInfo(pid)
+	return !ok || info.State != 'Z'
+}
@@ -0,0 +1,89 @@
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAttachControlsForeignProcessTree(t *testing.T) {
+	// Started outside the supervisor, as an agent launched from a terminal.
+	cmd := exec.Command("sh", "-c", "sleep 120 & wait")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	pid := cmd.Process.Pid
+	waited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(waited)
+	}()
+	t.Cleanup(func() {
+		_ = syscall.Kill(-pid, syscall.SIGKILL)
+		<-waited
+	})
+
+	f, err := Attach(pid)
+	if err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+	var children []int
+	deadline := time.Now().Add(2 * time.Second)
+	for len(children) == 0 && time.Now().Before(deadline) {
+		children = descendantPIDs(pid)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(children) == 0 {
+		t.Fatal("expected sleep child to be found under the attached shell")
+	}
+
+	if err := f.Pause(); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if !waitForProcessState(children[0], 'T', 2*time.Second) {
+		t.Fatalf("expected child %d to be stopped with its parent", children[0])
+	}
+	if err := f.Resume(); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+
+	if err := f.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	select {
+	case <-waitDone(f):
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait() did not return after the foreign process exited")
+	}
+	if !f.Exited() || !f.StopRequested() {
+		t.Fatalf("expected exited and stop-requested, got exited=%v stop=%v", f.Exited(), f.StopRequested())
+	}
+	if !waitForProcessExit(children[0], 2*time.Second) {
+		t.Fatalf("child %d still running after Stop", children[0])
+	}
+}
+
+func TestAttachRejectsMissingAndSelf(t *testing.T) {
+	if _, err := Attach(os.Getpid()); err == nil {
+		t.Fatal("expected error attaching to self")
+	}
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if _, err := Attach(cmd.Process.Pid); err == nil {
+		t.Fatalf("expected error attaching to exited pid %d", cmd.Process.Pid)
+	}
+}
+
+func waitDone(f *ForeignProcess) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		_ = f.Wait()
+		close(done)
+	}()
+	return done
+}
@@ -0,0 +1,43 @@
+package supervisor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// startCommand starts cmd directly inside the cgroup open as dir, using
+// clone3 with CLONE_INTO_CGROUP, so the child's first forks and allocations
+// already count against the limits. Where clone3 is missing (kernels before
+// 5.7, or a seccomp profile that rejects it) a fresh copy of cmd is started
+// outside the group instead; placed reports which of the two happened.
+func startCommand(cmd *exec.Cmd, dir *os.File) (started *exec.Cmd, placed bool, err error) {
+	if dir == nil {
+		return cmd, false, cmd.Start()
+	}
+	attr := *cmd.SysProcAttr
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+	err = cmd.Start()
+	if err == nil {
+		return cmd, true, nil
+	}
+	if !errors.Is(err, syscall.ENOSYS) && !errors.Is(err, syscall.EINVAL) {
+		return cmd, false, err
+	}
+	// An exec.Cmd cannot be started twice.
+	retry := &exec.Cmd{
+		Path:        cmd.Path,
+		Args:        cmd.Args,
+		Env:         cmd.Env,
+		Dir:         cmd.Dir,
+		Stdin:       cmd.Stdin,
+		Stdout:      cmd.Stdout,
+		Stderr:      cmd.Stderr,
+		ExtraFiles:  cmd.ExtraFiles,
+		SysProcAttr: &attr,
+		WaitDelay:   cmd.WaitDelay,
+	}
+	return retry, false, retry.Start()
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+)
+
+// startCommand starts cmd; placing it into a cgroup at fork time is
+// Linux-only, so placed is always false here.
+func startCommand(cmd *exec.Cmd, dir *os.File) (*exec.Cmd, bool, error) {
+	return cmd, false, cmd.Start()
+}
@@ -0,0 +1,406 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	descendantPollInterval = 100 * time.Millisecond
+	// Once the command has exited only escaped descendants can be left, so
+	// the tree is scanned less often.
+	lingeringPollInterval = time.Second
+)
+
+// Escape describes a descendant that left the supervised process group, so
+// signals sent to the group no longer reach it.
+type Escape struct {
+	PID    int
+	PPID   int
+	Name   string
+	Reason string
+}
+
+// procInfo is the part of /proc/<pid>/stat the supervisor needs.
+type procInfo struct {
+	State   byte
+	PPID    int
+	PGID    int
+	Session int
+	// StartTime is in clock ticks since boot; together with the PID it
+	// identifies a process across PID reuse.
+	StartTime uint64
+	Name      string
+}
+
+// liveRoots holds the PIDs of running supervised commands. An orphan
+// reparented to FlowForge belongs to the command whose process group, or a
+// session or group its descendants were seen in, the orphan is in. When
+// nothing ties it to one, it is only claimed while exactly one command is
+// running. While a Start is in flight the new child is not registered yet
+// and could be mistaken for an orphan, so that fallback is off.
+var (
+	liveRootsMu    sync.Mutex
+	liveRoots      = map[int]bool{}
+	startsInFlight int
+	// scopeOwners maps the sessions and process groups, other than
+	// FlowForge's own, that tracked descendants were seen in to the
+	// command they belong to.
+	scopeOwners = map[int]*Supervisor{}
+)
+
+func beginStart() {
+	liveRootsMu.Lock()
+	defer liveRootsMu.Unlock()
+	startsInFlight++
+}
+
+// endStart registers pid as a root; pid 0 means the start failed.
+func endStart(pid int) {
+	liveRootsMu.Lock()
+	defer liveRootsMu.Unlock()
+	startsInFlight--
+	if pid > 0 {
+		liveRoots[pid] = true
+	}
+}
+
+func unregisterRoot(pid int) {
+	liveRootsMu.Lock()
+	defer liveRootsMu.Unlock()
+	delete(liveRoots, pid)
+}
+
+// startPending reports whether a Start has forked a child it has not
+// registered yet.
+func startPending() bool {
+	liveRootsMu.Lock()
+	defer liveRootsMu.Unlock()
+	return startsInFlight > 0
+}
+
+func soleRoot() (int, bool) {
+	liveRootsMu.Lock()
+	defer liveRootsMu.Unlock()
+	if len(liveRoots) != 1 || startsInFlight > 0 {
+		return 0, false
+	}
+	for pid := range liveRoots {
+		return pid, true
+	}
+	return 0, false
+}
+
+func isRoot(pid int) bool {
+	liveRootsMu.Lock()
+	defer liveRootsMu.Unlock()
+	return liveRoots[pid]
+}
+
+// claimScope records session or process group id for s unless another
+// command already holds it.
+func claimScope(id int, s *Supervisor) {
+	liveRootsMu.Lock()
+	defer liveRootsMu.Unlock()
+	if _, ok := scopeOwners[id]; !ok {
+		scopeOwners[id] = s
+	}
+}
+
+func scopeOwner(id int) *Supervisor {
+	liveRootsMu.Lock()
+	defer liveRootsMu.Unlock()
+	return scopeOwners[id]
+}
+
+func releaseScopes(s *Supervisor) {
+	liveRootsMu.Lock()
+	defer liveRootsMu.Unlock()
+	for id, owner := range scopeOwners {
+		if owner == s {
+			delete(scopeOwners, id)
+		}
+	}
+}
+
+// trackDescendants records every process below the command until the
+// command has exited and no tracked descendant is left.
+func (s *Supervisor) trackDescendants() {
+	defer releaseScopes(s)
+	for {
+		s.scanDescendants()
+		if !s.Exited() {
+			s.sampleVMStat()
+		}
+		interval := descendantPollInterval
+		if s.Exited() {
+			if len(s.liveTracked(listProcs())) == 0 {
+				return
+			}
+			interval = lingeringPollInterval
+		}
+		time.Sleep(interval)
+	}
+}
+
+// scanDescendants adds new descendants to the tracked set, reaps tracked
+// zombies FlowForge adopted, and reports descendants that left the group.
+func (s *Supervisor) scanDescendants() {
+	root := s.PID()
+	if root <= 0 {
+		return
+	}
+	procs := listProcs()
+	self := os.Getpid()
+	selfInfo, selfSeen := procs[self]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tracked == nil {
+		s.tracked = make(map[int]procInfo)
+	}
+
+	var roots []int
+	if !s.Exited() {
+		// After exit the PID may be reused by an unrelated process.
+		roots = append(roots, root)
+	}
+	for pid, info := range s.tracked {
+		if cur, ok := procs[pid]; ok && cur.StartTime == info.StartTime {
+			roots = append(roots, pid)
+		} else {
+			delete(s.tracked, pid)
+		}
+	}
+	// As subreaper, FlowForge inherits orphans whose parent already exited
+	// before they were seen, e.g. after a quick double fork.
+	sole, isSole := soleRoot()
+	for pid, info := range procs {
+		if info.PPID != self || pid == root || isRoot(pid) {
+			continue
+		}
+		if _, seen := s.tracked[pid]; seen {
+			continue
+		}
+		owner := scopeOwner(info.Session)
+		if owner == nil {
+			owner = scopeOwner(info.PGID)
+		}
+		if info.PGID == root || owner == s || (owner == nil && isSole && sole == root) {
+			s.tracked[pid] = info
+			roots = append(roots, pid)
+		}
+	}
+	for _, pid := range descendantsIn(procs, roots) {
+		if _, seen := s.tracked[pid]; !seen {
+			s.tracked[pid] = procs[pid]
+		}
+	}
+	// Remember where descendants went, so their orphans can be claimed
+	// after the parent that linked them to the command is gone.
+	for pid := range s.tracked {
+		cur, ok := procs[pid]
+		if !ok || !selfSeen {
+			continue
+		}
+		if cur.Session != selfInfo.Session {
+			claimScope(cur.Session, s)
+		}
+		if cur.PGID != selfInfo.PGID {
+			claimScope(cur.PGID, s)
+		}
+	}
+
+	// Adopted zombies are reaped even if no command claimed them: group
+	// members that exited before a scan saw them, and anything outside
+	// FlowForge's own process group, where the only children it waits for
+	// itself are the roots. A root whose Start is in flight is not
+	// registered yet, so those zombies wait for the next scan.
+	pending := startPending()
+	for pid, info := range procs {
+		if info.State != 'Z' || info.PPID != self || pid == root || isRoot(pid) {
+			continue
+		}
+		if info.PGID == root || (selfSeen && !pending && info.PGID != selfInfo.PGID) {
+			var ws syscall.WaitStatus
+			_, _ = syscall.Wait4(pid, &ws, syscall.WNOHANG, nil)
+		}
+	}
+
+	rootInfo, rootAlive := procs[root]
+	for pid, info := range s.tracked {
+		cur := procs[pid]
+		if cur.State == 'Z' {
+			if cur.PPID == self {
+				var ws syscall.WaitStatus
+				_, _ = syscall.Wait4(pid, &ws, syscall.WNOHANG, nil)
+			}
+			continue
+		}
+		if cur.PGID == root || s.escaped[pid] {
+			continue
+		}
+		if s.escaped == nil {
+			s.escaped = make(map[int]bool)
+		}
+		s.escaped[pid] = true
+		reason := fmt.Sprintf("moved to process group %d", cur.PGID)
+		if cur.Session == pid || (rootAlive && cur.Session != rootInfo.Session) {
+			reason = "started a new session (setsid)"
+		}
+		if s.onEscape != nil {
+			go s.onEscape(Escape{PID: pid, PPID: cur.PPID, Name: info.Name, Reason: reason})
+		}
+	}
+}
+
+// reapAdopted reaps zombies FlowForge adopted from process group pgid. The
+// leader is left to its own parent.
+func reapAdopted(pgid int) {
+	self := os.Getpid()
+	for pid, info := range listProcs() {
+		if info.State == 'Z' && info.PPID == self && info.PGID == pgid && pid != pgid {
+			var ws syscall.WaitStatus
+			_, _ = syscall.Wait4(pid, &ws, syscall.WNOHANG, nil)
+		}
+	}
+}
+
+// liveTracked returns tracked descendants that are still running.
+func (s *Supervisor) liveTracked(procs map[int]procInfo) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []int
+	for pid, info := range s.tracked {
+		if cur, ok := procs[pid]; ok && cur.StartTime == info.StartTime && cur.State != 'Z' {
+			out = append(out, pid)
+		}
+	}
+	return out
+}
+
+// signalDescendants sends sig to every live tracked descendant, including
+// ones outside the process group.
+func (s *Supervisor) signalDescendants(sig syscall.Signal) error {
+	s.scanDescendants()
+	var firstErr error
+	for _, pid := range s.liveTracked(listProcs()) {
+		if err := syscall.Kill(pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// waitForTreeExit waits until the process group and every tracked
+// descendant are gone.
+func (s *Supervisor) waitForTreeExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		s.scanDescendants()
+		if !processGroupExists(pid) && len(s.liveTracked(listProcs())) == 0 {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+}
+
+// listProcs reads every process from /proc.
+func listProcs() map[int]procInfo {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	procs := make(map[int]procInfo, len(entries))
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		if info, ok := readProcInfo(pid); ok {
+			procs[pid] = info
+		}
+	}
+	return procs
+}
+
+// descendantsIn walks parent links below roots, breadth first.
+func descendantsIn(procs map[int]procInfo, roots []int) []int {
+	children := make(map[int][]int)
+	for pid, info := range procs {
+		children[info.PPID] = append(children[info.PPID], pid)
+	}
+
+	var out []int
+	seen := make(map[int]bool, len(roots))
+	var queue []int
+	for _, r := range roots {
+		seen[r] = true
+		queue = append(queue, children[r]...)
+	}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		out = append(out, pid)
+		queue = append(queue, children[pid]...)
+	}
+	return out
+}
+
+// descendantPIDs returns the processes currently below root.
+func descendantPIDs(root int) []int {
+	return descendantsIn(listProcs(), []int{root})
+}
+
+// readProcInfo parses /proc/<pid>/stat.
+func readProcInfo(pid int) (procInfo, bool) {
+	blob, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procInfo{}, false
+	}
+	return parseProcStat(string(blob))
+}
+
+func parseProcStat(stat string) (procInfo, bool) {
+	// The command name may contain spaces or parentheses; fields resume
+	// after the last ')'.
+	open := strings.IndexByte(stat, '(')
+	i := strings.LastIndexByte(stat, ')')
+	if open < 0 || i < open {
+		return procInfo{}, false
+	}
+	// fields[0] is field 3 (state) in proc(5); starttime is field 22.
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 20 || len(fields[0]) == 0 {
+		return procInfo{}, false
+	}
+	ppid, err1 := strconv.Atoi(fields[1])
+	pgid, err2 := strconv.Atoi(fields[2])
+	session, err3 := strconv.Atoi(fields[3])
+	start, err4 := strconv.ParseUint(fields[19], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return procInfo{}, false
+	}
+	return procInfo{
+		State:     fields[0][0],
+		PPID:      ppid,
+		PGID:      pgid,
+		Session:   session,
+		StartTime: start,
+		Name:      stat[open+1 : i],
+	}, true
+}
@@ -0,0 +1,176 @@
+package supervisor
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStopTerminatesDescendantsThatLeftTheGroup(t *testing.T) {
+	// The inner shell starts a new session, backgrounds sleep and exits at
+	// once, so sleep is orphaned outside the group: a classic daemonizing
+	// double fork.
+	cmd := exec.Command("sh", "-c", `setsid sh -c 'sleep 120 & echo $!'; sleep 120`)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe: %v", err)
+	}
+
+	s := New(cmd)
+	escapes := make(chan Escape, 4)
+	s.OnEscape(func(e Escape) { escapes <- e })
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	escapedPID := readPIDLine(t, bufio.NewReader(stdout), "escaped")
+	t.Cleanup(func() { _ = syscall.Kill(escapedPID, syscall.SIGKILL) })
+
+	if e := waitForEscape(t, escapes, escapedPID); e.Reason != "started a new session (setsid)" {
+		t.Fatalf("unexpected escape reason %q", e.Reason)
+	}
+	if info, ok := readProcInfo(escapedPID); !ok || info.PGID == s.PID() {
+		t.Fatalf("expected pid %d outside group %d, got %+v", escapedPID, s.PID(), info)
+	}
+
+	if err := s.Stop(2 * time.Second); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if !waitForProcessExit(escapedPID, 2*time.Second) {
+		t.Fatalf("escaped process %d is still running after stop", escapedPID)
+	}
+}
+
+func TestOrphansAreAttributedWithSeveralCommandsRunning(t *testing.T) {
+	// With a second command running, the orphaned sleep can only be tied
+	// to its command through the session its parent was seen in.
+	other := New(exec.Command("sleep", "120"))
+	if err := other.Start(); err != nil {
+		t.Fatalf("start other: %v", err)
+	}
+	defer func() { _ = other.Stop(time.Second) }()
+	otherEscapes := make(chan Escape, 4)
+	other.OnEscape(func(e Escape) { otherEscapes <- e })
+
+	cmd := exec.Command("sh", "-c", `setsid sh -c 'sleep 120 & echo $!; sleep 0.5'; sleep 120`)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe: %v", err)
+	}
+	s := New(cmd)
+	escapes := make(chan Escape, 4)
+	s.OnEscape(func(e Escape) { escapes <- e })
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	escapedPID := readPIDLine(t, bufio.NewReader(stdout), "escaped")
+	t.Cleanup(func() { _ = syscall.Kill(escapedPID, syscall.SIGKILL) })
+	waitForEscape(t, escapes, escapedPID)
+
+	// Once the setsid'd parent exits, sleep is FlowForge's orphan.
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		info, ok := readProcInfo(escapedPID)
+		if ok && info.PPID == os.Getpid() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected pid %d to be reparented to FlowForge, got %+v", escapedPID, info)
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+	if err := s.Stop(2 * time.Second); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if !waitForProcessExit(escapedPID, 2*time.Second) {
+		t.Fatalf("orphan %d is still running or unreaped after stop", escapedPID)
+	}
+	select {
+	case e := <-otherEscapes:
+		t.Fatalf("unrelated command was blamed for %+v", e)
+	default:
+	}
+}
+
+func TestUnclaimedOrphansAreReaped(t *testing.T) {
+	other := New(exec.Command("sleep", "120"))
+	if err := other.Start(); err != nil {
+		t.Fatalf("start other: %v", err)
+	}
+	defer func() { _ = other.Stop(time.Second) }()
+
+	cmd := exec.Command("sh", "-c", `setsid sh -c 'sleep 0.3 & echo $!'; sleep 120`)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe: %v", err)
+	}
+	s := New(cmd)
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer func() { _ = s.Stop(time.Second) }()
+	orphan := readPIDLine(t, bufio.NewReader(stdout), "orphan")
+	if !waitForProcessExit(orphan, 3*time.Second) {
+		t.Fatalf("orphan %d was left as a zombie", orphan)
+	}
+}
+
+func TestUnclaimedOrphansInOwnSessionAreReaped(t *testing.T) {
+	other := New(exec.Command("sleep", "120"))
+	if err := other.Start(); err != nil {
+		t.Fatalf("start other: %v", err)
+	}
+	defer func() { _ = other.Stop(time.Second) }()
+
+	// The orphan stays in FlowForge's session but in a group of its own
+	// that no scan saw before its parent exited.
+	script := "import os, sys, time\nos.setpgid(0, 0)\npid = os.fork()\nif pid == 0:\n    time.sleep(0.3)\n    os._exit(0)\nprint(pid, flush=True)"
+	cmd := exec.Command("sh", "-c", `python3 -c "$0"; sleep 120`, script)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe: %v", err)
+	}
+	s := New(cmd)
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer func() { _ = s.Stop(time.Second) }()
+	orphan := readPIDLine(t, bufio.NewReader(stdout), "orphan")
+	if !waitForProcessExit(orphan, 3*time.Second) {
+		t.Fatalf("orphan %d was left as a zombie", orphan)
+	}
+}
+
+// waitForEscape returns the report for pid, skipping reports for other
+// processes, such as the parent a double fork went through.
+func waitForEscape(t *testing.T, escapes <-chan Escape, pid int) Escape {
+	t.Helper()
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case e := <-escapes:
+			if e.PID == pid {
+				return e
+			}
+		case <-deadline:
+			t.Fatalf("no escape reported for pid %d", pid)
+		}
+	}
+}
+
+func TestParseProcStatHandlesParenthesesInName(t *testing.T) {
+	stat := "4242 (a) b (c) S 100 4242 4242 0 -1 4194560 120 0 0 0 1 2 0 0 20 0 1 0 987654 1000 200\n"
+	info, ok := parseProcStat(stat)
+	if !ok {
+		t.Fatal("expected stat line to parse")
+	}
+	want := procInfo{State: 'S', PPID: 100, PGID: 4242, Session: 4242, StartTime: 987654, Name: "a) b (c"}
+	if info != want {
+		t.Fatalf("parseProcStat() = %+v, want %+v", info, want)
+	}
+	if _, ok := parseProcStat("4242 (truncated"); ok {
+		t.Fatal("expected truncated stat line to be rejected")
+	}
+}
@@ -0,0 +1,249 @@
+package supervisor
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ExitClass is the coarse outcome of one run of a command.
+type ExitClass string
+
+const (
+	ExitClassClean   ExitClass = "clean"   // status 0
+	ExitClassError   ExitClass = "error"   // non-zero status
+	ExitClassCrash   ExitClass = "crash"   // fatal signal such as SIGSEGV, or a core dump
+	ExitClassOOM     ExitClass = "oom"     // killed by the kernel OOM killer
+	ExitClassSignal  ExitClass = "signal"  // any other signal FlowForge did not send
+	ExitClassStopped ExitClass = "stopped" // ended after Stop was requested
+)
+
+// ExitInfo describes how a command ended, from its wait status and the
+// rusage wait4 returned for it.
+type ExitInfo struct {
+	Class      ExitClass
+	ExitCode   int    // -1 when the command was killed by a signal
+	Signal     string // e.g. "SIGKILL"; empty for a normal exit
+	CoreDumped bool
+	OOMKilled  bool
+	// OOMSource is "cgroup" when memory.events counted the kill, or
+	// "vmstat" when, without a cgroup, the system-wide oom_kill counter
+	// moved just before the command died from SIGKILL.
+	OOMSource  string
+	CPUSeconds float64 // user + system time of the command and its reaped children
+	MaxRSSMB   float64
+	Runtime    time.Duration
+}
+
+// vmstatOOMWindow is how close to a SIGKILL the system-wide oom_kill
+// counter must have moved to explain it. Any OOM kill on the host moves
+// that counter, so a kill from hours earlier says nothing about this exit.
+const vmstatOOMWindow = 2 * time.Second
+
+// vmstatSampleInterval is how often the oom_kill counter is sampled while
+// the command runs; it must stay below vmstatOOMWindow.
+const vmstatSampleInterval = time.Second
+
+// oomBaseline is a snapshot of the OOM kill counters, taken when the
+// command starts and again when it exits.
+type oomBaseline struct {
+	cgroupKills int64
+	vmstatKills int64
+	vmstatOK    bool
+}
+
+// ExitInfo classifies how the command ended. It is only meaningful after
+// Wait has returned.
+func (s *Supervisor) ExitInfo() ExitInfo {
+	if !s.Exited() {
+		return ExitInfo{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ps *os.ProcessState
+	if s.cmd != nil {
+		ps = s.cmd.ProcessState
+	}
+	info := exitInfoFromState(ps)
+	info.Runtime = s.exitedAt.Sub(s.startedAt)
+
+	if info.Signal == "SIGKILL" {
+		switch {
+		case s.oomAtExit.cgroupKills > s.oomAtStart.cgroupKills:
+			info.OOMKilled, info.OOMSource = true, "cgroup"
+		case s.cgroup == nil && !s.stopBeforeExit && vmstatOOM(s.vmstatSample, s.oomAtExit, s.vmstatSampledAt, s.exitedAt):
+			info.OOMKilled, info.OOMSource = true, "vmstat"
+		}
+	} else if s.oomAtExit.cgroupKills > s.oomAtStart.cgroupKills && info.Class != ExitClassClean {
+		// The kernel killed another process in the run's cgroup and the
+		// command failed as a result.
+		info.OOMKilled, info.OOMSource = true, "cgroup"
+	}
+
+	switch {
+	case info.OOMKilled:
+		info.Class = ExitClassOOM
+	case s.stopBeforeExit:
+		info.Class = ExitClassStopped
+	}
+	return info
+}
+
+// String summarizes the exit, e.g. "killed by SIGSEGV (core dumped)".
+func (i ExitInfo) String() string {
+	var out string
+	switch {
+	case i.Signal != "":
+		out = "killed by " + i.Signal
+	default:
+		out = "exited with code " + strconv.Itoa(i.ExitCode)
+	}
+	switch {
+	case i.OOMKilled:
+		out += " (OOM kill, " + i.OOMSource + ")"
+	case i.CoreDumped:
+		out += " (core dumped)"
+	}
+	return out
+}
+
+func exitInfoFromState(ps *os.ProcessState) ExitInfo {
+	info := ExitInfo{Class: ExitClassError, ExitCode: -1}
+	if ps == nil {
+		return info
+	}
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok && ru != nil {
+		info.CPUSeconds = timevalSeconds(ru.Utime) + timevalSeconds(ru.Stime)
+		// Linux reports ru_maxrss in kilobytes.
+		info.MaxRSSMB = float64(ru.Maxrss) / 1024.0
+	}
+	ws, ok := ps.Sys().(syscall.WaitStatus)
+	if !ok {
+		info.ExitCode = ps.ExitCode()
+		if info.ExitCode == 0 {
+			info.Class = ExitClassClean
+		}
+		return info
+	}
+
+	switch {
+	case ws.Exited():
+		info.ExitCode = ws.ExitStatus()
+		if info.ExitCode == 0 {
+			info.Class = ExitClassClean
+		}
+	case ws.Signaled():
+		sig := ws.Signal()
+		info.Signal = signalName(sig)
+		info.CoreDumped = ws.CoreDump()
+		info.Class = ExitClassSignal
+		if info.CoreDumped || isCrashSignal(sig) {
+			info.Class = ExitClassCrash
+		}
+	}
+	return info
+}
+
+func isCrashSignal(sig syscall.Signal) bool {
+	switch sig {
+	case syscall.SIGSEGV, syscall.SIGBUS, syscall.SIGILL, syscall.SIGFPE, syscall.SIGABRT, syscall.SIGSYS, syscall.SIGTRAP:
+		return true
+	}
+	return false
+}
+
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGABRT: "SIGABRT",
+	syscall.SIGALRM: "SIGALRM",
+	syscall.SIGBUS:  "SIGBUS",
+	syscall.SIGFPE:  "SIGFPE",
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGILL:  "SIGILL",
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGKILL: "SIGKILL",
+	syscall.SIGPIPE: "SIGPIPE",
+	syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGSEGV: "SIGSEGV",
+	syscall.SIGSYS:  "SIGSYS",
+	syscall.SIGTERM: "SIGTERM",
+	syscall.SIGTRAP: "SIGTRAP",
+	syscall.SIGUSR1: "SIGUSR1",
+	syscall.SIGUSR2: "SIGUSR2",
+	syscall.SIGXCPU: "SIGXCPU",
+	syscall.SIGXFSZ: "SIGXFSZ",
+}
+
+func signalName(sig syscall.Signal) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	return "SIG" + strconv.Itoa(int(sig))
+}
+
+func timevalSeconds(tv syscall.Timeval) float64 {
+	return float64(tv.Sec) + float64(tv.Usec)/1e6
+}
+
+// vmstatOOM reports whether the oom_kill counter moved between sample,
+// taken at sampledAt, and the reading at exit, with the sample recent
+// enough that the move happened just before the exit.
+func vmstatOOM(sample, atExit oomBaseline, sampledAt, exitedAt time.Time) bool {
+	if !sample.vmstatOK || !atExit.vmstatOK || exitedAt.Sub(sampledAt) > vmstatOOMWindow {
+		return false
+	}
+	return atExit.vmstatKills > sample.vmstatKills
+}
+
+// sampleVMStat refreshes the oom_kill reading ExitInfo compares against.
+func (s *Supervisor) sampleVMStat() {
+	s.mu.Lock()
+	due := time.Since(s.vmstatSampledAt) >= vmstatSampleInterval
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+	kills, ok := readVMStatOOMKills()
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exitedAt.IsZero() {
+		s.vmstatSample = oomBaseline{vmstatKills: kills, vmstatOK: ok}
+		s.vmstatSampledAt = now
+	}
+}
+
+// readOOMCounters snapshots the cgroup and system-wide OOM kill counters.
+func (s *Supervisor) readOOMCounters() oomBaseline {
+	var b oomBaseline
+	if s.cgroup != nil {
+		if st, err := s.cgroup.Stats(); err == nil {
+			b.cgroupKills = st.OOMKills
+		}
+	}
+	b.vmstatKills, b.vmstatOK = readVMStatOOMKills()
+	return b
+}
+
+// readVMStatOOMKills returns the oom_kill counter from /proc/vmstat
+// (Linux 4.13+).
+func readVMStatOOMKills() (int64, bool) {
+	f, err := os.Open("/proc/vmstat")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || name != "oom_kill" {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,135 @@
+package supervisor
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestExitInfoClassifiesOutcomes(t *testing.T) {
+	cases := []struct {
+		name    string
+		script  string
+		class   ExitClass
+		code    int
+		signal  string
+		summary string
+	}{
+		{name: "clean", script: "exit 0", class: ExitClassClean, code: 0, summary: "exited with code 0"},
+		{name: "error", script: "exit 3", class: ExitClassError, code: 3, summary: "exited with code 3"},
+		{name: "crash", script: "kill -SEGV $$", class: ExitClassCrash, code: -1, signal: "SIGSEGV", summary: "killed by SIGSEGV"},
+		{name: "signal", script: "kill -USR1 $$", class: ExitClassSignal, code: -1, signal: "SIGUSR1", summary: "killed by SIGUSR1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New(exec.Command("sh", "-c", tc.script))
+			if err := s.Start(); err != nil {
+				t.Fatalf("start: %v", err)
+			}
+			_ = s.Wait()
+
+			info := s.ExitInfo()
+			if info.Class != tc.class || info.ExitCode != tc.code || info.Signal != tc.signal {
+				t.Fatalf("ExitInfo() = %+v, want class=%s code=%d signal=%q", info, tc.class, tc.code, tc.signal)
+			}
+			if got := info.String(); got != tc.summary {
+				t.Fatalf("String() = %q, want %q", got, tc.summary)
+			}
+			if info.OOMKilled {
+				t.Fatalf("unexpected OOM classification: %+v", info)
+			}
+			if info.Runtime <= 0 {
+				t.Fatalf("expected a positive runtime, got %s", info.Runtime)
+			}
+		})
+	}
+}
+
+func TestExitInfoReportsStopAndResourceUsage(t *testing.T) {
+	cmd := exec.Command("python3", "-c", "import time\nx = bytearray(32 << 20)\nend = time.time() + 0.3\nwhile time.time() < end: pass\nprint('ready', flush=True)\ntime.sleep(120)")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe: %v", err)
+	}
+	s := New(cmd)
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	buf := make([]byte, 16)
+	if _, err := stdout.Read(buf); err != nil {
+		t.Fatalf("read ready line: %v", err)
+	}
+
+	if err := s.Stop(2 * time.Second); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	_ = s.Wait()
+	info := s.ExitInfo()
+	if info.Class != ExitClassStopped {
+		t.Fatalf("expected stopped class, got %+v", info)
+	}
+	if info.CPUSeconds < 0.1 {
+		t.Fatalf("expected at least 0.1s of CPU time, got %+v", info)
+	}
+	if info.MaxRSSMB < 32 {
+		t.Fatalf("expected peak RSS of at least 32MB, got %+v", info)
+	}
+}
+
+func TestExitInfoIgnoresStopAfterExit(t *testing.T) {
+	s := New(exec.Command("sh", "-c", "exit 2"))
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	_ = s.Wait()
+	_ = s.Stop(100 * time.Millisecond)
+
+	if info := s.ExitInfo(); info.Class != ExitClassError || info.ExitCode != 2 {
+		t.Fatalf("expected the original error exit to stand, got %+v", info)
+	}
+}
+
+func TestVMStatOOMNeedsARecentMove(t *testing.T) {
+	exitedAt := time.Now()
+	before := oomBaseline{vmstatKills: 4, vmstatOK: true}
+	moved := oomBaseline{vmstatKills: 5, vmstatOK: true}
+	cases := []struct {
+		name      string
+		sample    oomBaseline
+		atExit    oomBaseline
+		sampledAt time.Time
+		want      bool
+	}{
+		{name: "moved since a recent sample", sample: before, atExit: moved, sampledAt: exitedAt.Add(-time.Second), want: true},
+		{name: "unchanged since a recent sample", sample: moved, atExit: moved, sampledAt: exitedAt.Add(-time.Second)},
+		{name: "moved since a stale sample", sample: before, atExit: moved, sampledAt: exitedAt.Add(-time.Minute)},
+		{name: "vmstat unreadable", sample: oomBaseline{}, atExit: moved, sampledAt: exitedAt.Add(-time.Second)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := vmstatOOM(tc.sample, tc.atExit, tc.sampledAt, exitedAt); got != tc.want {
+				t.Fatalf("vmstatOOM() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExitInfoReportsExternalKillAsSignal(t *testing.T) {
+	s := New(exec.Command("sleep", "30"))
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	// Pretend the host OOM killer fired early in the run, well before the
+	// kill; only the samples taken since then may explain the exit.
+	s.mu.Lock()
+	s.oomAtStart = oomBaseline{vmstatKills: -1, vmstatOK: true}
+	s.mu.Unlock()
+	time.Sleep(1500 * time.Millisecond)
+	_ = s.cmd.Process.Kill()
+	_ = s.Wait()
+
+	info := s.ExitInfo()
+	if info.Class != ExitClassSignal || info.Signal != "SIGKILL" || info.OOMKilled {
+		t.Fatalf("expected a plain SIGKILL, got %+v", info)
+	}
+}
@@ -0,0 +1,117 @@
+package supervisor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// RestartPolicy decides whether a supervised command is started again after
+// it exits.
+type RestartPolicy string
+
+const (
+	RestartNever          RestartPolicy = "never"
+	RestartOnFailure      RestartPolicy = "on-failure"
+	RestartAlways         RestartPolicy = "always"
+	RestartOnPolicyBreach RestartPolicy = "on-policy-breach"
+)
+
+// ParseRestartPolicy normalizes a configured policy name. Empty means never.
+func ParseRestartPolicy(raw string) (RestartPolicy, error) {
+	switch p := RestartPolicy(strings.ToLower(strings.TrimSpace(raw))); p {
+	case "":
+		return RestartNever, nil
+	case RestartNever, RestartOnFailure, RestartAlways, RestartOnPolicyBreach:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown restart policy %q (want never|on-failure|always|on-policy-breach)", raw)
+	}
+}
+
+// ExitCause classifies why one run of the command ended.
+type ExitCause int
+
+const (
+	// ExitClean means the command exited with status 0 on its own.
+	ExitClean ExitCause = iota
+	// ExitFailure means the command exited non-zero or died from a signal
+	// that FlowForge did not send.
+	ExitFailure
+	// ExitPolicyBreach means FlowForge stopped the command after a policy
+	// decision or safety limit.
+	ExitPolicyBreach
+	// ExitOperator means a user or operator asked for the stop.
+	ExitOperator
+)
+
+func (c ExitCause) String() string {
+	switch c {
+	case ExitClean:
+		return "clean exit"
+	case ExitFailure:
+		return "failure"
+	case ExitPolicyBreach:
+		return "policy breach"
+	case ExitOperator:
+		return "operator stop"
+	default:
+		return "unknown"
+	}
+}
+
+// ShouldRestart reports whether the policy restarts after an exit of the
+// given cause. Operator stops are never restarted.
+func (p RestartPolicy) ShouldRestart(cause ExitCause) bool {
+	switch cause {
+	case ExitOperator:
+		return false
+	case ExitClean:
+		return p == RestartAlways
+	case ExitFailure:
+		return p == RestartAlways || p == RestartOnFailure
+	case ExitPolicyBreach:
+		return p == RestartAlways || p == RestartOnPolicyBreach
+	default:
+		return false
+	}
+}
+
+// Backoff computes exponential delays between restart attempts.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// Jitter spreads each delay by up to ±Jitter of its value (0..1).
+	Jitter float64
+}
+
+// Delay returns the wait before restart attempt n (1-based). rnd must return
+// values in [0, 1); nil disables jitter.
+func (b Backoff) Delay(attempt int, rnd func() float64) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	initial := b.Initial
+	if initial <= 0 {
+		initial = time.Second
+	}
+	mult := b.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+
+	delay := float64(initial) * math.Pow(mult, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 && rnd != nil {
+		jitter := math.Min(b.Jitter, 1)
+		delay += delay * jitter * (2*rnd() - 1)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,54 @@
+package supervisor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartPolicyShouldRestart(t *testing.T) {
+	cases := []struct {
+		policy RestartPolicy
+		cause  ExitCause
+		want   bool
+	}{
+		{RestartNever, ExitFailure, false},
+		{RestartOnFailure, ExitFailure, true},
+		{RestartOnFailure, ExitClean, false},
+		{RestartOnFailure, ExitPolicyBreach, false},
+		{RestartAlways, ExitClean, true},
+		{RestartAlways, ExitPolicyBreach, true},
+		{RestartAlways, ExitOperator, false},
+		{RestartOnPolicyBreach, ExitPolicyBreach, true},
+		{RestartOnPolicyBreach, ExitFailure, false},
+	}
+	for _, tc := range cases {
+		if got := tc.policy.ShouldRestart(tc.cause); got != tc.want {
+			t.Errorf("%s.ShouldRestart(%s) = %v, want %v", tc.policy, tc.cause, got, tc.want)
+		}
+	}
+
+	if _, err := ParseRestartPolicy("sometimes"); err == nil {
+		t.Fatal("expected error for unknown restart policy")
+	}
+	if p, err := ParseRestartPolicy(" On-Failure "); err != nil || p != RestartOnFailure {
+		t.Fatalf("ParseRestartPolicy() = %q, %v", p, err)
+	}
+}
+
+func TestBackoffDelayGrowsCapsAndJitters(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := b.Delay(i+1, nil); got != w*time.Millisecond {
+			t.Fatalf("Delay(%d) = %s, want %s", i+1, got, w*time.Millisecond)
+		}
+	}
+
+	b.Jitter = 0.5
+	if got := b.Delay(1, func() float64 { return 0 }); got != 50*time.Millisecond {
+		t.Fatalf("Delay with minimum jitter = %s, want 50ms", got)
+	}
+	if got := b.Delay(1, func() float64 { return 0.999999 }); got < 149*time.Millisecond || got > 150*time.Millisecond {
+		t.Fatalf("Delay with maximum jitter = %s, want ~150ms", got)
+	}
+}
@@ -0,0 +1,19 @@
+package supervisor
+
+import (
+	"sync"
+	"syscall"
+)
+
+const prSetChildSubreaper = 36
+
+var subreaperOnce sync.Once
+
+// enableSubreaper makes FlowForge the reaper for orphaned descendants, so a
+// process that double-forks or calls setsid() is reparented here instead of
+// to init and stays visible in /proc under FlowForge's PID.
+func enableSubreaper() {
+	subreaperOnce.Do(func() {
+		_, _, _ = syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0)
+	})
+}
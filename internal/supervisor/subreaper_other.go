@@ -0,0 +1,7 @@
+//go:build !linux
+
+package supervisor
+
+// enableSubreaper is Linux-only; elsewhere descendants are tracked by
+// polling alone.
+func enableSubreaper() {}
@@ -2,6 +2,7 @@ package supervisor
 
 import (
 	"errors"
+	"flowforge/internal/cgroup"
 	"fmt"
 	"os"
 	"os/exec"
@@ -14,18 +15,37 @@ import (
 const defaultGrace = 5 * time.Second
 
 // Supervisor starts one command in a dedicated process group and guarantees
-// bounded teardown for the whole group.
+// bounded teardown for the whole group, plus any descendant that left it.
 type Supervisor struct {
 	cmd *exec.Cmd
 
 	mu      sync.Mutex
 	waitErr error
 	pid     int
-
-	waitCh   chan struct{}
-	started  bool
-	stopOnce sync.Once
-	stopErr  error
+	paused  bool
+
+	waitCh        chan struct{}
+	started       bool
+	stopOnce      sync.Once
+	stopErr       error
+	stopRequested bool
+
+	cgroup    *cgroup.Group
+	cgroupErr error
+
+	startedAt      time.Time
+	exitedAt       time.Time
+	oomAtStart     oomBaseline
+	oomAtExit      oomBaseline
+	stopBeforeExit bool
+	// vmstatSample is the latest oom_kill reading while the command ran.
+	vmstatSample    oomBaseline
+	vmstatSampledAt time.Time
+
+	// tracked holds every descendant seen since Start, keyed by PID.
+	tracked  map[int]procInfo
+	escaped  map[int]bool
+	onEscape func(Escape)
 }
 
 func New(cmd *exec.Cmd) *Supervisor {
@@ -35,6 +55,37 @@ func New(cmd *exec.Cmd) *Supervisor {
 	}
 }
 
+// UseCgroup starts the command inside g, so its limits apply from the
+// child's first instruction. It must be called before Start.
+func (s *Supervisor) UseCgroup(g *cgroup.Group) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cgroup = g
+}
+
+// Cgroup returns the group the command runs in, or nil when the process
+// could not be moved into one.
+func (s *Supervisor) Cgroup() *cgroup.Group {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cgroup
+}
+
+// OnEscape registers fn to be called once for each descendant that leaves
+// the process group. It must be called before Start.
+func (s *Supervisor) OnEscape(fn func(Escape)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onEscape = fn
+}
+
+// CgroupError reports why the command is not running in its cgroup.
+func (s *Supervisor) CgroupError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cgroupErr
+}
+
 func (s *Supervisor) Start() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -52,20 +103,53 @@ func (s *Supervisor) Start() error {
 	}
 	s.cmd.SysProcAttr.Setpgid = true
 
-	if err := s.cmd.Start(); err != nil {
+	var dir *os.File
+	if s.cgroup != nil {
+		var err error
+		if dir, err = s.cgroup.Open(); err != nil {
+			// Keep supervising without the cgroup; polling limits still apply.
+			s.cgroupErr = err
+			s.cgroup = nil
+		}
+	}
+
+	enableSubreaper()
+	beginStart()
+	cmd, placed, err := startCommand(s.cmd, dir)
+	if dir != nil {
+		_ = dir.Close()
+	}
+	if err != nil {
+		endStart(0)
 		return err
 	}
 
+	s.cmd = cmd
 	s.pid = s.cmd.Process.Pid
 	s.started = true
+	s.startedAt = time.Now()
+	endStart(s.pid)
+	if s.cgroup != nil && !placed {
+		if err := s.cgroup.AddProcess(s.pid); err != nil {
+			s.cgroupErr = err
+			s.cgroup = nil
+		}
+	}
+	s.oomAtStart = s.readOOMCounters()
+	s.vmstatSample, s.vmstatSampledAt = s.oomAtStart, time.Now()
 
 	go func() {
 		err := s.cmd.Wait()
 		s.mu.Lock()
 		s.waitErr = err
+		s.exitedAt = time.Now()
+		s.stopBeforeExit = s.stopRequested
+		s.oomAtExit = s.readOOMCounters()
 		s.mu.Unlock()
+		unregisterRoot(s.cmd.Process.Pid)
 		close(s.waitCh)
 	}()
+	go s.trackDescendants()
 
 	return nil
 }
@@ -92,15 +176,74 @@ func (s *Supervisor) Wait() error {
 	return s.waitErr
 }
 
-// Stop sends SIGTERM to the process group, waits for grace duration, then
-// escalates to SIGKILL for the process group if needed.
+// Stop sends SIGTERM to the process group and every tracked descendant
+// outside it, waits for grace duration, then escalates to SIGKILL.
 func (s *Supervisor) Stop(grace time.Duration) error {
+	s.mu.Lock()
+	s.stopRequested = true
+	s.mu.Unlock()
 	s.stopOnce.Do(func() {
 		s.stopErr = s.stopInternal(grace)
 	})
 	return s.stopErr
 }
 
+// StopRequested reports whether Stop was called, so callers can tell a
+// requested teardown from the command dying on its own.
+func (s *Supervisor) StopRequested() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopRequested
+}
+
+// Pause freezes the process group with SIGSTOP. The children keep their
+// memory and open files, so Resume continues exactly where they stopped.
+func (s *Supervisor) Pause() error {
+	return s.setPaused(true)
+}
+
+// Resume continues a group frozen by Pause with SIGCONT.
+func (s *Supervisor) Resume() error {
+	return s.setPaused(false)
+}
+
+// Paused reports whether the process group is currently frozen.
+func (s *Supervisor) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+func (s *Supervisor) setPaused(paused bool) error {
+	if s.Exited() {
+		return errors.New("supervisor: process has exited")
+	}
+	s.mu.Lock()
+	if !s.started || s.pid <= 0 {
+		s.mu.Unlock()
+		return errors.New("supervisor: command not started")
+	}
+	if s.paused == paused {
+		s.mu.Unlock()
+		return nil
+	}
+	pid := s.pid
+	s.mu.Unlock()
+
+	sig := syscall.SIGSTOP
+	if !paused {
+		sig = syscall.SIGCONT
+	}
+	if err := signalGroup(pid, sig); err != nil {
+		return fmt.Errorf("supervisor: send %v: %w", sig, err)
+	}
+	_ = s.signalDescendants(sig)
+	s.mu.Lock()
+	s.paused = paused
+	s.mu.Unlock()
+	return nil
+}
+
 // TrapSignals binds OS signals and invokes Stop when one is received.
 // The returned function must be called to stop signal notification.
 func (s *Supervisor) TrapSignals(grace time.Duration, onSignal func(os.Signal), sigs ...os.Signal) func() {
@@ -141,12 +284,23 @@ func (s *Supervisor) stopInternal(grace time.Duration) error {
 	}
 
 	termErr := signalGroup(pid, syscall.SIGTERM)
-	if waitForGroupExit(pid, grace) {
+	_ = s.signalDescendants(syscall.SIGTERM)
+	if s.Paused() {
+		// Stopped processes only act on SIGTERM once they are continued.
+		_ = s.Resume()
+	}
+	if s.waitForTreeExit(pid, grace) {
 		return nil
 	}
 
 	killErr := signalGroup(pid, syscall.SIGKILL)
-	if !waitForGroupExit(pid, 2*time.Second) {
+	if err := s.signalDescendants(syscall.SIGKILL); killErr == nil {
+		killErr = err
+	}
+	if g := s.Cgroup(); g != nil {
+		_ = g.Kill()
+	}
+	if !s.waitForTreeExit(pid, 2*time.Second) {
 		return fmt.Errorf("supervisor: process group %d did not exit after SIGKILL", pid)
 	}
 
@@ -184,17 +338,6 @@ func signalGroup(pid int, sig syscall.Signal) error {
 	return procErr
 }
 
-func waitForGroupExit(pid int, timeout time.Duration) bool {
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		if !processGroupExists(pid) {
-			return true
-		}
-		time.Sleep(25 * time.Millisecond)
-	}
-	return !processGroupExists(pid)
-}
-
 func processGroupExists(pid int) bool {
 	if pid <= 0 {
 		return false
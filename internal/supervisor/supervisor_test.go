@@ -2,9 +2,11 @@ package supervisor
 
 import (
 	"bufio"
+	"flowforge/internal/cgroup"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -121,6 +123,87 @@ func TestTrapSignalsStopsProcess(t *testing.T) {
 	}
 }
 
+func TestStartFallsBackWhenCgroupIsGone(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("memory"), 0o644); err != nil {
+		t.Fatalf("write controllers: %v", err)
+	}
+	g, err := cgroup.Create(cgroup.Config{Root: root, Parent: "/", Name: "run"})
+	if err != nil {
+		t.Fatalf("create cgroup: %v", err)
+	}
+	// Simulate the group disappearing before the child can be moved into it.
+	if err := os.RemoveAll(g.Path()); err != nil {
+		t.Fatalf("remove cgroup dir: %v", err)
+	}
+
+	cmd := exec.Command("python3", "-c", "import time; time.sleep(120)")
+	s := New(cmd)
+	s.UseCgroup(g)
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer func() { _ = s.Stop(500 * time.Millisecond) }()
+
+	if s.Cgroup() != nil {
+		t.Fatal("expected supervisor to drop the unusable cgroup")
+	}
+	if s.CgroupError() == nil {
+		t.Fatal("expected cgroup error to be recorded")
+	}
+	if !processExists(s.PID()) {
+		t.Fatal("process should keep running without cgroup enforcement")
+	}
+}
+
+func TestPauseFreezesGroupAndStopStillWorks(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "sleep 120 & wait")
+	s := New(cmd)
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer func() { _ = s.Stop(500 * time.Millisecond) }()
+
+	if err := s.Pause(); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if !s.Paused() || !waitForProcessState(s.PID(), 'T', 2*time.Second) {
+		t.Fatalf("expected process %d to be stopped after Pause", s.PID())
+	}
+	if err := s.Resume(); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if s.Paused() || !waitForProcessState(s.PID(), 'S', 2*time.Second) {
+		t.Fatalf("expected process %d to be running after Resume", s.PID())
+	}
+
+	// A paused group must still honour SIGTERM on Stop.
+	if err := s.Pause(); err != nil {
+		t.Fatalf("pause again: %v", err)
+	}
+	if err := s.Stop(2 * time.Second); err != nil {
+		t.Fatalf("stop paused group: %v", err)
+	}
+	if !waitForProcessExit(s.PID(), 2*time.Second) {
+		t.Fatalf("paused process %d still running after stop", s.PID())
+	}
+}
+
+func waitForProcessState(pid int, want byte, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		blob, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err == nil {
+			// State follows the parenthesised command name.
+			if i := strings.LastIndexByte(string(blob), ')'); i >= 0 && i+2 < len(blob) && blob[i+2] == want {
+				return true
+			}
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+	return false
+}
+
 func processExists(pid int) bool {
 	if pid <= 0 {
 		return false
@@ -0,0 +1,43 @@
+package sysmon
+
+import "time"
+
+// hangCPUPercent is the tree CPU below which a sample shows no CPU progress.
+// Idle processes still register a few tenths of a percent from timers.
+const hangCPUPercent = 1.0
+
+// HangTracker measures how long a process tree has gone without observable
+// progress: no CPU above hangCPUPercent, no new output, no I/O and no
+// context switches. A process blocked on a lock, a dead socket or in
+// uninterruptible sleep moves none of these.
+type HangTracker struct {
+	primed       bool
+	since        time.Time // Last sample that showed progress
+	lastActivity time.Time
+	ctx          uint64
+	io           IOCounters
+	processes    int
+}
+
+// Observe records a tree sample taken at now. activity is the latest
+// progress seen outside the kernel counters, such as the last output or a
+// resume. It returns how long the tree has been stuck; zero means it made
+// progress in this sample.
+func (h *HangTracker) Observe(now time.Time, s TreeSample, activity time.Time) time.Duration {
+	progressed := !h.primed ||
+		s.CPU >= hangCPUPercent ||
+		activity.After(h.lastActivity) ||
+		s.CtxSwitches != h.ctx ||
+		s.IO != h.io ||
+		len(s.Processes) != h.processes
+
+	h.primed = true
+	h.lastActivity = activity
+	h.ctx, h.io = s.CtxSwitches, s.IO
+	h.processes = len(s.Processes)
+	if progressed {
+		h.since = now
+		return 0
+	}
+	return now.Sub(h.since)
+}
@@ -0,0 +1,155 @@
+package sysmon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"flowforge/internal/state"
+)
+
+func TestHangTrackerResetsOnAnyProgress(t *testing.T) {
+	var h HangTracker
+	start := time.Now()
+	idle := TreeSample{CPU: 0.2, CtxSwitches: 10, IO: IOCounters{ReadSyscalls: 4, ReadChars: 100}, Processes: make([]state.ProcessSample, 1)}
+
+	if got := h.Observe(start, idle, time.Time{}); got != 0 {
+		t.Fatalf("first sample should count as progress, got %s", got)
+	}
+	if got := h.Observe(start.Add(2*time.Second), idle, time.Time{}); got != 2*time.Second {
+		t.Fatalf("expected 2s stuck, got %s", got)
+	}
+
+	// Each kind of progress resets the clock for exactly one sample.
+	now := start.Add(3 * time.Second)
+	current := idle
+	for _, step := range []struct {
+		name     string
+		progress func(*TreeSample)
+		activity time.Time
+	}{
+		{"context switch", func(s *TreeSample) { s.CtxSwitches++ }, time.Time{}},
+		{"io syscall", func(s *TreeSample) { s.IO.WriteSyscalls++ }, time.Time{}},
+		{"io bytes", func(s *TreeSample) { s.IO.ReadChars += 10 }, time.Time{}},
+		{"new process", func(s *TreeSample) { s.Processes = append(s.Processes, state.ProcessSample{}) }, time.Time{}},
+		{"output", func(*TreeSample) {}, start.Add(time.Minute)},
+	} {
+		step.progress(&current)
+		if got := h.Observe(now, current, step.activity); got != 0 {
+			t.Fatalf("%s: expected progress, got %s stuck", step.name, got)
+		}
+		now = now.Add(time.Second)
+		if got := h.Observe(now, current, step.activity); got != time.Second {
+			t.Fatalf("%s: expected 1s stuck after progress, got %s", step.name, got)
+		}
+		now = now.Add(time.Second)
+	}
+
+	busy := current
+	busy.CPU = 5
+	if got := h.Observe(now, busy, start.Add(time.Minute)); got != 0 {
+		t.Fatalf("cpu: expected progress, got %s stuck", got)
+	}
+}
+
+func TestHangTrackerFlagsBlockedRead(t *testing.T) {
+	// cat blocks forever reading a pipe nobody writes to.
+	cmd := exec.Command("cat")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("stdin pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = stdin.Close()
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	sampler := NewTreeSampler(cmd.Process.Pid)
+	var h HangTracker
+	var stuck time.Duration
+	var sample TreeSample
+	for i := 0; i < 8; i++ {
+		time.Sleep(200 * time.Millisecond)
+		sample, err = sampler.Sample()
+		if err != nil {
+			t.Fatalf("Sample() error = %v", err)
+		}
+		stuck = h.Observe(time.Now(), sample, time.Time{})
+	}
+	if stuck < time.Second {
+		t.Fatalf("expected a blocked cat to look stuck, got %s (sample %+v)", stuck, sample)
+	}
+	if ws := sample.WaitState(); !strings.HasPrefix(ws, "cat sleeping") {
+		t.Fatalf("WaitState() = %q, want cat sleeping", ws)
+	}
+
+	// Feeding it a line is progress.
+	_, _ = stdin.Write([]byte("hello\n"))
+	time.Sleep(200 * time.Millisecond)
+	sample, _ = sampler.Sample()
+	if got := h.Observe(time.Now(), sample, time.Time{}); got != 0 {
+		t.Fatalf("expected progress after input, got %s stuck", got)
+	}
+}
+
+func TestReadKernelStatsParsesProcFiles(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "4242")
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("stat", "4242 (agent (worker) x) D 1 4242 4242 0 -1 4194560 100 0 0 0 5 2 0 0 20 0 2 0\n")
+	write("wchan", "io_schedule")
+	write("io", "rchar: 1000\nwchar: 24\nsyscr: 7\nsyscw: 3\nread_bytes: 4096\nwrite_bytes: 0\ncancelled_write_bytes: 0\n")
+	write("task/4242/status", "Name:\tagent\nvoluntary_ctxt_switches:\t40\nnonvoluntary_ctxt_switches:\t2\n")
+	write("task/4243/status", "Name:\tworker\nvoluntary_ctxt_switches:\t5\nnonvoluntary_ctxt_switches:\t1\n")
+
+	old := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = old })
+
+	ks, err := ReadKernelStats(4242)
+	if err != nil {
+		t.Fatalf("ReadKernelStats: %v", err)
+	}
+	want := KernelStats{State: "D", WChan: "io_schedule", VoluntarySwitches: 45, InvoluntarySwitches: 3, IO: IOCounters{
+		ReadChars: 1000, WriteChars: 24, ReadSyscalls: 7, WriteSyscalls: 3, ReadBytes: 4096,
+	}}
+	if ks != want {
+		t.Fatalf("ReadKernelStats = %+v, want %+v", ks, want)
+	}
+
+	write("wchan", "0")
+	if ks, _ := ReadKernelStats(4242); ks.WChan != "" {
+		t.Fatalf("expected a hidden wchan to read as empty, got %q", ks.WChan)
+	}
+	if _, err := ReadKernelStats(4343); err == nil {
+		t.Fatal("expected an error for a missing process")
+	}
+
+	sample := TreeSample{Processes: []state.ProcessSample{
+		{PID: 4243, PPID: 4242, Name: "worker", State: "S", WChan: "futex_wait_queue"},
+		{PID: 4242, PPID: 1, Name: "agent", State: "S", WChan: "do_wait"},
+	}}
+	if ws := sample.WaitState(); ws != "agent sleeping in do_wait" {
+		t.Fatalf("WaitState() = %q, want the root", ws)
+	}
+	sample.Processes[0].State = "D"
+	if ws := sample.WaitState(); ws != "worker uninterruptible sleep in futex_wait_queue" {
+		t.Fatalf("WaitState() = %q, want the blocked worker", ws)
+	}
+}
@@ -0,0 +1,160 @@
+package sysmon
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// KernelStats is the kernel's view of one process: its scheduler state,
+// where it sleeps, and counters that only move when it makes progress.
+type KernelStats struct {
+	State               string // One letter from /proc/<pid>/stat: R, S, D, Z, T, ...
+	WChan               string // Kernel function the process waits in; "" when running or hidden
+	VoluntarySwitches   uint64 // Summed over all threads
+	InvoluntarySwitches uint64 // Summed over all threads
+	IO                  IOCounters
+}
+
+// IOCounters are the cumulative counters from /proc/<pid>/io.
+type IOCounters struct {
+	ReadChars     uint64 // rchar: bytes returned by read syscalls, including pipes and sockets
+	WriteChars    uint64 // wchar: bytes passed to write syscalls, including pipes and sockets
+	ReadSyscalls  uint64 // syscr
+	WriteSyscalls uint64 // syscw
+	ReadBytes     uint64 // read_bytes: bytes fetched from storage
+	WriteBytes    uint64 // write_bytes: bytes sent to storage, counted when pages are dirtied
+}
+
+// Add returns the element-wise sum of c and o.
+func (c IOCounters) Add(o IOCounters) IOCounters {
+	return IOCounters{
+		ReadChars:     c.ReadChars + o.ReadChars,
+		WriteChars:    c.WriteChars + o.WriteChars,
+		ReadSyscalls:  c.ReadSyscalls + o.ReadSyscalls,
+		WriteSyscalls: c.WriteSyscalls + o.WriteSyscalls,
+		ReadBytes:     c.ReadBytes + o.ReadBytes,
+		WriteBytes:    c.WriteBytes + o.WriteBytes,
+	}
+}
+
+// Since returns how far each counter has moved from prev. A counter that
+// went backwards (a recycled PID) counts from zero.
+func (c IOCounters) Since(prev IOCounters) IOCounters {
+	d := func(cur, old uint64) uint64 {
+		if cur < old {
+			return cur
+		}
+		return cur - old
+	}
+	return IOCounters{
+		ReadChars:     d(c.ReadChars, prev.ReadChars),
+		WriteChars:    d(c.WriteChars, prev.WriteChars),
+		ReadSyscalls:  d(c.ReadSyscalls, prev.ReadSyscalls),
+		WriteSyscalls: d(c.WriteSyscalls, prev.WriteSyscalls),
+		ReadBytes:     d(c.ReadBytes, prev.ReadBytes),
+		WriteBytes:    d(c.WriteBytes, prev.WriteBytes),
+	}
+}
+
+// procRoot is where the proc filesystem is mounted; tests point it at a
+// fixture tree.
+var procRoot = "/proc"
+
+// ReadKernelStats reads /proc/<pid>/stat, wchan, io and the status of every
+// thread. The state is required; the other files may be unreadable (for a
+// process owned by another user, say) and then leave their fields zero.
+func ReadKernelStats(pid int) (KernelStats, error) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	var ks KernelStats
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return ks, err
+	}
+	// The command name may contain spaces and parentheses; the state
+	// follows the last ')'.
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 || i+2 >= len(stat) {
+		return ks, fmt.Errorf("sysmon: malformed %s/stat", dir)
+	}
+	ks.State = string(stat[i+2])
+
+	if wchan, err := os.ReadFile(filepath.Join(dir, "wchan")); err == nil {
+		if w := strings.TrimSpace(string(wchan)); w != "0" {
+			ks.WChan = w
+		}
+	}
+
+	if io, err := os.ReadFile(filepath.Join(dir, "io")); err == nil {
+		fields := parseKeyValues(io)
+		ks.IO = IOCounters{
+			ReadChars:     fields["rchar"],
+			WriteChars:    fields["wchar"],
+			ReadSyscalls:  fields["syscr"],
+			WriteSyscalls: fields["syscw"],
+			ReadBytes:     fields["read_bytes"],
+			WriteBytes:    fields["write_bytes"],
+		}
+	}
+
+	// /proc/<pid>/status counts switches for the main thread only.
+	tasks, _ := filepath.Glob(filepath.Join(dir, "task", "*", "status"))
+	if len(tasks) == 0 {
+		tasks = []string{filepath.Join(dir, "status")}
+	}
+	for _, path := range tasks {
+		status, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		fields := parseKeyValues(status)
+		ks.VoluntarySwitches += fields["voluntary_ctxt_switches"]
+		ks.InvoluntarySwitches += fields["nonvoluntary_ctxt_switches"]
+	}
+	return ks, nil
+}
+
+// parseKeyValues reads the numeric "key: value" lines of a proc file.
+func parseKeyValues(data []byte) map[string]uint64 {
+	out := make(map[string]uint64)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64); err == nil {
+			out[key] = n
+		}
+	}
+	return out
+}
+
+// StateName spells out a /proc state letter.
+func StateName(state string) string {
+	switch state {
+	case "R":
+		return "running"
+	case "S":
+		return "sleeping"
+	case "D":
+		return "uninterruptible sleep"
+	case "Z":
+		return "zombie"
+	case "T":
+		return "stopped"
+	case "t":
+		return "tracing stop"
+	case "I":
+		return "idle"
+	case "X", "x":
+		return "dead"
+	default:
+		return state
+	}
+}
@@ -3,6 +3,7 @@ package sysmon
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/shirou/gopsutil/v3/process"
 )
@@ -11,21 +12,97 @@ import (
 type SysStats struct {
 	OpenFDs     int
 	SocketCount int
+	IO          IOStats // Zero until TreeIO has seen the PID as a tree root
+}
+
+// IOStats is a process tree's I/O since tracking began and its recent rates.
+type IOStats struct {
+	Total               IOCounters // Includes members that have since exited
+	ReadBytesPerSec     float64
+	WriteBytesPerSec    float64
+	ReadSyscallsPerSec  float64
+	WriteSyscallsPerSec float64
+}
+
+// ioRateWindow is how far back TreeIO looks when computing rates, so one
+// large flush does not read as a sustained runaway.
+const ioRateWindow = 5 * time.Second
+
+// treeIO accumulates the I/O of one process tree.
+type treeIO struct {
+	last   map[int]IOCounters // Latest counters per member
+	total  IOCounters
+	points []ioPoint // Totals over the trailing ioRateWindow, oldest first
+}
+
+type ioPoint struct {
+	at    time.Time
+	total IOCounters
 }
 
 // Monitor tracks process baselines safely
 type Monitor struct {
 	mu        sync.Mutex
 	baselines map[int]SysStats
+	trees     map[int]*treeIO
 }
 
 // NewMonitor creates a thread-safe monitor
 func NewMonitor() *Monitor {
 	return &Monitor{
 		baselines: make(map[int]SysStats),
+		trees:     make(map[int]*treeIO),
 	}
 }
 
+// TreeIO records the per-process counters of the tree rooted at root (see
+// TreeSample.IOByPID) and returns its totals and rates. Each member
+// contributes what it did since it was first seen, so bytes written by
+// short-lived children still count after they exit. The first sample of a
+// tree is the baseline: an attached process's earlier I/O is not counted.
+func (m *Monitor) TreeIO(root int, now time.Time, byPID map[int]IOCounters) IOStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.trees[root]
+	if !ok {
+		t = &treeIO{last: make(map[int]IOCounters)}
+		m.trees[root] = t
+	}
+	for pid, cur := range byPID {
+		if prev, seen := t.last[pid]; seen {
+			t.total = t.total.Add(cur.Since(prev))
+		} else if ok {
+			// A member that appeared between samples did all of its
+			// I/O under supervision.
+			t.total = t.total.Add(cur)
+		}
+	}
+	t.last = byPID
+
+	t.points = append(t.points, ioPoint{at: now, total: t.total})
+	// Keep one point at or beyond the window edge as the rate baseline.
+	for len(t.points) > 2 && now.Sub(t.points[1].at) >= ioRateWindow {
+		t.points = t.points[1:]
+	}
+
+	return t.stats()
+}
+
+// stats computes rates between the oldest and newest retained points.
+func (t *treeIO) stats() IOStats {
+	stats := IOStats{Total: t.total}
+	first, last := t.points[0], t.points[len(t.points)-1]
+	if secs := last.at.Sub(first.at).Seconds(); secs > 0 {
+		d := last.total.Since(first.total)
+		stats.ReadBytesPerSec = float64(d.ReadBytes) / secs
+		stats.WriteBytesPerSec = float64(d.WriteBytes) / secs
+		stats.ReadSyscallsPerSec = float64(d.ReadSyscalls) / secs
+		stats.WriteSyscallsPerSec = float64(d.WriteSyscalls) / secs
+	}
+	return stats
+}
+
 // GetStats returns current file descriptor and socket counts for the PID.
 // Uses gopsutil; no external shelling (no lsof fallback).
 func (m *Monitor) GetStats(pid int) (SysStats, error) {
@@ -53,10 +130,16 @@ func (m *Monitor) GetStats(pid int) (SysStats, error) {
 		socketCount = 0
 	}
 
-	return SysStats{
+	stats := SysStats{
 		OpenFDs:     int(fds),
 		SocketCount: socketCount,
-	}, nil
+	}
+	m.mu.Lock()
+	if t, ok := m.trees[pid]; ok && len(t.points) > 0 {
+		stats.IO = t.stats()
+	}
+	m.mu.Unlock()
+	return stats, nil
 }
 
 // IsMonitoring checks if we have a baseline for this PID
@@ -0,0 +1,95 @@
+package sysmon
+
+import (
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMonitorTreeIOKeepsExitedChildren(t *testing.T) {
+	m := NewMonitor()
+	start := time.Now()
+	w := func(bytes, calls uint64) IOCounters { return IOCounters{WriteBytes: bytes, WriteSyscalls: calls} }
+
+	// An attached root's earlier writes are the baseline.
+	if s := m.TreeIO(100, start, map[int]IOCounters{100: w(5000, 50)}); s.Total != (IOCounters{}) || s.WriteBytesPerSec != 0 {
+		t.Fatalf("first sample should be the baseline, got %+v", s)
+	}
+	// A child appears and writes; everything it did counts.
+	s := m.TreeIO(100, start.Add(time.Second), map[int]IOCounters{100: w(6000, 60), 101: w(4000, 4)})
+	if s.Total.WriteBytes != 5000 || s.Total.WriteSyscalls != 14 {
+		t.Fatalf("unexpected totals %+v", s.Total)
+	}
+	if s.WriteBytesPerSec != 5000 || s.WriteSyscallsPerSec != 14 {
+		t.Fatalf("unexpected rates %+v", s)
+	}
+	// The child exits; its bytes stay in the total.
+	s = m.TreeIO(100, start.Add(2*time.Second), map[int]IOCounters{100: w(6000, 60)})
+	if s.Total.WriteBytes != 5000 || s.WriteBytesPerSec != 2500 {
+		t.Fatalf("expected exited child to stay counted, got %+v", s)
+	}
+
+	// Rates only look back ioRateWindow, totals do not.
+	for i := 3; i <= 10; i++ {
+		s = m.TreeIO(100, start.Add(time.Duration(i)*time.Second), map[int]IOCounters{100: w(6000, 60)})
+	}
+	if s.Total.WriteBytes != 5000 || s.WriteBytesPerSec != 0 || s.WriteSyscallsPerSec != 0 {
+		t.Fatalf("expected idle tree to have no write rate, got %+v", s)
+	}
+
+	// A recycled PID counts from zero rather than going negative.
+	s = m.TreeIO(100, start.Add(11*time.Second), map[int]IOCounters{100: w(6000, 60), 101: w(10, 1)})
+	if s.Total.WriteBytes != 5010 {
+		t.Fatalf("unexpected totals after PID reuse %+v", s.Total)
+	}
+
+	// Other roots are tracked separately.
+	if s := m.TreeIO(200, start, map[int]IOCounters{200: w(1, 1)}); s.Total != (IOCounters{}) {
+		t.Fatalf("expected a fresh tree, got %+v", s)
+	}
+}
+
+func TestMonitorTreeIOMeasuresDiskWrites(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "blob")
+	cmd := exec.Command("sh", "-c", "sleep 0.3; dd if=/dev/zero of="+out+" bs=1M count=8 conv=fsync 2>/dev/null; sleep 30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		_ = cmd.Wait()
+	})
+
+	m := NewMonitor()
+	sampler := NewTreeSampler(cmd.Process.Pid)
+	var s IOStats
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		sample, err := sampler.Sample()
+		if err != nil {
+			t.Fatalf("Sample() error = %v", err)
+		}
+		s = m.TreeIO(cmd.Process.Pid, time.Now(), sample.IOByPID)
+		if s.Total.WriteBytes >= 8<<20 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if s.Total.WriteBytes < 8<<20 {
+		t.Skipf("filesystem did not report write_bytes for dd (total %+v)", s.Total)
+	}
+	if s.WriteBytesPerSec <= 0 {
+		t.Fatalf("expected a write rate, got %+v", s)
+	}
+
+	stats, err := m.GetStats(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.IO.Total.WriteBytes != s.Total.WriteBytes {
+		t.Fatalf("GetStats IO = %+v, want %+v", stats.IO, s)
+	}
+}
@@ -0,0 +1,216 @@
+package sysmon
+
+import (
+	"errors"
+	"flowforge/internal/state"
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// TreeSample aggregates resource usage across a supervised process tree.
+type TreeSample struct {
+	CPU       float64
+	RSSMB     float64
+	Threads   int
+	FDs       int
+	Processes []state.ProcessSample // Busiest first
+
+	// Progress counters summed over the tree; see KernelStats.
+	CtxSwitches uint64
+	IO          IOCounters
+	// IOByPID holds each member's own counters, for totals that must
+	// survive members exiting (see Monitor.TreeIO).
+	IOByPID map[int]IOCounters
+}
+
+// Top returns the process using the most CPU in the tree.
+func (t TreeSample) Top() (state.ProcessSample, bool) {
+	if len(t.Processes) == 0 {
+		return state.ProcessSample{}, false
+	}
+	return t.Processes[0], true
+}
+
+// WaitState describes where the tree is waiting, for hang reports: the
+// first process in uninterruptible sleep, or else the root's state, with
+// its wait channel when the kernel shows it. Samples are ordered busiest
+// first, so the root is found by its missing parent in the tree.
+func (t TreeSample) WaitState() string {
+	inTree := make(map[int]bool, len(t.Processes))
+	for _, p := range t.Processes {
+		inTree[p.PID] = true
+	}
+	var pick *state.ProcessSample
+	for i := range t.Processes {
+		p := &t.Processes[i]
+		if p.State == "D" {
+			pick = p
+			break
+		}
+		if pick == nil && !inTree[p.PPID] {
+			pick = p
+		}
+	}
+	if pick == nil || pick.State == "" {
+		return ""
+	}
+	desc := fmt.Sprintf("%s %s", pick.Name, StateName(pick.State))
+	if pick.WChan != "" {
+		desc += " in " + pick.WChan
+	}
+	return desc
+}
+
+// TreeSampler samples every process in the root's process group plus any
+// descendant that moved to its own group or session.
+type TreeSampler struct {
+	root int32
+
+	mu    sync.Mutex
+	procs map[int32]*process.Process
+}
+
+// NewTreeSampler creates a sampler for the tree rooted at pid.
+func NewTreeSampler(pid int) *TreeSampler {
+	return &TreeSampler{
+		root:  int32(pid),
+		procs: make(map[int32]*process.Process),
+	}
+}
+
+// Sample reads CPU, RSS, thread and FD counts for each member of the tree.
+// CPU is the usage since the previous Sample (100 = one core); processes
+// seen for the first time report their lifetime average instead.
+func (s *TreeSampler) Sample() (TreeSample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, err := s.membersLocked()
+	if err != nil {
+		return TreeSample{}, err
+	}
+	if len(members) == 0 {
+		return TreeSample{}, errors.New("sysmon: process tree has exited")
+	}
+
+	var sample TreeSample
+	for _, m := range members {
+		p := m.proc
+		ps := state.ProcessSample{PID: int(p.Pid), PPID: int(m.ppid)}
+		if name, err := p.Name(); err == nil {
+			ps.Name = name
+		}
+		if m.fresh {
+			ps.CPU, _ = p.CPUPercent()
+			_, _ = p.Percent(0) // prime the delta for the next tick
+		} else if cpu, err := p.Percent(0); err == nil {
+			ps.CPU = cpu
+		}
+		if mem, err := p.MemoryInfo(); err == nil {
+			ps.RSSMB = float64(mem.RSS) / 1024.0 / 1024.0
+		}
+		if threads, err := p.NumThreads(); err == nil {
+			ps.Threads = int(threads)
+		}
+		if fds, err := p.NumFDs(); err == nil {
+			ps.FDs = int(fds)
+		}
+		if ks, err := ReadKernelStats(int(p.Pid)); err == nil {
+			ps.State = ks.State
+			ps.WChan = ks.WChan
+			sample.CtxSwitches += ks.VoluntarySwitches + ks.InvoluntarySwitches
+			sample.IO = sample.IO.Add(ks.IO)
+			if sample.IOByPID == nil {
+				sample.IOByPID = make(map[int]IOCounters, len(members))
+			}
+			sample.IOByPID[int(p.Pid)] = ks.IO
+		}
+
+		sample.CPU += ps.CPU
+		sample.RSSMB += ps.RSSMB
+		sample.Threads += ps.Threads
+		sample.FDs += ps.FDs
+		sample.Processes = append(sample.Processes, ps)
+	}
+	sort.SliceStable(sample.Processes, func(i, j int) bool {
+		return sample.Processes[i].CPU > sample.Processes[j].CPU
+	})
+	return sample, nil
+}
+
+type treeMember struct {
+	proc  *process.Process
+	ppid  int32
+	fresh bool
+}
+
+// membersLocked lists the root, every process in its process group, and all
+// descendants reachable through parent links.
+func (s *TreeSampler) membersLocked() ([]treeMember, error) {
+	pids, err := process.Pids()
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[int32]int32, len(pids))
+	children := make(map[int32][]int32)
+	inGroup := make(map[int32]bool)
+	for _, pid := range pids {
+		p, ok := s.procs[pid]
+		if !ok {
+			p = &process.Process{Pid: pid}
+		}
+		ppid, err := p.Ppid()
+		if err != nil {
+			continue
+		}
+		parents[pid] = ppid
+		children[ppid] = append(children[ppid], pid)
+		if pgid, err := syscall.Getpgid(int(pid)); err == nil && int32(pgid) == s.root {
+			inGroup[pid] = true
+		}
+	}
+
+	// Walk parent links from the root and from every group member so that
+	// children which called setsid()/setpgid() are still attributed to the run.
+	include := make(map[int32]bool)
+	queue := []int32{}
+	if _, ok := parents[s.root]; ok {
+		queue = append(queue, s.root)
+	}
+	for pid := range inGroup {
+		queue = append(queue, pid)
+	}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if include[pid] {
+			continue
+		}
+		include[pid] = true
+		queue = append(queue, children[pid]...)
+	}
+
+	members := make([]treeMember, 0, len(include))
+	for pid := range include {
+		p, ok := s.procs[pid]
+		if !ok {
+			p = &process.Process{Pid: pid}
+			s.procs[pid] = p
+		}
+		members = append(members, treeMember{proc: p, ppid: parents[pid], fresh: !ok})
+	}
+
+	// Forget processes that have exited or left the tree.
+	for pid := range s.procs {
+		if !include[pid] {
+			delete(s.procs, pid)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].proc.Pid < members[j].proc.Pid })
+	return members, nil
+}
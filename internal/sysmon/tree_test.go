@@ -0,0 +1,50 @@
+package sysmon
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTreeSamplerAggregatesLauncherChildren(t *testing.T) {
+	// The shell launcher stays idle while its child does the work.
+	cmd := exec.Command("sh", "-c", `python3 -c "while True: pass" & sleep 30; wait`)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		_ = cmd.Wait()
+	})
+
+	sampler := NewTreeSampler(cmd.Process.Pid)
+	var sample TreeSample
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(300 * time.Millisecond)
+		var err error
+		sample, err = sampler.Sample()
+		if err != nil {
+			t.Fatalf("Sample() error = %v", err)
+		}
+		if top, ok := sample.Top(); ok && top.Name == "python3" && top.CPU > 20 {
+			break
+		}
+	}
+
+	if len(sample.Processes) < 3 {
+		t.Fatalf("expected sh, python3 and sleep in tree, got %+v", sample.Processes)
+	}
+	top, _ := sample.Top()
+	if top.Name != "python3" || top.PPID != cmd.Process.Pid {
+		t.Fatalf("top process = %+v, want busy python3 child of launcher %d", top, cmd.Process.Pid)
+	}
+	if sample.CPU < top.CPU {
+		t.Fatalf("tree CPU %.1f should include top child CPU %.1f", sample.CPU, top.CPU)
+	}
+	if sample.Threads < len(sample.Processes) || sample.RSSMB <= 0 {
+		t.Fatalf("expected aggregated threads/RSS, got threads=%d rss=%.2f", sample.Threads, sample.RSSMB)
+	}
+}
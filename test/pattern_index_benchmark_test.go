@@ -0,0 +1,122 @@
+package test
+
+import (
+	"flowforge/internal/database"
+	"flowforge/internal/patterns"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+)
+
+// patternWords mixes the placeholders normalization leaves behind with a
+// few thousand made-up words, so lines share structure the way learned
+// patterns do without being near copies of each other.
+var patternWords = func() []string {
+	words := []string{"<NUM>", "<NUM>s", "<HEX>", "<PATH>", "<UUID>", "error:", "failed:", "retrying", "request", "tool"}
+	syllables := []string{"ka", "lo", "re", "tis", "mon", "da", "per", "sul", "vi", "ent", "gro", "ch", "ax", "ne", "bu", "qua"}
+	rng := rand.New(rand.NewSource(1))
+	for len(words) < 3000 {
+		var w strings.Builder
+		for n := 2 + rng.Intn(3); n > 0; n-- {
+			w.WriteString(syllables[rng.Intn(len(syllables))])
+		}
+		words = append(words, w.String())
+	}
+	return words
+}()
+
+// syntheticPatterns builds n distinct normalized log lines like the ones
+// runs learn, deterministically.
+func syntheticPatterns(n int) []database.Pattern {
+	rng := rand.New(rand.NewSource(42))
+	seen := make(map[string]bool, n)
+	out := make([]database.Pattern, 0, n)
+	for len(out) < n {
+		words := make([]string, 4+rng.Intn(7))
+		for i := range words {
+			// Placeholders and common words turn up in most lines.
+			if rng.Intn(3) == 0 {
+				words[i] = patternWords[rng.Intn(10)]
+			} else {
+				words[i] = patternWords[rng.Intn(len(patternWords))]
+			}
+		}
+		s := strings.Join(words, " ")
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, database.Pattern{ID: int64(len(out) + 1), Pattern: s, Action: database.PatternActionWarn, Enabled: true})
+	}
+	return out
+}
+
+// patternQueries mixes near copies of stored patterns with unrelated lines.
+func patternQueries(stored []database.Pattern) []string {
+	rng := rand.New(rand.NewSource(7))
+	queries := make([]string, 0, 64)
+	for i := 0; i < 32; i++ {
+		queries = append(queries, stored[rng.Intn(len(stored))].Pattern+".")
+		queries = append(queries, fmt.Sprintf("epoch %d finished, loss %d.%d, accuracy improving steadily", i, rng.Intn(9), rng.Intn(99)))
+	}
+	return queries
+}
+
+func linearMatch(normalized string, stored []database.Pattern) (database.Pattern, bool) {
+	lev := metrics.NewLevenshtein()
+	for _, p := range stored {
+		if strutil.Similarity(normalized, p.Pattern, lev) >= patterns.MatchSimilarity {
+			return p, true
+		}
+	}
+	return database.Pattern{}, false
+}
+
+func TestPatternIndexMatchesLinearScan(t *testing.T) {
+	stored := syntheticPatterns(2000)
+	registry := patterns.NewRegistry(stored)
+	for _, q := range patternQueries(stored) {
+		want, wantOK := linearMatch(q, stored)
+		got, ok := registry.Match(q)
+		if ok != wantOK || got.ID != want.ID {
+			t.Fatalf("%q: indexed match #%d (%v), linear scan #%d (%v)", q, got.ID, ok, want.ID, wantOK)
+		}
+	}
+}
+
+// BenchmarkPatternMatch compares a registry lookup with the full Levenshtein
+// scan it replaces. The scan takes seconds per line at 100k patterns; the
+// indexed lookup reads the postings of the line's q-grams and verifies a
+// handful of candidates, staying in the low milliseconds.
+func BenchmarkPatternMatch(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		stored := syntheticPatterns(n)
+		queries := patternQueries(stored)
+		registry := patterns.NewRegistry(stored)
+
+		b.Run(fmt.Sprintf("indexed/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				registry.Match(queries[i%len(queries)])
+			}
+		})
+		b.Run(fmt.Sprintf("linear/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				linearMatch(queries[i%len(queries)], stored)
+			}
+		})
+	}
+}
+
+func BenchmarkPatternRegistryBuild(b *testing.B) {
+	stored := syntheticPatterns(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		patterns.NewRegistry(stored)
+	}
+}